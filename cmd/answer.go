@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/jsonschema"
 	"github.com/roboalchemist/exa-cli/pkg/output"
 	"github.com/spf13/cobra"
 )
@@ -16,6 +19,8 @@ var (
 	answerStream       bool
 	answerText         bool
 	answerOutputSchema string
+	answerSession      string
+	answerRetryInvalid int
 )
 
 var answerCmd = &cobra.Command{
@@ -31,51 +36,122 @@ Examples:
   exa answer "What are the latest AI breakthroughs in 2025?"
   exa answer "How does photosynthesis work?" --text
   exa answer "Explain quantum computing" --stream
-  exa answer "List top 5 programming languages" --json`,
-	Args: cobra.MinimumNArgs(1),
-	RunE: runAnswer,
+  exa answer "List top 5 programming languages" --json
+  exa answer --batch questions.txt --concurrency 2
+  exa answer "Who introduced the transformer architecture?" --format ris
+
+  # Structured output, validated against the schema
+  exa answer "Largest cities in Japan" --output-schema cities.json --json
+  exa answer "Largest cities in Japan" --output-schema cities.json --retry-on-invalid 2
+
+  # Follow-up questions in a saved conversation
+  exa answer "What is retrieval augmented generation?" --session rag
+  exa answer "Which paper introduced it?" --session rag
+  exa answer sessions show rag`,
+	Args:        batchArgs(cobra.MinimumNArgs(1)),
+	Annotations: map[string]string{formatsAnnotation: citationFormats},
+	RunE:        runAnswer,
 }
 
 func init() {
 	f := answerCmd.Flags()
 	f.BoolVar(&answerStream, "stream", false, "Stream the answer")
 	f.BoolVar(&answerText, "text", false, "Include full text in citations")
-	f.StringVar(&answerOutputSchema, "output-schema", "", "JSON schema file for structured output")
+	f.StringVar(&answerOutputSchema, "output-schema", "", "JSON schema file for structured output; the answer is validated against it")
+	f.IntVar(&answerRetryInvalid, "retry-on-invalid", 0, "Ask again up to N times when the answer does not match --output-schema")
+	f.StringVar(&answerSession, "session", "", "Continue a saved conversation: earlier questions and answers are sent as context")
+	addBatchFlags(answerCmd)
 
 	rootCmd.AddCommand(answerCmd)
 }
 
+// streamable reports whether --stream can write the answer in the output
+// mode of opts as it arrives.
+func streamable(opts output.Options) bool {
+	return opts.Mode.IsHuman() || (opts.Mode == output.ModeNDJSON && opts.JQ == "")
+}
+
 func runAnswer(cmd *cobra.Command, args []string) error {
+	if answerSession != "" && batchFile != "" {
+		return &output.UsageError{Err: fmt.Errorf("--session cannot be combined with --batch")}
+	}
+	if answerRetryInvalid < 0 {
+		return &output.UsageError{Err: fmt.Errorf("--retry-on-invalid must be 0 or more")}
+	}
+	if answerRetryInvalid > 0 && answerStream {
+		return &output.UsageError{Err: fmt.Errorf("--retry-on-invalid cannot be combined with --stream")}
+	}
+	if opts := GetOutputOptions(); answerStream && batchFile == "" && !streamable(opts) {
+		// A stream default from the config file gives way to the output
+		// format; asking for both on the command line is a mistake.
+		if cmd.Flags().Changed("stream") {
+			return &output.UsageError{Err: fmt.Errorf("--stream needs table, plaintext or ndjson output without --jq")}
+		}
+		DebugLog("Not streaming: this output format is written once the answer is complete")
+		answerStream = false
+	}
+
 	client, err := newClient()
 	if err != nil {
 		return err
 	}
 
-	req := &api.AnswerRequest{
-		Query: strings.Join(args, " "),
-		Text:  answerText,
+	if batchFile != "" {
+		base, baseSchema, err := buildAnswerRequest("")
+		if err != nil {
+			return err
+		}
+		return runBatch(client, func(ctx context.Context, item batchItem) (interface{}, *api.CostInfo, error) {
+			req, schema := *base, baseSchema
+			req.Query = item.Query
+			if err := item.apply(&req); err != nil {
+				return nil, nil, err
+			}
+			if item.sets("outputSchema") {
+				s, err := answerSchema(&req)
+				if err != nil {
+					return nil, nil, err
+				}
+				schema = s
+			}
+			resp, structured, err := askAnswer(ctx, client, &req, schema)
+			if err != nil {
+				return nil, nil, err
+			}
+			return answerView(resp, structured), resp.CostDollars, nil
+		})
 	}
 
-	if answerOutputSchema != "" {
-		data, err := os.ReadFile(answerOutputSchema)
+	turn, err := newAnswerTurn(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	req, schema, err := buildAnswerRequest(turn.query)
+	if err != nil {
+		return err
+	}
+
+	opts := GetOutputOptions()
+
+	if answerStream && opts.Mode == output.ModeNDJSON {
+		resp, err := streamAnswerEvents(client, req, opts)
 		if err != nil {
-			return fmt.Errorf("read schema file: %w", err)
+			return err
 		}
-		var schema interface{}
-		if err := json.Unmarshal(data, &schema); err != nil {
-			return fmt.Errorf("parse schema: %w", err)
+		if _, err := checkAnswer(schema, resp); err != nil {
+			return err
 		}
-		req.OutputSchema = schema
+		return turn.save(resp)
 	}
 
-	opts := GetOutputOptions()
-
 	// Streaming mode
-	if answerStream && opts.Mode != output.ModeJSON {
+	if answerStream && opts.Mode.IsHuman() {
+		var text strings.Builder
 		var finalResp *api.AnswerResponse
 		err := client.AnswerStream(newContext(), req,
-			func(text string) {
-				fmt.Print(text)
+			func(chunk string) {
+				text.WriteString(chunk)
+				fmt.Print(chunk)
 			},
 			func(resp *api.AnswerResponse) {
 				finalResp = resp
@@ -100,21 +176,141 @@ func runAnswer(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		return nil
+		resp := streamedAnswer(text.String(), finalResp)
+		if _, err := checkAnswer(schema, resp); err != nil {
+			return err
+		}
+		return turn.save(resp)
 	}
 
 	// Non-streaming
-	resp, err := client.Answer(newContext(), req)
+	resp, structured, err := askAnswer(newContext(), client, req, schema)
 	if err != nil {
 		return err
 	}
+	if err := turn.save(resp); err != nil {
+		return err
+	}
 
-	if opts.Mode == output.ModeJSON {
-		return output.RenderJSON(resp, opts)
+	return renderAnswer(resp, structured)
+}
+
+// askAnswer sends req and, when schema (req's compiled output schema) is
+// not nil, validates the structured answer, asking again up to
+// --retry-on-invalid times while it does not match. It returns the parsed
+// answer, or nil without a schema.
+func askAnswer(ctx context.Context, client *api.Client, req *api.AnswerRequest, schema *jsonschema.Schema) (*api.AnswerResponse, interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Answer(ctx, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		structured, err := checkAnswer(schema, resp)
+		if err == nil {
+			return resp, structured, nil
+		}
+		if attempt >= answerRetryInvalid {
+			return nil, nil, err
+		}
+		// Machine-readable output stays clean; the note is for people.
+		if GetOutputOptions().Mode.IsHuman() {
+			fmt.Fprintf(os.Stderr, "Note: %v; retrying (%d/%d)\n", err, attempt+1, answerRetryInvalid)
+		} else {
+			DebugLog("%v; retrying (%d/%d)", err, attempt+1, answerRetryInvalid)
+		}
+	}
+}
+
+// checkAnswer parses resp's answer and validates it against schema.
+// Without a schema it returns nil.
+func checkAnswer(schema *jsonschema.Schema, resp *api.AnswerResponse) (interface{}, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	structured, err := schema.ValidateJSON([]byte(resp.Answer))
+	var verr *jsonschema.ValidationError
+	if errors.As(err, &verr) {
+		issues := make([]output.Issue, len(verr.Issues))
+		for i, is := range verr.Issues {
+			issues[i] = output.Issue{Path: is.Path, Message: is.Message}
+		}
+		return nil, &output.InvalidAnswerError{Err: fmt.Errorf("answer %w", err), Issues: issues}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("answer %w", err)
+	}
+	return structured, nil
+}
+
+// answerSchema compiles req's output schema, or returns nil if it has none.
+func answerSchema(req *api.AnswerRequest) (*jsonschema.Schema, error) {
+	if req.OutputSchema == nil {
+		return nil, nil
+	}
+	schema, err := jsonschema.Compile(req.OutputSchema)
+	if err != nil {
+		return nil, &output.UsageError{Err: fmt.Errorf("invalid output schema: %w", err)}
+	}
+	return schema, nil
+}
+
+// structuredAnswerView is the JSON form of an answer to a request with an
+// output schema: the answer is the parsed object rather than text.
+type structuredAnswerView struct {
+	*api.AnswerResponse
+	Answer interface{} `json:"answer"`
+}
+
+// answerView returns the JSON form of resp, given its parsed structured
+// answer (nil for a text answer).
+func answerView(resp *api.AnswerResponse, structured interface{}) interface{} {
+	if structured == nil {
+		return resp
+	}
+	return structuredAnswerView{AnswerResponse: resp, Answer: structured}
+}
+
+// streamedAnswer assembles a streamed answer from its text and the final
+// chunk, which may be nil.
+func streamedAnswer(text string, final *api.AnswerResponse) *api.AnswerResponse {
+	resp := &api.AnswerResponse{}
+	if final != nil {
+		*resp = *final
+	}
+	resp.Answer = text
+	return resp
+}
+
+// renderAnswer prints a non-streamed answer in the selected output mode.
+// structured is the parsed answer to a request with an output schema, or
+// nil.
+func renderAnswer(resp *api.AnswerResponse, structured interface{}) error {
+	opts := GetOutputOptions()
+
+	if opts.Mode == output.ModeNDJSON && opts.JQ == "" {
+		if err := output.WriteEvent(output.Event{Type: "text", Text: resp.Answer}); err != nil {
+			return err
+		}
+		return writeAnswerTail(resp, opts)
+	}
+
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(answerView(resp, structured), opts)
+	}
+	if !opts.Mode.IsHuman() {
+		return output.RenderTable(output.TableData{}, resp, opts)
 	}
 
 	// Pretty print answer
-	fmt.Println(resp.Answer)
+	if structured != nil {
+		data, err := json.MarshalIndent(structured, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Println(resp.Answer)
+	}
 
 	if len(resp.Citations) > 0 {
 		fmt.Println()
@@ -131,3 +327,74 @@ func runAnswer(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// streamAnswerEvents streams an answer as typed NDJSON events: one "text"
+// event per chunk, then "citations", "cost" and a closing "done". It returns
+// the assembled answer.
+func streamAnswerEvents(client *api.Client, req *api.AnswerRequest, opts output.Options) (*api.AnswerResponse, error) {
+	var text strings.Builder
+	var finalResp *api.AnswerResponse
+	var writeErr error
+	err := client.AnswerStream(newContext(), req,
+		func(chunk string) {
+			text.WriteString(chunk)
+			if writeErr == nil {
+				writeErr = output.WriteEvent(output.Event{Type: "text", Text: chunk})
+			}
+		},
+		func(resp *api.AnswerResponse) {
+			finalResp = resp
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+	return streamedAnswer(text.String(), finalResp), writeAnswerTail(finalResp, opts)
+}
+
+// writeAnswerTail writes the "citations", "cost" and "done" events that
+// close an NDJSON answer.
+func writeAnswerTail(resp *api.AnswerResponse, opts output.Options) error {
+	if resp != nil && len(resp.Citations) > 0 {
+		ev := output.Event{Type: "citations", Citations: output.FilterFields(resp.Citations, opts.Fields)}
+		if err := output.WriteEvent(ev); err != nil {
+			return err
+		}
+	}
+	if resp != nil && resp.CostDollars != nil {
+		if err := output.WriteEvent(output.Event{Type: "cost", CostDollars: resp.CostDollars}); err != nil {
+			return err
+		}
+	}
+	return output.WriteEvent(output.Event{Type: "done"})
+}
+
+// buildAnswerRequest builds an AnswerRequest for query from the answer
+// flags, along with its compiled output schema (nil without one).
+func buildAnswerRequest(query string) (*api.AnswerRequest, *jsonschema.Schema, error) {
+	req := &api.AnswerRequest{
+		Query: query,
+		Text:  answerText,
+	}
+
+	if answerOutputSchema != "" {
+		data, err := os.ReadFile(answerOutputSchema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read schema file: %w", err)
+		}
+		var raw interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, nil, &output.UsageError{Err: fmt.Errorf("parse schema: %w", err)}
+		}
+		req.OutputSchema = raw
+	}
+
+	schema, err := answerSchema(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, schema, nil
+}
@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
 
+	"github.com/roboalchemist/exa-cli/pkg/api"
 	"github.com/roboalchemist/exa-cli/pkg/auth"
 	"github.com/roboalchemist/exa-cli/pkg/output"
 	"github.com/spf13/cobra"
@@ -16,58 +20,540 @@ var authCmd = &cobra.Command{
 	Short: "Configure API key authentication",
 	Long: `Store your Exa API key in a local config file.
 
-The API key is stored in ~/.exa-auth.json with mode 0600.
+The API key is stored in ~/.exa-auth.json with mode 0600, under a named
+profile ("default" unless --profile or EXA_PROFILE selects another).
 You can also set the EXA_API_KEY environment variable instead.
 
+Profiles hold an API key plus optional base URL, default output format and
+default search type. Select one per invocation with --profile or
+EXA_PROFILE, or make it the default with 'exa auth use'.
+
 Get your API key at: https://dashboard.exa.ai/api-keys
 
 Examples:
   exa auth                       # Interactive setup
+  exa auth --api-key xxx --verify
+  echo "$EXA_KEY" | exa auth --api-key-stdin
+  exa auth status
+  exa auth logout
+  exa auth add team --api-key xxx --search-type deep
+  exa auth add staging --api-key xxx --base-url https://staging.example
+  exa auth use team
+  exa search "..." --profile staging
   EXA_API_KEY=xxx exa search ... # Use env var instead`,
 	RunE: runAuth,
 }
 
+var (
+	authAPIKey      string
+	authAPIKeyStdin bool
+	authVerify      bool
+	authNoVerify    bool
+	authLogoutAll   bool
+)
+
+var (
+	authAddKey        string
+	authAddKeyStdin   bool
+	authAddBaseURL    string
+	authAddOutput     string
+	authAddSearchType string
+	authAddUse        bool
+)
+
+var authAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or update a profile",
+	Long: `Add a named profile, or update the given fields of an existing one.
+
+Without --api-key the key is prompted for. The first profile added becomes
+the current one; pass --use to switch to the new profile.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthAdd,
+}
+
+var authListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthList,
+}
+
+var authUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Set the current profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := auth.LoadConfig()
+		if err != nil {
+			return err
+		}
+		if _, ok := config.Profiles[args[0]]; !ok {
+			return fmt.Errorf("%w: %q", auth.ErrProfileNotFound, args[0])
+		}
+		config.Current = args[0]
+		if err := auth.SaveAuth(*config); err != nil {
+			return fmt.Errorf("save auth: %w", err)
+		}
+		output.Success(fmt.Sprintf("Now using profile %q", args[0]), GetOutputOptions())
+		return nil
+	},
+}
+
+var authRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a profile",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := auth.LoadConfig()
+		if err != nil {
+			return err
+		}
+		if _, ok := config.Profiles[args[0]]; !ok {
+			return fmt.Errorf("%w: %q", auth.ErrProfileNotFound, args[0])
+		}
+		delete(config.Profiles, args[0])
+		if config.Current == args[0] {
+			config.Current = ""
+		}
+		if err := auth.SaveAuth(*config); err != nil {
+			return fmt.Errorf("save auth: %w", err)
+		}
+		output.Success(fmt.Sprintf("Removed profile %q", args[0]), GetOutputOptions())
+		return nil
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which API key is in effect and verify it",
+	Long: `Report where the API key in effect comes from (env, profile or file)
+and verify it with a cheap authenticated API call.
+
+Exits 3 when no key is configured and 4 when the key is rejected.`,
+	Args: cobra.NoArgs,
+	RunE: runAuthStatus,
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove stored credentials",
+	Long: `Remove the active profile from ~/.exa-auth.json, or the whole file
+with --all. The EXA_API_KEY environment variable is not affected.`,
+	Args: cobra.NoArgs,
+	RunE: runAuthLogout,
+}
+
+var authShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show a profile (default: the active one)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runAuthShow,
+}
+
 func init() {
+	af := authCmd.Flags()
+	af.StringVar(&authAPIKey, "api-key", "", "API key to store (non-interactive)")
+	af.BoolVar(&authAPIKeyStdin, "api-key-stdin", false, "Read the API key from stdin")
+	af.BoolVar(&authVerify, "verify", false, "Check the key with the API before saving")
+
+	authStatusCmd.Flags().BoolVar(&authNoVerify, "no-verify", false, "Skip the API call")
+	authLogoutCmd.Flags().BoolVar(&authLogoutAll, "all", false, "Remove every profile and the config file")
+
+	f := authAddCmd.Flags()
+	f.StringVar(&authAddKey, "api-key", "", "API key for the profile")
+	f.BoolVar(&authAddKeyStdin, "api-key-stdin", false, "Read the API key from stdin")
+	f.StringVar(&authAddBaseURL, "base-url", "", "API base URL for the profile")
+	f.StringVar(&authAddOutput, "output", "", "Default output format (table, json, ndjson, csv, ...)")
+	f.StringVar(&authAddSearchType, "search-type", "", "Default search type: auto|fast|deep|neural")
+	f.BoolVar(&authAddUse, "use", false, "Make this the current profile")
+
+	authCmd.AddCommand(authAddCmd, authListCmd, authUseCmd, authRemoveCmd, authShowCmd, authStatusCmd, authLogoutCmd)
 	rootCmd.AddCommand(authCmd)
 }
 
 func runAuth(cmd *cobra.Command, args []string) error {
 	opts := GetOutputOptions()
 
+	config, err := auth.LoadConfig()
+	if err != nil {
+		return err
+	}
+	name := config.ActiveName()
+
 	// Check if already authenticated
-	if key, err := auth.GetAPIKey(); err == nil && key != "" {
-		configPath, _ := auth.ConfigPath()
-		if os.Getenv("EXA_API_KEY") != "" {
-			fmt.Println("Currently authenticated via EXA_API_KEY environment variable.")
-		} else {
-			fmt.Printf("Currently authenticated via config file: %s\n", configPath)
+	if authAPIKey == "" && !authAPIKeyStdin {
+		if creds, err := auth.Resolve(); err == nil {
+			// On stderr, so --json output stays a single document.
+			configPath, _ := auth.ConfigPath()
+			if creds.Source == auth.SourceEnv {
+				fmt.Fprintln(os.Stderr, "Currently authenticated via EXA_API_KEY environment variable.")
+			} else {
+				fmt.Fprintf(os.Stderr, "Currently authenticated via config file: %s (profile %q)\n", configPath, name)
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	key, err := readAPIKey(authAPIKey, authAPIKeyStdin)
+	if err != nil {
+		return err
+	}
+	p := config.Profiles[name]
+	if authVerify {
+		switch err := verifyAPIKey(key, p.Endpoint()); {
+		case err == nil:
+		case isForbidden(err):
+			fmt.Fprintln(os.Stderr, "Note: the API recognized the key but refused to search with it (403); saving it anyway.")
+		case errors.Is(err, api.ErrUnverified):
+			fmt.Fprintf(os.Stderr, "Note: could not verify the key (%v); saving it anyway.\n", err)
+		default:
+			return fmt.Errorf("verify API key: %w", err)
+		}
+	}
+
+	p.APIKey = key
+	config.Profiles[name] = p
+	if config.Current == "" {
+		config.Current = name
+	}
+	if err := auth.SaveAuth(*config); err != nil {
+		return fmt.Errorf("save auth: %w", err)
+	}
+
+	configPath, _ := auth.ConfigPath()
+	output.Success(fmt.Sprintf("API key saved to %s (profile %q)", configPath, name), opts)
+	return nil
+}
+
+// readAPIKey returns the key given by --api-key or --api-key-stdin, or
+// prompts for one.
+func readAPIKey(flagKey string, fromStdin bool) (string, error) {
+	switch {
+	case flagKey != "" && fromStdin:
+		return "", &output.UsageError{Err: fmt.Errorf("--api-key and --api-key-stdin cannot be combined")}
+	case flagKey != "":
+		return strings.TrimSpace(flagKey), nil
+	case fromStdin:
+		data, err := io.ReadAll(io.LimitReader(os.Stdin, 4096))
+		if err != nil {
+			return "", fmt.Errorf("read stdin: %w", err)
 		}
-		fmt.Println()
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", &output.UsageError{Err: fmt.Errorf("no API key on stdin")}
+		}
+		return key, nil
 	}
+	return promptAPIKey()
+}
+
+// verifyAPIKey checks that the API at baseURL accepts key, without a billed
+// call. It uses a bare client, so the check is never cached, retried,
+// recorded in history or the spend ledger, or turned into a dry run.
+func verifyAPIKey(key, baseURL string) error {
+	client := api.NewClient(baseURL, key)
+	client.SetRetryPolicy(api.RetryPolicy{MaxAttempts: 1})
+	if flagDebug {
+		client.SetDebug(DebugLog)
+	}
+	return client.VerifyKey(newContext())
+}
 
+// isForbidden reports whether err is a 403: the API knows the key but does
+// not allow the request.
+func isForbidden(err error) bool {
+	var apiErr *api.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// promptAPIKey reads an API key from an interactive terminal.
+func promptAPIKey() (string, error) {
 	// Check if stdin is interactive
 	fi, _ := os.Stdin.Stat()
 	if fi.Mode()&os.ModeCharDevice == 0 {
-		return fmt.Errorf("--api-key flag or EXA_API_KEY env var required in non-interactive mode")
+		return "", &output.UsageError{Err: fmt.Errorf("--api-key or --api-key-stdin required in non-interactive mode")}
 	}
 
 	fmt.Print("Enter your Exa API key: ")
 	reader := bufio.NewReader(os.Stdin)
 	key, err := reader.ReadString('\n')
 	if err != nil {
-		return fmt.Errorf("read input: %w", err)
+		return "", fmt.Errorf("read input: %w", err)
 	}
 	key = strings.TrimSpace(key)
 
 	if key == "" {
-		return fmt.Errorf("API key cannot be empty")
+		return "", &output.UsageError{Err: fmt.Errorf("API key cannot be empty")}
 	}
+	return key, nil
+}
 
-	if err := auth.SaveAuth(auth.AuthConfig{APIKey: key}); err != nil {
+func runAuthAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if authAddOutput != "" {
+		if _, err := output.ParseMode(authAddOutput); err != nil {
+			return &output.UsageError{Err: err}
+		}
+	}
+	switch authAddSearchType {
+	case "", "auto", "fast", "deep", "neural":
+	default:
+		return &output.UsageError{Err: fmt.Errorf("invalid --search-type %q (want auto, fast, deep or neural)", authAddSearchType)}
+	}
+
+	config, err := auth.LoadConfig()
+	if err != nil {
+		return err
+	}
+	p, exists := config.Profiles[name]
+
+	if authAddKey != "" || authAddKeyStdin || !exists {
+		if p.APIKey, err = readAPIKey(authAddKey, authAddKeyStdin); err != nil {
+			return err
+		}
+	}
+	if authAddBaseURL != "" {
+		p.BaseURL = strings.TrimRight(authAddBaseURL, "/")
+	}
+	if authAddOutput != "" {
+		p.Output = authAddOutput
+	}
+	if authAddSearchType != "" {
+		p.SearchType = authAddSearchType
+	}
+
+	config.Profiles[name] = p
+	if authAddUse || config.Current == "" {
+		config.Current = name
+	}
+	if err := auth.SaveAuth(*config); err != nil {
 		return fmt.Errorf("save auth: %w", err)
 	}
 
+	verb := "Added"
+	if exists {
+		verb = "Updated"
+	}
+	output.Success(fmt.Sprintf("%s profile %q", verb, name), GetOutputOptions())
+	return nil
+}
+
+// profileView is the displayed form of a profile, with the key masked.
+type profileView struct {
+	Name       string `json:"name"`
+	Current    bool   `json:"current"`
+	APIKey     string `json:"apiKey"`
+	BaseURL    string `json:"baseUrl,omitempty"`
+	Output     string `json:"output,omitempty"`
+	SearchType string `json:"searchType,omitempty"`
+}
+
+func newProfileView(config *auth.AuthConfig, name string) profileView {
+	p := config.Profiles[name]
+	return profileView{
+		Name:       name,
+		Current:    name == config.ActiveName(),
+		APIKey:     auth.MaskKey(p.APIKey),
+		BaseURL:    p.BaseURL,
+		Output:     p.Output,
+		SearchType: p.SearchType,
+	}
+}
+
+func runAuthList(cmd *cobra.Command, args []string) error {
+	config, err := auth.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	views := make([]profileView, 0, len(config.Profiles))
+	for _, name := range config.Names() {
+		views = append(views, newProfileView(config, name))
+	}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(map[string]interface{}{"profiles": views}, opts)
+	}
+
+	td := output.TableData{
+		Headers: []string{"", "NAME", "API KEY", "BASE URL", "OUTPUT", "SEARCH TYPE"},
+	}
+	for _, v := range views {
+		marker := ""
+		if v.Current {
+			marker = "*"
+		}
+		td.Rows = append(td.Rows, []string{marker, v.Name, v.APIKey, v.BaseURL, v.Output, v.SearchType})
+	}
+	if len(views) == 0 {
+		td.Footer = "No profiles. Run 'exa auth add <name>' to create one."
+	}
+	return output.RenderTable(td, views, opts)
+}
+
+func runAuthShow(cmd *cobra.Command, args []string) error {
+	config, err := auth.LoadConfig()
+	if err != nil {
+		return err
+	}
+	name := config.ActiveName()
+	if len(args) == 1 {
+		name = args[0]
+	}
+	if _, ok := config.Profiles[name]; !ok {
+		return fmt.Errorf("%w: %q", auth.ErrProfileNotFound, name)
+	}
+	v := newProfileView(config, name)
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(v, opts)
+	}
+
+	baseURL := v.BaseURL
+	if baseURL == "" {
+		baseURL = auth.Profile{}.Endpoint() + " (default)"
+	}
+	td := output.TableData{
+		Headers: []string{"FIELD", "VALUE"},
+		Rows: [][]string{
+			{"Name", v.Name},
+			{"Current", fmt.Sprintf("%t", v.Current)},
+			{"API key", v.APIKey},
+			{"Base URL", baseURL},
+			{"Output", v.Output},
+			{"Search type", v.SearchType},
+		},
+	}
+	return output.RenderTable(td, v, opts)
+}
+
+// authStatus reports the credentials in effect.
+type authStatus struct {
+	Authenticated bool   `json:"authenticated"`
+	Source        string `json:"source,omitempty"`
+	Profile       string `json:"profile,omitempty"`
+	ConfigPath    string `json:"configPath,omitempty"`
+	APIKey        string `json:"apiKey,omitempty"`
+	BaseURL       string `json:"baseUrl"`
+	Verified      bool   `json:"verified"`
+	Restricted    bool   `json:"restricted,omitempty"` // Key accepted but not allowed to search (403)
+	Unverified    bool   `json:"unverified,omitempty"` // The API neither accepted nor rejected the key
+	Error         string `json:"error,omitempty"`
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	st := authStatus{BaseURL: auth.GetBaseURL()}
+	st.ConfigPath, _ = auth.ConfigPath()
+
+	creds, err := auth.Resolve()
+	if err == nil {
+		st.Authenticated = true
+		st.Source = creds.Source
+		st.Profile = creds.Profile
+		st.APIKey = auth.MaskKey(creds.APIKey)
+		if !authNoVerify {
+			err = verifyAPIKey(creds.APIKey, st.BaseURL)
+			switch {
+			case isForbidden(err):
+				st.Restricted = true
+				err = nil
+			case errors.Is(err, api.ErrUnverified):
+				st.Unverified = true
+				st.Error = err.Error()
+				err = nil
+			}
+			st.Verified = err == nil && !st.Unverified
+		}
+	}
+	if err != nil {
+		st.Error = output.Classify(err).Message
+	}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		if rerr := output.RenderJSON(st, opts); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+
+	verified := "no"
+	switch {
+	case st.Restricted:
+		verified = "yes (not permitted to search)"
+	case st.Unverified:
+		verified = "unverified"
+	case st.Verified:
+		verified = "yes"
+	case authNoVerify && st.Authenticated:
+		verified = "skipped"
+	}
+	source := st.Source
+	switch st.Source {
+	case auth.SourceEnv:
+		source = "env (EXA_API_KEY)"
+	case auth.SourceProfile:
+		source = "profile (--profile / EXA_PROFILE)"
+	case auth.SourceFile:
+		source = "file (" + st.ConfigPath + ")"
+	}
+	td := output.TableData{
+		Headers: []string{"FIELD", "VALUE"},
+		Rows: [][]string{
+			{"Authenticated", fmt.Sprintf("%t", st.Authenticated)},
+			{"Source", source},
+			{"Profile", st.Profile},
+			{"API key", st.APIKey},
+			{"Base URL", st.BaseURL},
+			{"Verified", verified},
+		},
+	}
+	if rerr := output.RenderTable(td, st, opts); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	opts := GetOutputOptions()
 	configPath, _ := auth.ConfigPath()
-	output.Success(fmt.Sprintf("API key saved to %s", configPath), opts)
+
+	if authLogoutAll {
+		if err := auth.RemoveConfig(); err != nil {
+			return fmt.Errorf("remove %s: %w", configPath, err)
+		}
+		output.Success(fmt.Sprintf("Removed %s", configPath), opts)
+	} else {
+		config, err := auth.LoadConfig()
+		if err != nil {
+			return err
+		}
+		name := config.ActiveName()
+		if _, ok := config.Profiles[name]; !ok {
+			output.Success(fmt.Sprintf("No stored credentials for profile %q", name), opts)
+		} else {
+			delete(config.Profiles, name)
+			if config.Current == name {
+				config.Current = ""
+			}
+			if len(config.Profiles) == 0 {
+				err = auth.RemoveConfig()
+			} else {
+				err = auth.SaveAuth(*config)
+			}
+			if err != nil {
+				return fmt.Errorf("save auth: %w", err)
+			}
+			output.Success(fmt.Sprintf("Logged out of profile %q", name), opts)
+		}
+	}
+
+	if os.Getenv("EXA_API_KEY") != "" {
+		fmt.Fprintln(os.Stderr, "Note: EXA_API_KEY is still set in the environment.")
+	}
 	return nil
 }
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchFile        string
+	batchConcurrency int
+)
+
+// addBatchFlags registers --batch and --concurrency on a command.
+func addBatchFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVar(&batchFile, "batch", "", "Run one request per line of this file (- for stdin); lines may be JSON objects with request overrides")
+	f.IntVar(&batchConcurrency, "concurrency", 4, "Parallel requests in --batch mode")
+}
+
+// batchArgs wraps an argument validator so that --batch replaces positional
+// arguments.
+func batchArgs(next cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if batchFile == "" {
+			return next(cmd, args)
+		}
+		if len(args) > 0 {
+			return &output.UsageError{Err: fmt.Errorf("--batch cannot be combined with positional arguments")}
+		}
+		return nil
+	}
+}
+
+// batchItem is one line of a batch file.
+type batchItem struct {
+	Index int
+	Line  int             // Line number in the batch file
+	Query string          // Plain-text line, or the query/url field of a JSON line
+	Raw   json.RawMessage // JSON line, applied over the flag-built request
+}
+
+// apply overlays the item's JSON overrides (API field names) onto req.
+func (b batchItem) apply(req interface{}) error {
+	if len(b.Raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(b.Raw, req); err != nil {
+		return &output.UsageError{Err: fmt.Errorf("batch line %d: invalid overrides: %w", b.Line, err)}
+	}
+	return nil
+}
+
+// sets reports whether the item's overrides include the JSON field key.
+func (b batchItem) sets(key string) bool {
+	var fields map[string]json.RawMessage
+	return json.Unmarshal(b.Raw, &fields) == nil && fields[key] != nil
+}
+
+// batchLine is the NDJSON record written for each batch item.
+type batchLine struct {
+	Index  int              `json:"index"`
+	Query  string           `json:"query"`
+	Result interface{}      `json:"result,omitempty"`
+	Error  *output.CLIError `json:"error,omitempty"`
+}
+
+// batchSummary is reported on stderr once all items finish.
+type batchSummary struct {
+	Queries     int     `json:"queries"`
+	Succeeded   int     `json:"succeeded"`
+	Failed      int     `json:"failed"`
+	CostDollars float64 `json:"costDollars"`
+}
+
+// batchDefaultFields are the csv/tsv columns used when --fields is not set.
+const batchDefaultFields = "title,url"
+
+// batchWriter writes batch records to stdout as NDJSON, or as CSV/TSV rows
+// with index, query, the --fields columns and an error column.
+type batchWriter struct {
+	opts   output.Options
+	enc    *json.Encoder
+	cw     *csv.Writer
+	fields []string
+}
+
+func newBatchWriter(w io.Writer, opts output.Options) (*batchWriter, error) {
+	bw := &batchWriter{opts: opts}
+	if !opts.Mode.IsDelimited() {
+		bw.enc = json.NewEncoder(w)
+		return bw, nil
+	}
+
+	bw.cw = output.NewDelimitedWriter(w, opts.Mode)
+	bw.fields = output.ParseFields(opts.Fields)
+	if len(bw.fields) == 0 {
+		bw.fields = output.ParseFields(batchDefaultFields)
+	}
+	if !opts.NoHeader {
+		header := append(append([]string{"index", "query"}, bw.fields...), "error")
+		if err := bw.cw.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	return bw, nil
+}
+
+// write emits one item; result is the unfiltered response for successes.
+func (bw *batchWriter) write(line batchLine, result interface{}) error {
+	if bw.cw == nil {
+		if line.Error == nil {
+			line.Result = output.FilterFields(result, bw.opts.Fields)
+		}
+		return bw.enc.Encode(line)
+	}
+
+	prefix := []string{strconv.Itoa(line.Index), line.Query}
+	if line.Error != nil {
+		row := append(append(prefix, make([]string, len(bw.fields))...), line.Error.Message)
+		_ = bw.cw.Write(row)
+	} else {
+		for _, r := range output.FieldRows(result, bw.fields) {
+			row := append(append(append([]string(nil), prefix...), r...), "")
+			_ = bw.cw.Write(row)
+		}
+	}
+	bw.cw.Flush()
+	return bw.cw.Error()
+}
+
+// batchFunc executes one batch item and returns its result and cost.
+type batchFunc func(ctx context.Context, item batchItem) (interface{}, *api.CostInfo, error)
+
+// batchCost returns the cost actually incurred: nothing for cached responses.
+func batchCost(cost *api.CostInfo, cached bool) *api.CostInfo {
+	if cached {
+		return nil
+	}
+	return cost
+}
+
+// readBatch parses a batch file. Blank lines and lines starting with # are
+// skipped. JSON lines take their query from "query", "url" or the first
+// entry of "urls".
+func readBatch(r io.Reader) ([]batchItem, error) {
+	var items []batchItem
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		item := batchItem{Index: len(items), Line: lineNo, Query: line}
+		if strings.HasPrefix(line, "{") {
+			var fields struct {
+				Query string   `json:"query"`
+				URL   string   `json:"url"`
+				URLs  []string `json:"urls"`
+			}
+			if err := json.Unmarshal([]byte(line), &fields); err != nil {
+				return nil, fmt.Errorf("batch line %d: %w", lineNo, err)
+			}
+			item.Raw = json.RawMessage(line)
+			switch {
+			case fields.Query != "":
+				item.Query = fields.Query
+			case fields.URL != "":
+				item.Query = fields.URL
+			case len(fields.URLs) > 0:
+				item.Query = fields.URLs[0]
+			default:
+				return nil, fmt.Errorf("batch line %d: missing query, url or urls", lineNo)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// openBatch opens the --batch source.
+func openBatch(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// runBatch executes fn for every item in the --batch file using a bounded
+// worker pool and writes one NDJSON line per item, in input order.
+func runBatch(client *api.Client, fn batchFunc) error {
+	r, err := openBatch(batchFile)
+	if err != nil {
+		return fmt.Errorf("open batch file: %w", err)
+	}
+	items, err := readBatch(r)
+	_ = r.Close()
+	if err != nil {
+		return &output.UsageError{Err: err}
+	}
+
+	workers := batchConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	opts := GetOutputOptions()
+	bw, err := newBatchWriter(os.Stdout, opts)
+	if err != nil {
+		return err
+	}
+	// Cancelled when output fails, so no further paid requests are sent.
+	ctx, cancel := context.WithCancel(newContext())
+	defer cancel()
+
+	type outcome struct {
+		line   batchLine
+		result interface{}
+		cost   float64
+		err    error
+	}
+	results := make([]chan outcome, len(items))
+	for i := range results {
+		results[i] = make(chan outcome, 1)
+	}
+
+	jobs := make(chan batchItem)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				res, cost, err := fn(ctx, item)
+				o := outcome{line: batchLine{Index: item.Index, Query: item.Query}, result: res, err: err}
+				if err != nil {
+					cliErr := output.Classify(err)
+					o.line.Error = &cliErr
+				}
+				if cost != nil {
+					o.cost = cost.Total
+				}
+				results[item.Index] <- o
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, item := range items {
+			select {
+			case jobs <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	summary := batchSummary{Queries: len(items)}
+	var firstErr error
+	for _, ch := range results {
+		o := <-ch
+		if errors.Is(o.err, api.ErrDryRun) {
+			continue
+		}
+		if err := bw.write(o.line, o.result); err != nil {
+			cancel()
+			wg.Wait()
+			return err
+		}
+		summary.CostDollars += o.cost
+		if o.err != nil {
+			summary.Failed++
+			if firstErr == nil {
+				firstErr = o.err
+			}
+		} else {
+			summary.Succeeded++
+		}
+	}
+	wg.Wait()
+	if flagDryRun != "" {
+		return nil
+	}
+
+	if opts.Mode.IsJSON() {
+		_ = json.NewEncoder(os.Stderr).Encode(map[string]batchSummary{"summary": summary})
+	} else {
+		fmt.Fprintf(os.Stderr, "Batch: %d queries, %d succeeded, %d failed | Cost: $%.4f\n",
+			summary.Queries, summary.Succeeded, summary.Failed, summary.CostDollars)
+	}
+
+	if firstErr != nil {
+		return fmt.Errorf("%d of %d batch queries failed: %w", summary.Failed, summary.Queries, firstErr)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local response cache",
+	Long: `Manage the on-disk cache of search, contents and similar responses.
+
+Caching is off by default. Enable it per invocation with --cache; entries
+older than --cache-ttl are ignored. Responses live in $XDG_CACHE_HOME/exa
+(~/.cache/exa on Linux).
+
+Examples:
+  exa search "golang generics" --cache
+  exa cache stats
+  exa cache prune --cache-ttl 1h
+  exa cache clear`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache size and entry counts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+		st, err := c.Stats()
+		if err != nil {
+			return fmt.Errorf("read cache: %w", err)
+		}
+
+		opts := GetOutputOptions()
+		if opts.Mode.IsJSON() {
+			return output.RenderJSON(st, opts)
+		}
+
+		td := output.TableData{
+			Headers: []string{"DIR", "ENTRIES", "EXPIRED", "SIZE"},
+			Rows: [][]string{{
+				st.Dir,
+				fmt.Sprintf("%d", st.Entries),
+				fmt.Sprintf("%d", st.Expired),
+				formatBytes(st.Bytes),
+			}},
+		}
+		if st.Entries > 0 {
+			td.Footer = fmt.Sprintf("Oldest: %s | Newest: %s | TTL: %s",
+				st.Oldest.Local().Format("2006-01-02 15:04"),
+				st.Newest.Local().Format("2006-01-02 15:04"),
+				flagCacheTTL)
+		}
+		return output.RenderTable(td, st, opts)
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached responses",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+		n, err := c.Clear()
+		if err != nil {
+			return fmt.Errorf("clear cache: %w", err)
+		}
+		output.Success(fmt.Sprintf("Removed %d cached responses from %s", n, c.Dir()), GetOutputOptions())
+		return nil
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove cached responses older than --cache-ttl",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+		n, err := c.Prune()
+		if err != nil {
+			return fmt.Errorf("prune cache: %w", err)
+		}
+		output.Success(fmt.Sprintf("Removed %d expired responses (older than %s)", n, flagCacheTTL), GetOutputOptions())
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,391 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/config"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage default flags in the config file",
+	Long: `Manage the user config file ($XDG_CONFIG_HOME/exa/config.yaml, or
+$EXA_CONFIG), which supplies defaults for command-line flags.
+
+Top-level keys set global flags (no-color, format, retries, ...). A section
+named after a command sets that command's flags, addressed with dots:
+
+  format: table
+  search:
+    type: deep
+    num-results: 10
+    exclude-domains: [pinterest.com]
+
+Command keys can also be set through an environment variable named EXA_
+plus the key in upper case with dots and dashes as underscores, e.g.
+EXA_SEARCH_NUM_RESULTS=5. Of the global flags, only these are read from the
+environment: EXA_FORMAT, EXA_NO_COLOR, EXA_NO_HEADER, EXA_RETRIES,
+EXA_RETRY_MAX_WAIT, EXA_CACHE, EXA_CACHE_TTL, EXA_NO_HISTORY, EXA_MAX_COST,
+EXA_DAILY_BUDGET and EXA_MONTHLY_BUDGET.
+
+Precedence: command-line flag > environment > config file > built-in default.
+
+Examples:
+  exa config set search.type deep
+  exa config set search.exclude-domains pinterest.com,quora.com
+  exa config get search.type
+  exa config list
+  exa config unset search.type
+  exa config edit`,
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file location",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.Path()
+		if err != nil {
+			return fmt.Errorf("locate config: %w", err)
+		}
+		fmt.Println(path)
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured keys",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+
+		opts := GetOutputOptions()
+		values := make(map[string]interface{})
+		td := output.TableData{Headers: []string{"KEY", "VALUE"}}
+		for _, key := range f.Keys() {
+			v, _ := f.Get(key)
+			values[key] = v
+			td.Rows = append(td.Rows, []string{key, config.FormatValue(v)})
+		}
+		if opts.Mode.IsJSON() {
+			return output.RenderJSON(values, opts)
+		}
+		td.Footer = f.Path()
+		return output.RenderTable(td, values, opts)
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a configured value",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		v, ok := f.Get(args[0])
+		if !ok {
+			return &output.UsageError{Err: fmt.Errorf("%s is not set in %s", args[0], f.Path())}
+		}
+
+		opts := GetOutputOptions()
+		if opts.Mode.IsJSON() {
+			return output.RenderJSON(map[string]interface{}{"key": args[0], "value": v}, opts)
+		}
+		fmt.Println(config.FormatValue(v))
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>...",
+	Short: "Set a default flag value",
+	Long: `Set a default flag value. The key must name a global flag or a flag of
+the command in the section, and the value must parse as that flag's type.
+List flags take several values or one comma-separated value.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flag, err := lookupConfigFlag(args[0])
+		if err != nil {
+			return &output.UsageError{Err: err}
+		}
+		value, err := configValue(flag, args[1:])
+		if err != nil {
+			return &output.UsageError{Err: fmt.Errorf("%s: %w", args[0], err)}
+		}
+
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		if err := f.Set(args[0], value); err != nil {
+			return err
+		}
+		if err := f.Save(); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+		output.Success(fmt.Sprintf("Set %s = %s", args[0], config.FormatValue(value)), GetOutputOptions())
+		return nil
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a configured value",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		if !f.Unset(args[0]) {
+			return &output.UsageError{Err: fmt.Errorf("%s is not set in %s", args[0], f.Path())}
+		}
+		if err := f.Save(); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+		output.Success(fmt.Sprintf("Unset %s", args[0]), GetOutputOptions())
+		return nil
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in $VISUAL or $EDITOR",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.Path()
+		if err != nil {
+			return fmt.Errorf("locate config: %w", err)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if err := config.WriteTemplate(path); err != nil {
+				return fmt.Errorf("create config: %w", err)
+			}
+		}
+
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+		fields := strings.Fields(editor)
+		c := exec.Command(fields[0], append(fields[1:], path)...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("run %s: %w", editor, err)
+		}
+
+		_, err = config.Load(path)
+		return err
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPathCmd, configListCmd, configGetCmd, configSetCmd, configUnsetCmd, configEditCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func loadConfigFile() (*config.File, error) {
+	path, err := config.Path()
+	if err != nil {
+		return nil, fmt.Errorf("locate config: %w", err)
+	}
+	return config.Load(path)
+}
+
+// outputModeFlags select the output mode. If any is given on the command
+// line, config and environment values for the others are ignored, and
+// environment values likewise win over the config file as a group.
+var outputModeFlags = map[string]bool{
+	"json": true, "plaintext": true, "ndjson": true,
+	"format": true, "template": true, "template-file": true,
+}
+
+// envGlobalFlags are the global flags that may be set from the environment.
+// Others, such as --debug, --json or --dry-run, change behaviour too much to
+// pick up from a stray variable.
+var envGlobalFlags = map[string]bool{
+	"format": true, "no-color": true, "no-header": true,
+	"retries": true, "retry-max-wait": true,
+	"cache": true, "cache-ttl": true, "no-history": true,
+	"max-cost": true, "daily-budget": true, "monthly-budget": true,
+}
+
+// configuredFlags records the flags applyConfig filled in, which stay
+// unchanged in the cobra sense.
+var configuredFlags = make(map[*pflag.Flag]bool)
+
+// flagGiven reports whether flag name of cmd was set on the command line,
+// in the environment or in the config file.
+func flagGiven(cmd *cobra.Command, name string) bool {
+	f := cmd.Flags().Lookup(name)
+	return f != nil && (f.Changed || configuredFlags[f])
+}
+
+// configKey returns the config key for flag f of cmd: the bare flag name
+// for global flags, otherwise the command path and flag name joined by dots.
+func configKey(cmd *cobra.Command, f *pflag.Flag) string {
+	root := cmd.Root()
+	if root.PersistentFlags().Lookup(f.Name) == f {
+		return f.Name
+	}
+	path := strings.TrimPrefix(cmd.CommandPath(), root.Name()+" ")
+	return strings.ReplaceAll(path, " ", ".") + "." + f.Name
+}
+
+// configEnv returns the environment variable that sets key, or "" if key
+// is a global flag that is not read from the environment.
+func configEnv(key string) string {
+	if !strings.Contains(key, ".") && !envGlobalFlags[key] {
+		return ""
+	}
+	return "EXA_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// applyConfig fills flags of cmd that were not given on the command line
+// from the environment, then from the config file. The flags are set
+// without marking them changed; see flagGiven.
+func applyConfig(cmd *cobra.Command) error {
+	var file *config.File
+	if !isConfigCommand(cmd) {
+		f, err := loadConfigFile()
+		if err != nil {
+			return err
+		}
+		file = f
+	}
+
+	type setting struct {
+		flag   *pflag.Flag
+		value  string
+		source string
+		env    bool
+	}
+	var settings []setting
+	modeFromCLI, modeFromEnv := false, false
+
+	flags := cmd.Flags()
+	flags.VisitAll(func(f *pflag.Flag) {
+		delete(configuredFlags, f)
+		if f.Changed {
+			if outputModeFlags[f.Name] {
+				modeFromCLI = true
+			}
+			return
+		}
+		if f.Name == "help" || f.Name == "version" {
+			return
+		}
+		key := configKey(cmd, f)
+		if env := configEnv(key); env != "" && os.Getenv(env) != "" {
+			settings = append(settings, setting{f, os.Getenv(env), env, true})
+			if outputModeFlags[f.Name] {
+				modeFromEnv = true
+			}
+			return
+		}
+		if file != nil {
+			if v, ok := file.Get(key); ok {
+				settings = append(settings, setting{f, config.FormatValue(v), "config " + key, false})
+			}
+		}
+	})
+
+	for _, s := range settings {
+		if outputModeFlags[s.flag.Name] && (modeFromCLI || (modeFromEnv && !s.env)) {
+			continue
+		}
+		var err error
+		if sv, ok := s.flag.Value.(pflag.SliceValue); ok {
+			err = sv.Replace(strings.Split(s.value, ","))
+		} else {
+			err = s.flag.Value.Set(s.value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: invalid value %q for --%s: %w", s.source, s.value, s.flag.Name, err)
+		}
+		configuredFlags[s.flag] = true
+	}
+	return nil
+}
+
+func isConfigCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == configCmd {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupConfigFlag returns the flag a config key refers to.
+func lookupConfigFlag(key string) (*pflag.Flag, error) {
+	parts := strings.Split(key, ".")
+	name := parts[len(parts)-1]
+	if len(parts) == 1 {
+		if f := rootCmd.PersistentFlags().Lookup(name); f != nil && name != "help" {
+			return f, nil
+		}
+		return nil, fmt.Errorf("unknown global flag %q", name)
+	}
+
+	c, rest, err := rootCmd.Find(parts[:len(parts)-1])
+	if err != nil || len(rest) > 0 || c == rootCmd {
+		return nil, fmt.Errorf("unknown command %q in key %q", strings.Join(parts[:len(parts)-1], " "), key)
+	}
+	if f := c.Flags().Lookup(name); f != nil && name != "help" {
+		return f, nil
+	}
+	return nil, fmt.Errorf("%s has no flag --%s", c.CommandPath(), name)
+}
+
+// configValue converts command-line values into the YAML value stored for
+// flag, checking that they parse as the flag's type.
+func configValue(flag *pflag.Flag, args []string) (interface{}, error) {
+	typ := flag.Value.Type()
+	if strings.HasSuffix(typ, "Slice") || strings.HasSuffix(typ, "Array") {
+		var list []interface{}
+		for _, a := range args {
+			for _, v := range strings.Split(a, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					list = append(list, v)
+				}
+			}
+		}
+		return list, nil
+	}
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("--%s takes a single value", flag.Name)
+	}
+	v := args[0]
+	switch typ {
+	case "bool":
+		return strconv.ParseBool(v)
+	case "int":
+		return strconv.Atoi(v)
+	case "float64":
+		return strconv.ParseFloat(v, 64)
+	case "duration":
+		if _, err := time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
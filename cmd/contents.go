@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/roboalchemist/exa-cli/pkg/api"
@@ -29,9 +30,12 @@ Examples:
   exa contents https://example.com https://another.com
   exa contents https://example.com --highlights --summary
   exa contents https://example.com --text-max-chars 5000
-  exa contents https://example.com --json`,
-	Args: cobra.MinimumNArgs(1),
-	RunE: runContents,
+  exa contents https://example.com --json
+  exa contents --batch urls.txt --summary
+  exa contents https://arxiv.org/abs/1706.03762 --format bibtex >> refs.bib`,
+	Args:        batchArgs(cobra.MinimumNArgs(1)),
+	Annotations: map[string]string{formatsAnnotation: citationFormats},
+	RunE:        runContents,
 }
 
 func init() {
@@ -42,6 +46,7 @@ func init() {
 	f.BoolVar(&contentsSummary, "summary", false, "Include summary")
 	f.IntVar(&contentsMaxAge, "max-age-hours", -1, "Content freshness (-1=cache, 0=always livecrawl)")
 	f.IntVar(&contentsSubpages, "subpages", 0, "Subpages to crawl")
+	addBatchFlags(contentsCmd)
 
 	rootCmd.AddCommand(contentsCmd)
 }
@@ -52,8 +57,32 @@ func runContents(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if batchFile != "" {
+		return runBatch(client, func(ctx context.Context, item batchItem) (interface{}, *api.CostInfo, error) {
+			req := buildContentsRequest([]string{item.Query})
+			if err := item.apply(req); err != nil {
+				return nil, nil, err
+			}
+			resp, err := client.GetContents(ctx, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			return resp, batchCost(resp.CostDollars, resp.Cached), nil
+		})
+	}
+
+	resp, err := client.GetContents(newContext(), buildContentsRequest(args))
+	if err != nil {
+		return err
+	}
+
+	return renderContents(resp)
+}
+
+// buildContentsRequest builds a ContentsRequest for urls from the contents flags.
+func buildContentsRequest(urls []string) *api.ContentsRequest {
 	req := &api.ContentsRequest{
-		URLs: args,
+		URLs: urls,
 	}
 
 	if contentsText {
@@ -76,15 +105,18 @@ func runContents(cmd *cobra.Command, args []string) error {
 		req.Subpages = contentsSubpages
 	}
 
-	resp, err := client.GetContents(newContext(), req)
-	if err != nil {
-		return err
-	}
+	return req
+}
 
+// renderContents prints a contents response in the selected output mode.
+func renderContents(resp *api.ContentsResponse) error {
 	opts := GetOutputOptions()
 
-	if opts.Mode == output.ModeJSON {
-		return output.RenderJSON(resp, opts)
+	if opts.Mode.IsJSON() {
+		if err := output.RenderJSON(resp, opts); err != nil {
+			return err
+		}
+		return resultsErr(len(resp.Results))
 	}
 
 	// Table mode: show title and URL, then text below
@@ -95,15 +127,14 @@ func runContents(cmd *cobra.Command, args []string) error {
 		td.Rows = append(td.Rows, []string{truncateStr(r.Title, 60), r.URL})
 	}
 
-	footer := fmt.Sprintf("%d pages", len(resp.Results))
-	if resp.CostDollars != nil {
-		footer = fmt.Sprintf("Cost: $%.4f | %s", resp.CostDollars.Total, footer)
-	}
-	td.Footer = footer
+	td.Footer = costFooter(resp.CostDollars, resp.Cached, fmt.Sprintf("%d pages", len(resp.Results)))
 
 	if err := output.RenderTable(td, resp, opts); err != nil {
 		return err
 	}
+	if !opts.Mode.IsHuman() {
+		return resultsErr(len(resp.Results))
+	}
 
 	// Print text content below table for each result
 	for _, r := range resp.Results {
@@ -121,5 +152,5 @@ func runContents(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return nil
+	return resultsErr(len(resp.Results))
 }
@@ -39,25 +39,36 @@ func runContext(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	req := &api.ContextRequest{
-		Query: strings.Join(args, " "),
+	resp, err := client.GetContext(newContext(), buildContextRequest(strings.Join(args, " ")))
+	if err != nil {
+		return err
 	}
+
+	return renderContext(cmd, resp)
+}
+
+// buildContextRequest builds a ContextRequest for query from the context
+// flags.
+func buildContextRequest(query string) *api.ContextRequest {
+	req := &api.ContextRequest{Query: query}
 	if contextTokens > 0 {
 		req.TokensNum = contextTokens
 	} else {
 		req.TokensNum = "dynamic"
 	}
+	return req
+}
 
-	resp, err := client.GetContext(newContext(), req)
-	if err != nil {
-		return err
-	}
-
+// renderContext prints a code context response in the selected output mode.
+func renderContext(cmd *cobra.Command, resp *api.ContextResponse) error {
 	opts := GetOutputOptions()
 
-	if opts.Mode == output.ModeJSON {
+	if opts.Mode.IsJSON() {
 		return output.RenderJSON(resp, opts)
 	}
+	if !opts.Mode.IsHuman() {
+		return output.RenderTable(output.TableData{}, resp, opts)
+	}
 
 	// Print context directly
 	fmt.Println(resp.Context)
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+)
+
+// Values accepted by --dry-run.
+const (
+	dryRunJSON = "json"
+	dryRunCurl = "curl"
+)
+
+// dryRunRequest is the JSON form of a request printed by --dry-run.
+type dryRunRequest struct {
+	Method        string            `json:"method"`
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers"`
+	Body          json.RawMessage   `json:"body,omitempty"`
+	EstimatedCost api.Estimate      `json:"estimatedCost"`
+}
+
+// dryRunMu keeps requests printed by concurrent batch workers apart.
+var dryRunMu sync.Mutex
+
+// validateDryRun checks the --dry-run value. rawArgs are the command-line
+// arguments before parsing: as the format is optional, '--dry-run curl'
+// leaves "curl" as an argument, which is rejected rather than searched for.
+func validateDryRun(rawArgs []string) error {
+	switch flagDryRun {
+	case "", dryRunJSON, dryRunCurl:
+	default:
+		return &output.UsageError{Err: fmt.Errorf("invalid --dry-run %q (want json or curl)", flagDryRun)}
+	}
+	for i, arg := range rawArgs {
+		if arg == "--" {
+			break
+		}
+		if arg != "--dry-run" || i+1 == len(rawArgs) {
+			continue
+		}
+		if next := rawArgs[i+1]; next == dryRunJSON || next == dryRunCurl {
+			return &output.UsageError{Err: fmt.Errorf("--dry-run takes its format after '=': use --dry-run=%s (to use %q as an argument, put it before --dry-run)", next, next)}
+		}
+	}
+	return nil
+}
+
+// dryRunGuard prints each request instead of sending it.
+func dryRunGuard(ctx context.Context, call *api.Call) error {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+
+	estimate := api.DefaultPrices.Estimate(call)
+	if flagDryRun == dryRunCurl {
+		fmt.Println(curlCommand(call))
+		fmt.Printf("# Estimated cost: %s\n", formatEstimate(estimate))
+		return api.ErrDryRun
+	}
+
+	data, err := json.MarshalIndent(dryRunRequest{
+		Method:        call.Method,
+		URL:           call.URL,
+		Headers:       dryRunHeaders(call),
+		Body:          call.Body,
+		EstimatedCost: estimate,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return api.ErrDryRun
+}
+
+// dryRunHeaders returns the headers the client sends, with the key redacted.
+func dryRunHeaders(call *api.Call) map[string]string {
+	h := map[string]string{
+		"Content-Type": "application/json",
+		"x-api-key":    "REDACTED",
+		"User-Agent":   "exa-cli/" + api.Version,
+	}
+	if call.Stream {
+		h["Accept"] = "text/event-stream"
+	}
+	return h
+}
+
+// curlCommand renders call as a curl command that reads the key from
+// $EXA_API_KEY.
+func curlCommand(call *api.Call) string {
+	lines := []string{"curl"}
+	if call.Stream {
+		lines[0] += " -N"
+	}
+	if call.Method != http.MethodGet {
+		lines[0] += " -X " + call.Method
+	}
+	lines[0] += " " + shellQuote(call.URL)
+	lines = append(lines,
+		"  -H "+shellQuote("Content-Type: application/json"),
+		`  -H "x-api-key: $EXA_API_KEY"`,
+		"  -H "+shellQuote("User-Agent: exa-cli/"+api.Version))
+	if call.Stream {
+		lines = append(lines, "  -H "+shellQuote("Accept: text/event-stream"))
+	}
+	if call.Body != nil {
+		lines = append(lines, "  --data-raw "+shellQuote(string(call.Body)))
+	}
+	return strings.Join(lines, " \\\n")
+}
+
+// shellQuote wraps s in single quotes for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// formatEstimate renders an estimate as "$0.0150 (search $0.0050, text 10 × $0.0010)".
+func formatEstimate(e api.Estimate) string {
+	if len(e.Items) == 0 {
+		return "$0.0000"
+	}
+	parts := make([]string, 0, len(e.Items))
+	for _, it := range e.Items {
+		if it.Count == 1 {
+			parts = append(parts, fmt.Sprintf("%s $%.4f", it.Item, it.Dollars))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s %d × $%.4f", it.Item, it.Count, it.Unit))
+		}
+	}
+	return fmt.Sprintf("$%.4f (%s)", e.Total, strings.Join(parts, ", "))
+}
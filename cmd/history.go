@@ -0,0 +1,428 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/history"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyLimit   int
+	historyCommand string
+	historyAll     bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List, re-run and compare past API calls",
+	Long: `Every API call is logged to $XDG_DATA_HOME/exa/history.jsonl
+(~/.local/share by default) with its command, request body, request ID,
+result URLs, cost and time. Disable logging with --no-history.
+
+Calls made for the clients of 'exa serve' and 'exa mcp serve' are marked
+with the client they were made for. 'history list' shows them only with
+--all, and 'history rerun' does not replay them.
+
+Examples:
+  exa history list
+  exa history show 42
+  exa history rerun 42
+  exa history diff 42        # against the previous run of the same request
+  exa history diff 42 57`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent calls",
+	Args:  cobra.NoArgs,
+	RunE:  runHistoryList,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a recorded call",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryShow,
+}
+
+var historyRerunCmd = &cobra.Command{
+	Use:         "rerun <id>",
+	Short:       "Send a recorded request again",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{formatsAnnotation: feedFormats + "," + citationFormats},
+	RunE:        runHistoryRerun,
+}
+
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff <id> [<id>]",
+	Short: "Compare result URLs of two runs",
+	Long: `Show URLs that appeared, disappeared or moved in rank between two runs.
+With one ID, compare it with the previous run of the same request.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runHistoryDiff,
+}
+
+func init() {
+	historyListCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of most recent entries to show (0 = all)")
+	historyListCmd.Flags().StringVar(&historyCommand, "command", "", "Only show calls made by this command")
+	historyListCmd.Flags().BoolVar(&historyAll, "all", false, "Include calls made for proxy and MCP clients")
+
+	historyCmd.AddCommand(historyListCmd, historyShowCmd, historyRerunCmd, historyDiffCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// openHistory opens the history store in the XDG data directory.
+func openHistory() (*history.Store, error) {
+	path, err := history.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("locate history: %w", err)
+	}
+	return history.Open(path), nil
+}
+
+// historyEntry loads the entry named by a command-line ID.
+func historyEntry(store *history.Store, arg string) (history.Entry, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil || id < 1 {
+		return history.Entry{}, &output.UsageError{Err: fmt.Errorf("invalid history ID %q", arg)}
+	}
+	e, err := store.Get(id)
+	if errors.Is(err, history.ErrNotFound) {
+		return e, &output.UsageError{Err: err}
+	}
+	return e, err
+}
+
+// recordHistory logs every successful call made by client. Replayed
+// responses are not logged.
+func recordHistory(client *api.Client) {
+	if flagNoHistory || os.Getenv("EXA_REPLAY") != "" {
+		return
+	}
+	store, err := openHistory()
+	if err != nil {
+		DebugLog("History unavailable: %s", err)
+		return
+	}
+	client.AddObserver(func(call *api.Call) {
+		e := history.Entry{
+			Time:      time.Now(),
+			Command:   commandName,
+			Endpoint:  call.Endpoint,
+			Request:   call.Body,
+			RequestID: call.RequestID,
+			URLs:      resultURLs(call.Response),
+			Cached:    call.Cached,
+			Client:    call.Caller,
+		}
+		if call.Cost != nil && !call.Cached {
+			e.Cost = call.Cost.Total
+		}
+		if err := store.Append(e); err != nil {
+			DebugLog("History write failed: %s", err)
+		}
+	})
+}
+
+// firstSeen returns when each result URL in the history first appeared,
+// for dating feed entries that have no publish date.
+func firstSeen() map[string]time.Time {
+	store, err := openHistory()
+	if err != nil {
+		DebugLog("History unavailable: %s", err)
+		return nil
+	}
+	entries, err := store.List()
+	if err != nil {
+		DebugLog("History unavailable: %s", err)
+		return nil
+	}
+	seen := make(map[string]time.Time)
+	for _, e := range entries {
+		for _, u := range e.URLs {
+			if t, ok := seen[u]; !ok || e.Time.Before(t) {
+				seen[u] = e.Time
+			}
+		}
+	}
+	return seen
+}
+
+// resultURLs returns the result or citation URLs of a response, in rank
+// order.
+func resultURLs(resp []byte) []string {
+	var body struct {
+		Results   []struct{ URL string } `json:"results"`
+		Citations []struct{ URL string } `json:"citations"`
+	}
+	_ = json.Unmarshal(resp, &body)
+	urls := []string{}
+	for _, r := range append(body.Results, body.Citations...) {
+		urls = append(urls, r.URL)
+	}
+	return urls
+}
+
+// requestSubject returns the query or URL a recorded request was about.
+func requestSubject(req json.RawMessage) string {
+	var body struct {
+		Query string   `json:"query"`
+		URL   string   `json:"url"`
+		URLs  []string `json:"urls"`
+		IDs   []string `json:"ids"`
+	}
+	_ = json.Unmarshal(req, &body)
+	switch {
+	case body.Query != "":
+		return body.Query
+	case body.URL != "":
+		return body.URL
+	case len(body.URLs) > 0:
+		return strings.Join(body.URLs, " ")
+	}
+	return strings.Join(body.IDs, " ")
+}
+
+func runHistoryList(cmd *cobra.Command, args []string) error {
+	store, err := openHistory()
+	if err != nil {
+		return err
+	}
+	all, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	entries := []history.Entry{}
+	for _, e := range all {
+		if (e.Client == "" || historyAll) && (historyCommand == "" || e.Command == historyCommand) {
+			entries = append(entries, e)
+		}
+	}
+	if historyLimit > 0 && len(entries) > historyLimit {
+		entries = entries[len(entries)-historyLimit:]
+	}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(entries, opts)
+	}
+
+	td := output.TableData{Headers: []string{"ID", "TIME", "COMMAND", "QUERY", "RESULTS", "COST"}}
+	for _, e := range entries {
+		cost := fmt.Sprintf("$%.4f", e.Cost)
+		if e.Cached {
+			cost = "cached"
+		}
+		command := e.Command
+		if e.Client != "" {
+			command += " (" + e.Client + ")"
+		}
+		td.Rows = append(td.Rows, []string{
+			strconv.Itoa(e.ID),
+			e.Time.Local().Format("2006-01-02 15:04"),
+			command,
+			truncateStr(requestSubject(e.Request), 50),
+			strconv.Itoa(len(e.URLs)),
+			cost,
+		})
+	}
+	td.Footer = fmt.Sprintf("%d of %d entries | %s", len(entries), len(all), store.Path())
+	return output.RenderTable(td, entries, opts)
+}
+
+func runHistoryShow(cmd *cobra.Command, args []string) error {
+	store, err := openHistory()
+	if err != nil {
+		return err
+	}
+	e, err := historyEntry(store, args[0])
+	if err != nil {
+		return err
+	}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(e, opts)
+	}
+
+	td := output.TableData{
+		Headers: []string{"FIELD", "VALUE"},
+		Rows: [][]string{
+			{"ID", strconv.Itoa(e.ID)},
+			{"Time", e.Time.Local().Format(time.RFC3339)},
+			{"Command", e.Command},
+			{"Client", e.Client},
+			{"Endpoint", e.Endpoint},
+			{"Request", string(e.Request)},
+			{"Request ID", e.RequestID},
+			{"Cost", fmt.Sprintf("$%.4f", e.Cost)},
+			{"Cached", strconv.FormatBool(e.Cached)},
+		},
+	}
+	for i, u := range e.URLs {
+		td.Rows = append(td.Rows, []string{fmt.Sprintf("Result %d", i+1), u})
+	}
+	return output.RenderTable(td, e, opts)
+}
+
+func runHistoryRerun(cmd *cobra.Command, args []string) error {
+	store, err := openHistory()
+	if err != nil {
+		return err
+	}
+	e, err := historyEntry(store, args[0])
+	if err != nil {
+		return err
+	}
+	if e.Client != "" {
+		return &output.UsageError{Err: fmt.Errorf("history entry %d was made for client %q (%s); rerun only replays your own calls", e.ID, e.Client, e.Command)}
+	}
+
+	// Log the new run under the original command so list and diff group it
+	// with earlier runs.
+	commandName = e.Command
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	ctx := newContext()
+
+	decode := func(req interface{}) error {
+		if err := json.Unmarshal(e.Request, req); err != nil {
+			return fmt.Errorf("history entry %d: invalid request: %w", e.ID, err)
+		}
+		return nil
+	}
+	switch e.Endpoint {
+	case "/search":
+		var req api.SearchRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		if req.Type != "" {
+			searchType = req.Type
+		}
+		resp, err := client.Search(ctx, &req)
+		if err != nil {
+			return err
+		}
+		return renderSearch(req.Query, resp)
+	case "/findSimilar":
+		var req api.FindSimilarRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		resp, err := client.FindSimilar(ctx, &req)
+		if err != nil {
+			return err
+		}
+		return renderSimilar(req.URL, resp)
+	case "/contents":
+		var req api.ContentsRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		resp, err := client.GetContents(ctx, &req)
+		if err != nil {
+			return err
+		}
+		return renderContents(resp)
+	case "/answer":
+		var req api.AnswerRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		req.StreamOutput = false
+		schema, err := answerSchema(&req)
+		if err != nil {
+			return err
+		}
+		resp, structured, err := askAnswer(ctx, client, &req, schema)
+		if err != nil {
+			return err
+		}
+		return renderAnswer(resp, structured)
+	case "/context":
+		var req api.ContextRequest
+		if err := decode(&req); err != nil {
+			return err
+		}
+		resp, err := client.GetContext(ctx, &req)
+		if err != nil {
+			return err
+		}
+		return renderContext(cmd, resp)
+	}
+	return &output.UsageError{Err: fmt.Errorf("history entry %d: cannot re-run %s requests", e.ID, e.Endpoint)}
+}
+
+// historyDiff is the JSON form of 'exa history diff'.
+type historyDiff struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	history.Comparison
+}
+
+func runHistoryDiff(cmd *cobra.Command, args []string) error {
+	store, err := openHistory()
+	if err != nil {
+		return err
+	}
+	to, err := historyEntry(store, args[len(args)-1])
+	if err != nil {
+		return err
+	}
+	var from history.Entry
+	if len(args) == 2 {
+		if from, err = historyEntry(store, args[0]); err != nil {
+			return err
+		}
+		if from.Endpoint != to.Endpoint {
+			return &output.UsageError{Err: fmt.Errorf("entries %d and %d call different endpoints (%s, %s)",
+				from.ID, to.ID, from.Endpoint, to.Endpoint)}
+		}
+		if !from.SameRequest(to) {
+			fmt.Fprintf(os.Stderr, "Note: entries %d and %d sent different requests\n", from.ID, to.ID)
+		}
+	} else if from, err = store.Previous(to); err != nil {
+		if errors.Is(err, history.ErrNotFound) {
+			return &output.UsageError{Err: err}
+		}
+		return err
+	}
+
+	diff := historyDiff{From: from.ID, To: to.ID, Comparison: history.Compare(from.URLs, to.URLs)}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(diff, opts)
+	}
+
+	td := output.TableData{Headers: []string{"CHANGE", "RANK", "URL"}}
+	for _, c := range diff.Appeared {
+		td.Rows = append(td.Rows, []string{"+ appeared", fmt.Sprintf("- → %d", c.To), c.URL})
+	}
+	for _, c := range diff.Disappeared {
+		td.Rows = append(td.Rows, []string{"- disappeared", fmt.Sprintf("%d → -", c.From), c.URL})
+	}
+	for _, c := range diff.Moved {
+		change := "↑ moved up"
+		if c.To > c.From {
+			change = "↓ moved down"
+		}
+		td.Rows = append(td.Rows, []string{change, fmt.Sprintf("%d → %d", c.From, c.To), c.URL})
+	}
+	td.Footer = fmt.Sprintf("#%d → #%d | %d appeared, %d disappeared, %d moved, %d unchanged",
+		from.ID, to.ID, len(diff.Appeared), len(diff.Disappeared), len(diff.Moved), diff.Unchanged)
+	return output.RenderTable(td, diff, opts)
+}
@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/mcp"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Model Context Protocol server for AI agents",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Exa tools over stdio using the Model Context Protocol",
+	Long: `Run an MCP server on stdin/stdout (JSON-RPC 2.0, one message per line).
+
+Tools: search, contents, similar, answer and context. Their input
+properties are the command's flags (e.g. "num-results", "include-domains")
+plus the query, URL or URLs. Results are returned as structured content with
+the same JSON as --json, including costDollars. Failed calls return the
+structured error from --json mode.
+
+Global flags such as --profile, --max-cost and --cache apply to every call.
+
+Example MCP client configuration:
+  {"mcpServers": {"exa": {"command": "exa", "args": ["mcp", "serve"]}}}
+
+Test by piping messages:
+  echo '{"jsonrpc":"2.0","id":1,"method":"tools/list"}' | exa mcp serve`,
+	Args: cobra.NoArgs,
+	RunE: runMCPServe,
+}
+
+func init() {
+	mcpCmd.AddCommand(mcpServeCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
+
+// mcpCaller labels calls made for MCP clients; see api.WithCaller.
+const mcpCaller = "mcp"
+
+// mcpSkipFlags are command flags not exposed as tool inputs.
+var mcpSkipFlags = map[string]bool{"help": true, "batch": true, "concurrency": true}
+
+// mcpTool exposes a command as an MCP tool. Its flags become input
+// properties alongside arg, the positional argument.
+type mcpTool struct {
+	cmd   *cobra.Command
+	arg   string
+	input *mcp.Schema // Schema of arg
+	skip  []string    // Command flags not exposed
+	extra map[string]*mcp.Schema
+	call  func(ctx context.Context, client *api.Client, arg interface{}, args map[string]interface{}) (interface{}, error)
+}
+
+// mcpTools returns the tools served by 'exa mcp serve'.
+func mcpTools() []mcpTool {
+	query := &mcp.Schema{Type: "string", Description: "Query text"}
+	return []mcpTool{
+		{
+			cmd: searchCmd, arg: "query", input: query,
+			call: func(ctx context.Context, client *api.Client, arg interface{}, _ map[string]interface{}) (interface{}, error) {
+				applyProfileSearchType(searchCmd)
+				return client.Search(ctx, buildSearchRequest(arg.(string)))
+			},
+		},
+		{
+			cmd: contentsCmd, arg: "urls",
+			input: &mcp.Schema{Type: "array", Items: &mcp.Schema{Type: "string"}, MinItems: 1, Description: "Page URLs"},
+			call: func(ctx context.Context, client *api.Client, arg interface{}, _ map[string]interface{}) (interface{}, error) {
+				return client.GetContents(ctx, buildContentsRequest(arg.([]string)))
+			},
+		},
+		{
+			cmd: similarCmd, arg: "url",
+			input: &mcp.Schema{Type: "string", Description: "URL to find similar pages for"},
+			call: func(ctx context.Context, client *api.Client, arg interface{}, _ map[string]interface{}) (interface{}, error) {
+				return client.FindSimilar(ctx, buildSimilarRequest(arg.(string)))
+			},
+		},
+		{
+			cmd: answerCmd, arg: "query", input: query,
+			skip: []string{"stream", "output-schema", "session"},
+			extra: map[string]*mcp.Schema{
+				"outputSchema": {Type: "object", Description: "JSON Schema the answer must follow"},
+			},
+			call: func(ctx context.Context, client *api.Client, arg interface{}, args map[string]interface{}) (interface{}, error) {
+				req, schema, err := buildAnswerRequest(arg.(string))
+				if err != nil {
+					return nil, err
+				}
+				if raw, ok := args["outputSchema"]; ok {
+					req.OutputSchema = raw
+					if schema, err = answerSchema(req); err != nil {
+						return nil, err
+					}
+				}
+				resp, structured, err := askAnswer(ctx, client, req, schema)
+				if err != nil {
+					return nil, err
+				}
+				return answerView(resp, structured), nil
+			},
+		},
+		{
+			cmd: contextCmd, arg: "query", input: query,
+			call: func(ctx context.Context, client *api.Client, arg interface{}, _ map[string]interface{}) (interface{}, error) {
+				return client.GetContext(ctx, buildContextRequest(arg.(string)))
+			},
+		},
+	}
+}
+
+// exposedFlags returns the flags of t.cmd that are tool inputs.
+func (t mcpTool) exposedFlags() []*pflag.Flag {
+	var flags []*pflag.Flag
+	t.cmd.LocalNonPersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if t.exposed(f.Name) {
+			flags = append(flags, f)
+		}
+	})
+	return flags
+}
+
+// schema builds the tool's input schema from its flags.
+func (t mcpTool) schema() *mcp.Schema {
+	closed := false
+	s := &mcp.Schema{
+		Type:                 "object",
+		Properties:           map[string]*mcp.Schema{t.arg: t.input},
+		Required:             []string{t.arg},
+		AdditionalProperties: &closed,
+	}
+	for name, p := range t.extra {
+		s.Properties[name] = p
+	}
+	for _, f := range t.exposedFlags() {
+		s.Properties[f.Name] = flagSchema(t.cmd, f)
+	}
+	return s
+}
+
+// flagSchema describes a flag as a JSON Schema property. String flags with
+// shell completions are restricted to the completed values.
+func flagSchema(cmd *cobra.Command, f *pflag.Flag) *mcp.Schema {
+	s := &mcp.Schema{Description: f.Usage}
+	switch f.Value.Type() {
+	case "bool":
+		s.Type = "boolean"
+		if f.DefValue == "true" {
+			s.Default = true
+		}
+	case "int":
+		s.Type = "integer"
+		if n, err := strconv.Atoi(f.DefValue); err == nil && n != 0 {
+			s.Default = n
+		}
+	case "float64":
+		s.Type = "number"
+		if n, err := strconv.ParseFloat(f.DefValue, 64); err == nil && n != 0 {
+			s.Default = n
+		}
+	case "stringSlice":
+		s.Type = "array"
+		s.Items = &mcp.Schema{Type: "string"}
+	default:
+		s.Type = "string"
+		if f.DefValue != "" {
+			s.Default = f.DefValue
+		}
+		if complete, ok := cmd.GetFlagCompletionFunc(f.Name); ok {
+			values, _ := complete(cmd, nil, "")
+			for _, v := range values {
+				value, _, _ := strings.Cut(v, "\t")
+				s.Enum = append(s.Enum, value)
+			}
+		}
+	}
+	return s
+}
+
+// resetFlags restores all of the command's flags, including those not
+// exposed, to their defaults between calls, then fills the exposed ones
+// from the environment and config file as a command-line run would.
+func (t mcpTool) resetFlags() error {
+	flags := t.cmd.LocalNonPersistentFlags()
+	flags.VisitAll(resetFlag)
+	if err := applyConfig(t.cmd); err != nil {
+		return err
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		if !t.exposed(f.Name) {
+			resetFlag(f)
+			delete(configuredFlags, f)
+		}
+	})
+	return nil
+}
+
+// resetFlag restores f to its default and marks it unchanged.
+func resetFlag(f *pflag.Flag) {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		_ = sv.Replace(nil)
+	} else {
+		_ = f.Value.Set(f.DefValue)
+	}
+	f.Changed = false
+}
+
+// setFlag sets f from a JSON argument value.
+func setFlag(f *pflag.Flag, v interface{}) error {
+	invalid := fmt.Errorf("%s: expected %s, got %T", f.Name, f.Value.Type(), v)
+	var err error
+	switch f.Value.Type() {
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return invalid
+		}
+		err = f.Value.Set(strconv.FormatBool(b))
+	case "int":
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return invalid
+		}
+		err = f.Value.Set(strconv.FormatInt(int64(n), 10))
+	case "float64":
+		n, ok := v.(float64)
+		if !ok {
+			return invalid
+		}
+		err = f.Value.Set(strconv.FormatFloat(n, 'f', -1, 64))
+	case "stringSlice":
+		items, ok := stringList(v)
+		if !ok {
+			return invalid
+		}
+		err = f.Value.(pflag.SliceValue).Replace(items)
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return invalid
+		}
+		err = f.Value.Set(s)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Name, err)
+	}
+	f.Changed = true
+	return nil
+}
+
+// stringList converts a JSON array of strings.
+func stringList(v interface{}) ([]string, bool) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
+
+// handler returns the MCP handler for t, which sets the command's flags
+// from the arguments and calls the API with the client from getClient.
+// The flags and commandName are shared globals; this relies on mcp.Server
+// never running two handlers at once.
+func (t mcpTool) handler(getClient func() (*api.Client, error)) mcp.Handler {
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		if err := t.resetFlags(); err != nil {
+			return nil, err
+		}
+		commandName = "mcp " + t.cmd.Name()
+
+		for name, v := range args {
+			if name == t.arg || t.extra[name] != nil {
+				continue
+			}
+			f := t.cmd.LocalNonPersistentFlags().Lookup(name)
+			if f == nil || !t.exposed(name) {
+				return nil, &output.UsageError{Err: fmt.Errorf("unknown argument %q", name)}
+			}
+			if err := setFlag(f, v); err != nil {
+				return nil, &output.UsageError{Err: err}
+			}
+		}
+
+		var arg interface{}
+		switch t.input.Type {
+		case "array":
+			list, ok := stringList(args[t.arg])
+			if !ok || len(list) == 0 {
+				return nil, &output.UsageError{Err: fmt.Errorf("%s: expected a non-empty array of strings", t.arg)}
+			}
+			arg = list
+		default:
+			s, ok := args[t.arg].(string)
+			if !ok || strings.TrimSpace(s) == "" {
+				return nil, &output.UsageError{Err: fmt.Errorf("%s: required", t.arg)}
+			}
+			arg = s
+		}
+
+		client, err := getClient()
+		if err != nil {
+			return nil, err
+		}
+		// History and the spend ledger mark the call as made for the client.
+		return t.call(api.WithCaller(ctx, mcpCaller), client, arg, args)
+	}
+}
+
+// exposed reports whether the named flag is a tool input.
+func (t mcpTool) exposed(name string) bool {
+	if mcpSkipFlags[name] {
+		return false
+	}
+	for _, s := range t.skip {
+		if s == name {
+			return false
+		}
+	}
+	return true
+}
+
+// toolDescription is the command's long help without its examples.
+func toolDescription(cmd *cobra.Command) string {
+	desc, _, _ := strings.Cut(cmd.Long, "Examples:")
+	if desc = strings.TrimSpace(desc); desc == "" {
+		return cmd.Short
+	}
+	return desc
+}
+
+func runMCPServe(cmd *cobra.Command, args []string) error {
+	if flagDryRun != "" {
+		return &output.UsageError{Err: fmt.Errorf("--dry-run is not supported by 'exa mcp serve'")}
+	}
+
+	var client *api.Client
+	getClient := func() (*api.Client, error) {
+		if client != nil {
+			return client, nil
+		}
+		c, err := newClient()
+		if err != nil {
+			return nil, err
+		}
+		client = c
+		return client, nil
+	}
+
+	server := &mcp.Server{
+		Name:      "exa",
+		Version:   appVersion,
+		ErrorData: func(err error) interface{} { return output.Classify(err) },
+	}
+	for _, t := range mcpTools() {
+		server.Tools = append(server.Tools, mcp.Tool{
+			Name:        t.cmd.Name(),
+			Title:       t.cmd.Short,
+			Description: toolDescription(t.cmd),
+			InputSchema: t.schema(),
+			Handler:     t.handler(getClient),
+		})
+	}
+
+	DebugLog("MCP server ready with %d tools", len(server.Tools))
+	return server.Serve(newContext(), cmd.InOrStdin(), cmd.OutOrStdout())
+}
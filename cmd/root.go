@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/roboalchemist/exa-cli/pkg/api"
 	"github.com/roboalchemist/exa-cli/pkg/auth"
+	"github.com/roboalchemist/exa-cli/pkg/cache"
+	"github.com/roboalchemist/exa-cli/pkg/cassette"
 	"github.com/roboalchemist/exa-cli/pkg/output"
 	"github.com/spf13/cobra"
 )
@@ -17,10 +23,26 @@ var appVersion = "dev"
 var (
 	flagJSON      bool
 	flagPlaintext bool
+	flagNDJSON    bool
+	flagFormat    string
+	flagNoHeader  bool
+	flagTemplate  string
+	flagTmplFile  string
+	flagProfile   string
 	flagNoColor   bool
 	flagDebug     bool
 	flagFields    string
 	flagJQ        string
+	flagRetries   int
+	flagRetryWait time.Duration
+	flagCache     bool
+	flagNoCache   bool
+	flagCacheTTL  time.Duration
+	flagMaxCost   float64
+	flagDailyBdgt float64
+	flagMonthBdgt float64
+	flagDryRun    string
+	flagNoHistory bool
 )
 
 var rootCmd = &cobra.Command{
@@ -44,66 +66,285 @@ Examples:
 	Version:       appVersion,
 	SilenceUsage:  true,
 	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+		auth.SetProfile(flagProfile)
+		if flagFormat != "" {
+			m, err := output.ParseMode(flagFormat)
+			if err != nil {
+				return &output.UsageError{Err: err}
+			}
+			if (m.IsFeed() || m.IsCitation()) && !supportsFormat(cmd, m) {
+				return &output.UsageError{Err: fmt.Errorf("--format %s is not supported by '%s'", flagFormat, cmd.CommandPath())}
+			}
+		}
+		if err := loadTemplate(cmd); err != nil {
+			return err
+		}
+		if err := validateDryRun(os.Args[1:]); err != nil {
+			return err
+		}
+		commandName = strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+		return nil
+	},
 }
 
+// formatsAnnotation lists the result-only output formats a command
+// supports, comma separated. Other commands reject them.
+const formatsAnnotation = "exa/formats"
+
+// Values for formatsAnnotation.
+const (
+	feedFormats     = "atom,rss"
+	citationFormats = "bibtex,ris,csljson"
+)
+
+// supportsFormat reports whether cmd lists mode m in its formatsAnnotation.
+func supportsFormat(cmd *cobra.Command, m output.Mode) bool {
+	for _, name := range strings.Split(cmd.Annotations[formatsAnnotation], ",") {
+		if named, err := output.ParseMode(name); err == nil && named == m {
+			return true
+		}
+	}
+	return false
+}
+
+// commandName is the running command path without the root name, e.g.
+// "search" or "auth status", recorded in the spend ledger and history.
+var commandName string
+
 func init() {
 	pf := rootCmd.PersistentFlags()
 	pf.BoolVarP(&flagJSON, "json", "j", false, "JSON output")
 	pf.BoolVarP(&flagPlaintext, "plaintext", "p", false, "Tab-separated output for piping")
+	pf.BoolVar(&flagNDJSON, "ndjson", false, "Newline-delimited JSON: one compact object per result")
+	pf.StringVar(&flagFormat, "format", "", "Output format: table, plaintext, json, ndjson, csv, tsv, markdown, atom, rss, bibtex, ris, csljson")
+	pf.BoolVar(&flagNoHeader, "no-header", false, "Omit the header row in csv/tsv output")
+	pf.StringVar(&flagTemplate, "template", "", "Render the response with a Go text/template")
+	pf.StringVar(&flagTmplFile, "template-file", "", "Read the --template from a file")
+	pf.StringVar(&flagProfile, "profile", "", "Auth profile to use (overrides EXA_PROFILE)")
 	pf.BoolVar(&flagNoColor, "no-color", false, "Disable colored output")
 	pf.BoolVar(&flagDebug, "debug", false, "Verbose logging to stderr")
-	pf.StringVar(&flagFields, "fields", "", "Comma-separated fields for JSON output")
+	pf.StringVar(&flagFields, "fields", "", "Comma-separated fields for JSON output, or columns for csv/tsv (dotted paths allowed)")
 	pf.StringVar(&flagJQ, "jq", "", "JQ expression to filter JSON output")
+	pf.IntVar(&flagRetries, "retries", 2, "Retries for rate-limited or failed requests (0 disables)")
+	pf.DurationVar(&flagRetryWait, "retry-max-wait", 30*time.Second, "Max wait between retries")
+	pf.BoolVar(&flagCache, "cache", false, "Cache search/contents/similar responses on disk")
+	pf.BoolVar(&flagNoCache, "no-cache", false, "Bypass the response cache")
+	pf.DurationVar(&flagCacheTTL, "cache-ttl", 24*time.Hour, "Max age of cached responses")
+	pf.BoolVar(&flagNoHistory, "no-history", false, "Do not log calls to the local history")
+	pf.Float64Var(&flagMaxCost, "max-cost", 0, "Refuse further API calls once this invocation has spent this many dollars (0 = no limit)")
+	pf.Float64Var(&flagDailyBdgt, "daily-budget", 0, "Refuse API calls once today's recorded spend reaches this many dollars (0 = no limit)")
+	pf.Float64Var(&flagMonthBdgt, "monthly-budget", 0, "Refuse API calls once this month's recorded spend reaches this many dollars (0 = no limit)")
+	pf.StringVar(&flagDryRun, "dry-run", "", "Print the request (json, or curl with --dry-run=curl) and estimated cost instead of sending it")
+	pf.Lookup("dry-run").NoOptDefVal = dryRunJSON
 }
 
 // GetOutputOptions builds output.Options from global flags.
 func GetOutputOptions() output.Options {
 	opts := output.Options{
-		NoColor: flagNoColor,
-		Debug:   flagDebug,
-		Fields:  flagFields,
-		JQ:      flagJQ,
+		NoColor:  flagNoColor,
+		Debug:    flagDebug,
+		Fields:   flagFields,
+		JQ:       flagJQ,
+		NoHeader: flagNoHeader,
 	}
 	switch {
+	case flagNDJSON:
+		opts.Mode = output.ModeNDJSON
 	case flagJSON:
 		opts.Mode = output.ModeJSON
 	case flagPlaintext:
 		opts.Mode = output.ModePlaintext
 	default:
 		opts.Mode = output.ModeTable
+		if p, _, err := auth.ActiveProfile(); err == nil && p.Output != "" {
+			if m, err := output.ParseMode(p.Output); err == nil {
+				opts.Mode = m
+			}
+		}
+	}
+	if m, err := output.ParseMode(flagFormat); err == nil {
+		opts.Mode = m
+	}
+	if flagTemplate != "" {
+		opts.Mode = output.ModeTemplate
+		opts.Template = flagTemplate
 	}
 	return opts
 }
 
+// loadTemplate reads --template-file into flagTemplate and checks that the
+// template parses.
+func loadTemplate(cmd *cobra.Command) error {
+	if flagTmplFile != "" {
+		if flagTemplate != "" {
+			return &output.UsageError{Err: fmt.Errorf("--template and --template-file cannot be combined")}
+		}
+		data, err := os.ReadFile(flagTmplFile)
+		if err != nil {
+			return fmt.Errorf("read template file: %w", err)
+		}
+		flagTemplate = string(data)
+	}
+	if flagTemplate == "" {
+		return nil
+	}
+	if err := output.CheckTemplate(flagTemplate, templateSamples[cmd]); err != nil {
+		return &output.UsageError{Err: err}
+	}
+	return nil
+}
+
+// templateSamples are the response types commands render with --template,
+// which loadTemplate executes the template against before any request.
+var templateSamples = map[*cobra.Command]interface{}{
+	searchCmd:   &api.SearchResponse{},
+	similarCmd:  &api.FindSimilarResponse{},
+	contentsCmd: &api.ContentsResponse{},
+	answerCmd:   &api.AnswerResponse{},
+	contextCmd:  &api.ContextResponse{},
+	usageCmd:    &api.UsageResponse{},
+}
+
 // newClient creates an authenticated API client.
 func newClient() (*api.Client, error) {
+	replay := os.Getenv("EXA_REPLAY")
+
 	apiKey, err := auth.GetAPIKey()
+	if err != nil {
+		if replay == "" && flagDryRun == "" {
+			return nil, err
+		}
+		// Replayed and dry-run requests never reach the API, so no key is
+		// needed.
+		apiKey = cassette.Redacted
+	}
+
+	return newClientWithKey(apiKey)
+}
+
+// newClientWithKey creates an API client for apiKey with the global
+// transport, retry, cache and debug settings.
+func newClientWithKey(apiKey string) (*api.Client, error) {
+	baseURL := auth.GetBaseURL()
+	client := api.NewClient(baseURL, apiKey)
+
+	rt, err := cassetteTransport(os.Getenv("EXA_REPLAY"), os.Getenv("EXA_RECORD"))
 	if err != nil {
 		return nil, err
 	}
+	if rt != nil {
+		client.SetTransport(rt)
+	}
+
+	retry := api.DefaultRetryPolicy()
+	retry.MaxAttempts = flagRetries + 1
+	retry.MaxDelay = flagRetryWait
+	client.SetRetryPolicy(retry)
+
+	if flagCache && !flagNoCache && flagDryRun == "" {
+		c, err := openCache()
+		if err != nil {
+			return nil, err
+		}
+		client.SetCache(c.Scoped(baseURL, apiKey))
+	}
 
-	client := api.NewClient(auth.GetBaseURL(), apiKey)
 	if flagDebug {
 		client.SetDebug(DebugLog)
 	}
+	if flagDryRun != "" {
+		client.AddGuard(dryRunGuard)
+	}
+	trackSpend(client)
+	recordHistory(client)
 	return client, nil
 }
 
+// cassetteTransport returns a replaying or recording transport when
+// EXA_REPLAY or EXA_RECORD is set, or nil otherwise.
+func cassetteTransport(replay, record string) (http.RoundTripper, error) {
+	switch {
+	case replay != "" && record != "":
+		return nil, fmt.Errorf("EXA_REPLAY and EXA_RECORD cannot both be set")
+	case replay != "":
+		r, err := cassette.NewReplayer(replay)
+		if err != nil {
+			return nil, fmt.Errorf("load cassette: %w", err)
+		}
+		DebugLog("Replaying responses from %s", replay)
+		return r, nil
+	case record != "":
+		r, err := cassette.NewRecorder(record, nil)
+		if err != nil {
+			return nil, fmt.Errorf("open cassette: %w", err)
+		}
+		DebugLog("Recording responses to %s", record)
+		return r, nil
+	}
+	return nil, nil
+}
+
+// openCache opens the response cache in the XDG cache directory.
+func openCache() (*cache.Cache, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, fmt.Errorf("locate cache dir: %w", err)
+	}
+	return cache.New(dir, flagCacheTTL), nil
+}
+
 // newContext returns a background context.
 func newContext() context.Context {
 	return context.Background()
 }
 
+// markUsageErrors makes the flag and argument errors cobra reports for c
+// and its subcommands usage errors.
+func markUsageErrors(c *cobra.Command) {
+	c.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &output.UsageError{Err: err}
+	})
+	if args := c.Args; args != nil {
+		c.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return &output.UsageError{Err: err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range c.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
 // Execute runs the root command.
 func Execute() error {
+	markUsageErrors(rootCmd)
 	err := rootCmd.Execute()
+	if errors.Is(err, api.ErrDryRun) {
+		return nil
+	}
 	if err != nil {
+		// Cobra reports a mistyped command name while resolving the command,
+		// before any validator runs.
+		if strings.HasPrefix(err.Error(), "unknown command ") {
+			err = &output.UsageError{Err: err}
+		}
 		opts := GetOutputOptions()
 		output.RenderError(err, opts)
 	}
 	return err
 }
 
+// ExitCode maps an error returned by Execute to a process exit code.
+func ExitCode(err error) int {
+	return output.ExitCode(err)
+}
+
 // SetVersion sets the application version.
 func SetVersion(v string) {
 	appVersion = v
@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/auth"
 	"github.com/roboalchemist/exa-cli/pkg/output"
 	"github.com/spf13/cobra"
 )
@@ -46,14 +48,24 @@ Examples:
   exa search "machine learning" --category research_paper
   exa search "golang tutorials" --include-domains go.dev,gobyexample.com
   exa search "AI news" --start-date 2025-01-01 --highlights
-  exa search "React hooks" --json --fields title,url,score`,
-	Args:       cobra.MinimumNArgs(1),
-	SuggestFor: []string{"find", "query", "lookup"},
-	RunE:       runSearch,
+  exa search "React hooks" --json --fields title,url,score
+  exa search --batch queries.txt --concurrency 8 > results.ndjson
+  exa search "rust async" --max-age-hours 24 --format atom > rust.xml`,
+	Args:        batchArgs(cobra.MinimumNArgs(1)),
+	SuggestFor:  []string{"find", "query", "lookup"},
+	Annotations: map[string]string{formatsAnnotation: feedFormats + "," + citationFormats},
+	RunE:        runSearch,
 }
 
 func init() {
-	f := searchCmd.Flags()
+	addSearchFlags(searchCmd)
+	addBatchFlags(searchCmd)
+	rootCmd.AddCommand(searchCmd)
+}
+
+// addSearchFlags registers the flags that build a SearchRequest on cmd.
+func addSearchFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
 	f.IntVarP(&searchNumResults, "num-results", "n", 25, "Max results (max 100)")
 	f.StringVarP(&searchType, "type", "t", "auto", "Search type: auto|fast|deep|neural")
 	f.StringVar(&searchCategory, "category", "", "Category: company|news|research_paper|tweet|github|etc")
@@ -72,7 +84,7 @@ func init() {
 	f.BoolVar(&searchModeration, "moderation", false, "Enable content safety moderation")
 	f.IntVar(&searchSubpages, "subpages", 0, "Number of subpages to crawl per result")
 
-	_ = searchCmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	_ = cmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{
 			"auto\tCombines methods with reranker (default)",
 			"fast\tLow latency (<400ms)",
@@ -81,14 +93,12 @@ func init() {
 		}, cobra.ShellCompDirectiveNoFileComp
 	})
 
-	_ = searchCmd.RegisterFlagCompletionFunc("category", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	_ = cmd.RegisterFlagCompletionFunc("category", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{
 			"company", "news", "research_paper", "tweet", "github",
 			"linkedin_profile", "pdf", "personal_site",
 		}, cobra.ShellCompDirectiveNoFileComp
 	})
-
-	rootCmd.AddCommand(searchCmd)
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
@@ -97,8 +107,45 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	applyProfileSearchType(cmd)
+
+	if batchFile != "" {
+		return runBatch(client, func(ctx context.Context, item batchItem) (interface{}, *api.CostInfo, error) {
+			req := buildSearchRequest(item.Query)
+			if err := item.apply(req); err != nil {
+				return nil, nil, err
+			}
+			resp, err := client.Search(ctx, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			return resp, batchCost(resp.CostDollars, resp.Cached), nil
+		})
+	}
+
+	query := strings.Join(args, " ")
+	resp, err := client.Search(newContext(), buildSearchRequest(query))
+	if err != nil {
+		return err
+	}
+
+	return renderSearch(query, resp)
+}
+
+// applyProfileSearchType uses the active profile's search type unless
+// --type was given or configured.
+func applyProfileSearchType(cmd *cobra.Command) {
+	if !flagGiven(cmd, "type") {
+		if p, _, err := auth.ActiveProfile(); err == nil && p.SearchType != "" {
+			searchType = p.SearchType
+		}
+	}
+}
+
+// buildSearchRequest builds a SearchRequest for query from the search flags.
+func buildSearchRequest(query string) *api.SearchRequest {
 	req := &api.SearchRequest{
-		Query:      strings.Join(args, " "),
+		Query:      query,
 		NumResults: searchNumResults,
 	}
 
@@ -165,15 +212,23 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	resp, err := client.Search(newContext(), req)
-	if err != nil {
-		return err
-	}
+	return req
+}
 
+// renderSearch prints a search response for query in the selected output
+// mode.
+func renderSearch(query string, resp *api.SearchResponse) error {
 	opts := GetOutputOptions()
+	opts.FeedTitle = "Exa search: " + query
+	if opts.Mode.IsFeed() {
+		opts.FeedSeen = firstSeen()
+	}
 
-	if opts.Mode == output.ModeJSON {
-		return output.RenderJSON(resp, opts)
+	if opts.Mode.IsJSON() {
+		if err := output.RenderJSON(resp, opts); err != nil {
+			return err
+		}
+		return resultsErr(len(resp.Results))
 	}
 
 	td := output.TableData{
@@ -193,13 +248,13 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		td.Rows = append(td.Rows, []string{title, r.URL, date, fmt.Sprintf("%.2f", r.Score)})
 	}
 
-	footer := fmt.Sprintf("%d results | Type: %s", len(resp.Results), searchType)
-	if resp.CostDollars != nil {
-		footer = fmt.Sprintf("Cost: $%.4f | %s", resp.CostDollars.Total, footer)
-	}
-	td.Footer = footer
+	td.Footer = costFooter(resp.CostDollars, resp.Cached,
+		fmt.Sprintf("%d results | Type: %s", len(resp.Results), searchType))
 
-	return output.RenderTable(td, resp, opts)
+	if err := output.RenderTable(td, resp, opts); err != nil {
+		return err
+	}
+	return resultsErr(len(resp.Results))
 }
 
 func truncateStr(s string, max int) string {
@@ -208,3 +263,27 @@ func truncateStr(s string, max int) string {
 	}
 	return s[:max-3] + "..."
 }
+
+// resultsErr returns output.ErrNoResults for an empty result set so the
+// process exits with a distinct code. Machine-readable output is a complete
+// document even when empty, so it succeeds.
+func resultsErr(n int) error {
+	if n == 0 && GetOutputOptions().Mode.IsHuman() {
+		return output.ErrNoResults
+	}
+	return nil
+}
+
+// costFooter prefixes a table footer with the response cost. Cached
+// responses cost nothing, so their original cost is labelled as such.
+func costFooter(cost *api.CostInfo, cached bool, footer string) string {
+	switch {
+	case cached && cost != nil:
+		return fmt.Sprintf("Cached (original cost $%.4f) | %s", cost.Total, footer)
+	case cached:
+		return "Cached | " + footer
+	case cost != nil:
+		return fmt.Sprintf("Cost: $%.4f | %s", cost.Total, footer)
+	}
+	return footer
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/roboalchemist/exa-cli/pkg/proxy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen    string
+	serveRateLimit int
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a local Exa API proxy that injects the API key",
+	Long: `Serve an Exa-compatible REST API so other tools can call Exa without
+holding the API key. Point them at the proxy with EXA_API_URL.
+
+POST /search, /contents, /findSimilar, /answer and /context are forwarded
+with the configured key (any x-api-key sent by the client is dropped).
+Streaming /answer requests are passed through as server-sent events.
+Responses use the on-disk cache unless --no-cache is given, and the retry,
+--max-cost and budget settings apply to every request.
+
+Each request is accounted to a client: the X-Exa-Client header, else the
+hash of the client's x-api-key, else its IP address. Costs are added to
+the spend ledger ('exa spend --by client') and GET /_proxy/stats returns
+totals since the proxy started.
+
+Examples:
+  exa serve --listen :8080
+  exa serve --listen 127.0.0.1:8080 --rate-limit 60 --daily-budget 5
+
+  # In another service:
+  EXA_API_URL=http://localhost:8080 EXA_API_KEY=billing-service exa search "..."
+  curl -s localhost:8080/search -H 'X-Exa-Client: crawler' -d '{"query":"rust async"}'`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "Address to listen on, e.g. :8080")
+	serveCmd.Flags().IntVar(&serveRateLimit, "rate-limit", 0, "Max requests per minute per client (0 = unlimited)")
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if flagDryRun != "" {
+		return &output.UsageError{Err: fmt.Errorf("--dry-run is not supported by 'exa serve'")}
+	}
+	if serveRateLimit < 0 {
+		return &output.UsageError{Err: fmt.Errorf("--rate-limit must not be negative")}
+	}
+	// The proxy caches by default; --no-cache or --cache=false turns it off.
+	if !flagGiven(cmd, "cache") {
+		flagCache = true
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	server := &proxy.Server{
+		Client:  client,
+		Limiter: proxy.NewLimiter(serveRateLimit),
+		Log: func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, time.Now().Format("2006-01-02 15:04:05")+" "+format+"\n", args...)
+		},
+	}
+
+	ln, err := net.Listen("tcp", serveListen)
+	if err != nil {
+		return &output.UsageError{Err: fmt.Errorf("listen on %s: %w", serveListen, err)}
+	}
+	srv := &http.Server{Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Exa proxy listening on http://%s (cache: %t, rate limit: %s)\n",
+		ln.Addr(), flagCache && !flagNoCache, rateLimitLabel(serveRateLimit))
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	for _, st := range server.Stats() {
+		fmt.Fprintf(os.Stderr, "%s: %d requests, %d cached, %d errors, $%.4f\n",
+			st.Client, st.Requests, st.CacheHits, st.Errors, st.CostDollars)
+	}
+	return nil
+}
+
+func rateLimitLabel(perMinute int) string {
+	if perMinute == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d/min per client", perMinute)
+}
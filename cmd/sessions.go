@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/roboalchemist/exa-cli/pkg/session"
+	"github.com/spf13/cobra"
+)
+
+var answerSessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manage saved answer conversations",
+	Long: `List, show, export and delete conversations saved with 'exa answer --session'.
+
+Each session keeps its questions, answers and citations in
+$XDG_DATA_HOME/exa/sessions/<name>.json (~/.local/share by default). A
+follow-up question is sent with the most recent turns as context.
+
+'exa answer sessions' is only taken as this command when a subcommand
+follows. To ask a question that starts with the word "sessions", quote it or
+put it after '--'. 'exa sessions' is an alias.
+
+Examples:
+  exa answer sessions list
+  exa answer sessions show rag
+  exa answer sessions export rag > rag.md
+  exa answer sessions export rag --format bibtex > rag.bib
+  exa answer sessions delete rag
+  exa answer -- sessions of the UN general assembly`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runSessions,
+}
+
+var sessionsCmd = &cobra.Command{
+	Use:     "sessions",
+	Aliases: []string{"session"},
+	Short:   "Alias for 'exa answer sessions'",
+	Long:    answerSessionsCmd.Long,
+	Args:    cobra.ArbitraryArgs,
+	RunE:    runSessions,
+}
+
+// newSessionsSubcommands returns the session subcommands. Each parent
+// needs its own copies, as a cobra command has a single parent.
+func newSessionsSubcommands() []*cobra.Command {
+	return []*cobra.Command{
+		{
+			Use:   "list",
+			Short: "List saved conversations",
+			Args:  cobra.NoArgs,
+			RunE:  runSessionsList,
+		},
+		{
+			Use:   "show <name>",
+			Short: "Show a conversation's questions, answers and sources",
+			Args:  cobra.ExactArgs(1),
+			RunE:  runSessionsShow,
+		},
+		{
+			Use:   "export <name>",
+			Short: "Export a conversation as Markdown, JSON or citations",
+			Long: `Write a conversation to stdout: a Markdown transcript by default, the full
+session with --json, or every cited source with --format bibtex, ris or
+csljson.`,
+			Args:        cobra.ExactArgs(1),
+			Annotations: map[string]string{formatsAnnotation: citationFormats},
+			RunE:        runSessionsExport,
+		},
+		{
+			Use:     "delete <name>",
+			Aliases: []string{"rm"},
+			Short:   "Delete a saved conversation",
+			Args:    cobra.ExactArgs(1),
+			RunE:    runSessionsDelete,
+		},
+	}
+}
+
+func init() {
+	answerSessionsCmd.AddCommand(newSessionsSubcommands()...)
+	answerCmd.AddCommand(answerSessionsCmd)
+	sessionsCmd.AddCommand(newSessionsSubcommands()...)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+// runSessions runs when no known subcommand follows 'sessions'. Under
+// 'exa answer' that is most likely an unquoted question.
+func runSessions(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return cmd.Help()
+	}
+	err := fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())
+	if cmd.Parent() == answerCmd {
+		err = fmt.Errorf("%w; to ask a question starting with \"sessions\", quote it or put it after '--': exa answer -- sessions %s",
+			err, strings.Join(args, " "))
+	}
+	return &output.UsageError{Err: err}
+}
+
+// openSessions opens the session store in the XDG data directory.
+func openSessions() (*session.Store, error) {
+	dir, err := session.DefaultDir()
+	if err != nil {
+		return nil, fmt.Errorf("locate sessions: %w", err)
+	}
+	return session.Open(dir), nil
+}
+
+// loadSession reads the named session, reporting unknown names as usage
+// errors.
+func loadSession(name string) (*session.Session, error) {
+	if err := session.ValidateName(name); err != nil {
+		return nil, &output.UsageError{Err: err}
+	}
+	store, err := openSessions()
+	if err != nil {
+		return nil, err
+	}
+	s, err := store.Load(name)
+	if errors.Is(err, session.ErrNotFound) {
+		return nil, &output.UsageError{Err: fmt.Errorf("%w; see 'exa sessions list'", err)}
+	}
+	return s, err
+}
+
+// answerTurn is a question asked within the --session conversation, if
+// one is selected.
+type answerTurn struct {
+	question string
+	query    string // question with conversation context
+	store    *session.Store
+	conv     *session.Session
+}
+
+// newAnswerTurn loads the --session conversation and composes the query
+// for question.
+func newAnswerTurn(question string) (*answerTurn, error) {
+	t := &answerTurn{question: question, query: question}
+	if answerSession == "" {
+		return t, nil
+	}
+	if err := session.ValidateName(answerSession); err != nil {
+		return nil, &output.UsageError{Err: err}
+	}
+	store, err := openSessions()
+	if err != nil {
+		return nil, err
+	}
+	conv, err := store.LoadOrNew(answerSession)
+	if err != nil {
+		return nil, err
+	}
+	t.store, t.conv = store, conv
+	t.query = conv.Compose(question)
+	DebugLog("Session %s: turn %d, query of %d chars", conv.Name, len(conv.Turns)+1, len(t.query))
+	return t, nil
+}
+
+// save records the answer in the conversation. It does nothing without
+// --session.
+func (t *answerTurn) save(resp *api.AnswerResponse) error {
+	if t.conv == nil {
+		return nil
+	}
+	t.conv.Add(t.question, t.query, resp, time.Now())
+	if err := t.store.Save(t.conv); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+// sessionView is the JSON form of a session in 'exa sessions list'.
+type sessionView struct {
+	Name         string    `json:"name"`
+	Turns        int       `json:"turns"`
+	Created      time.Time `json:"created"`
+	Updated      time.Time `json:"updated"`
+	CostDollars  float64   `json:"costDollars"`
+	LastQuestion string    `json:"lastQuestion,omitempty"`
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) error {
+	store, err := openSessions()
+	if err != nil {
+		return err
+	}
+	sessions, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	views := []sessionView{}
+	td := output.TableData{Headers: []string{"NAME", "TURNS", "UPDATED", "COST", "LAST QUESTION"}}
+	for _, s := range sessions {
+		v := sessionView{Name: s.Name, Turns: len(s.Turns), Created: s.Created, Updated: s.Updated, CostDollars: s.Cost()}
+		if len(s.Turns) > 0 {
+			v.LastQuestion = s.Turns[len(s.Turns)-1].Question
+		}
+		views = append(views, v)
+		td.Rows = append(td.Rows, []string{
+			s.Name,
+			strconv.Itoa(len(s.Turns)),
+			s.Updated.Local().Format("2006-01-02 15:04"),
+			fmt.Sprintf("$%.4f", v.CostDollars),
+			truncateStr(v.LastQuestion, 50),
+		})
+	}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(views, opts)
+	}
+	td.Footer = store.Dir()
+	return output.RenderTable(td, views, opts)
+}
+
+func runSessionsShow(cmd *cobra.Command, args []string) error {
+	s, err := loadSession(args[0])
+	if err != nil {
+		return err
+	}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(s, opts)
+	}
+	if !opts.Mode.IsHuman() {
+		return renderSessionTurns(s, opts)
+	}
+
+	cyan := color.New(color.FgCyan)
+	for i, t := range s.Turns {
+		if i > 0 {
+			fmt.Println()
+		}
+		cyan.Printf("Q%d: %s\n", i+1, t.Question)
+		fmt.Println(t.Answer)
+		for j, c := range t.Citations {
+			fmt.Printf("  %d. %s — %s\n", j+1, c.Title, c.URL)
+		}
+	}
+	fmt.Printf("\n%d turns | Cost: $%.4f | Updated %s\n", len(s.Turns), s.Cost(), s.Updated.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+// renderSessionTurns renders one row per turn for delimited, markdown and
+// template output.
+func renderSessionTurns(s *session.Session, opts output.Options) error {
+	td := output.TableData{Headers: []string{"TURN", "QUESTION", "ANSWER", "SOURCES"}}
+	for i, t := range s.Turns {
+		td.Rows = append(td.Rows, []string{strconv.Itoa(i + 1), t.Question, t.Answer, strconv.Itoa(len(t.Citations))})
+	}
+	return output.RenderTable(td, s.Turns, opts)
+}
+
+func runSessionsExport(cmd *cobra.Command, args []string) error {
+	s, err := loadSession(args[0])
+	if err != nil {
+		return err
+	}
+
+	opts := GetOutputOptions()
+	switch {
+	case opts.Mode.IsJSON():
+		return output.RenderJSON(s, opts)
+	case opts.Mode.IsCitation():
+		return output.RenderTable(output.TableData{}, &api.AnswerResponse{Citations: s.Citations()}, opts)
+	case opts.Mode.IsHuman(), opts.Mode == output.ModeMarkdown:
+		return s.WriteMarkdown(os.Stdout)
+	}
+	return renderSessionTurns(s, opts)
+}
+
+func runSessionsDelete(cmd *cobra.Command, args []string) error {
+	if err := session.ValidateName(args[0]); err != nil {
+		return &output.UsageError{Err: err}
+	}
+	store, err := openSessions()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(args[0]); err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			return &output.UsageError{Err: err}
+		}
+		return err
+	}
+	output.Success(fmt.Sprintf("Deleted session %q", args[0]), GetOutputOptions())
+	return nil
+}
@@ -0,0 +1,519 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var shellExport string
+
+// shellHelp lists the shell's commands.
+const shellHelp = `Any line not starting with ':' is a search query. Commands:
+  :search QUERY       Search with the current filters
+  :results            List the current results again
+  :open N|URL ...     Fetch the contents of results N (or URLs)
+  :similar N|URL      Find pages similar to result N; they become the current results
+  :answer QUESTION    Ask a question and get a cited answer
+  :set FLAG VALUE     Set a filter, e.g. ':set type deep', ':set include-domains a.com,b.org'
+  :unset FLAG         Restore a filter to its default
+  :filters            Show the filters set with :set
+  :export FILE [FMT]  Write every result seen this session (json, ndjson, csv, tsv,
+                      markdown, atom, rss, bibtex, ris, csljson; default from extension)
+  :help               Show this list (also 'help')
+  :quit               Leave the session (also 'quit', 'exit' or Ctrl-D)`
+
+var shellCmd = &cobra.Command{
+	Use:     "shell",
+	Aliases: []string{"repl"},
+	Short:   "Interactive research session: search, browse, follow up and export",
+	Long: `Start an interactive session that reads one command per line from stdin.
+
+` + shellHelp + `
+
+':set' accepts any search, similar, contents or answer flag by name and
+applies it to every command that has it. Defaults come from the environment
+and config file as for the commands themselves. Output honours --json, --plaintext
+and other global output flags, so sessions can be scripted by piping commands
+in. A failed command prints its error and the session continues; when input
+is not a terminal, the shell then exits non-zero at the end.
+
+Examples:
+  exa shell
+  exa shell --export session.bib
+  printf ':set category research_paper\nattention is all you need\n:open 1\n' | exa shell`,
+	Args: cobra.NoArgs,
+	RunE: runShell,
+}
+
+func init() {
+	shellCmd.Flags().StringVar(&shellExport, "export", "", "Export collected results to this file when the session ends")
+
+	rootCmd.AddCommand(shellCmd)
+}
+
+// shellSkipFlags are command flags that 'set' cannot change.
+var shellSkipFlags = map[string]bool{"help": true, "batch": true, "concurrency": true, "stream": true}
+
+// shellExportModes maps export file extensions to output modes.
+var shellExportModes = map[string]output.Mode{
+	".json":    output.ModeJSON,
+	".ndjson":  output.ModeNDJSON,
+	".jsonl":   output.ModeNDJSON,
+	".csv":     output.ModeCSV,
+	".tsv":     output.ModeTSV,
+	".md":      output.ModeMarkdown,
+	".atom":    output.ModeAtom,
+	".xml":     output.ModeAtom,
+	".rss":     output.ModeRSS,
+	".bib":     output.ModeBibTeX,
+	".ris":     output.ModeRIS,
+	".csljson": output.ModeCSLJSON,
+}
+
+// shellSession is the state of an 'exa shell' session.
+type shellSession struct {
+	client    *api.Client
+	results   []api.SearchResult // Current listing, numbered from 1
+	collected []api.SearchResult // Every result seen, in first-seen order
+	index     map[string]int     // URL -> position in collected
+	filters   map[string]string  // Flag name -> value given with :set
+	calls     int
+	cost      float64
+}
+
+func runShell(cmd *cobra.Command, args []string) error {
+	if flagDryRun != "" {
+		return &output.UsageError{Err: fmt.Errorf("--dry-run is not supported by 'exa shell'")}
+	}
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	s := &shellSession{client: client, index: make(map[string]int), filters: make(map[string]string)}
+	client.AddObserver(func(call *api.Call) {
+		s.calls++
+		if call.Cost != nil && !call.Cached {
+			s.cost += call.Cost.Total
+		}
+	})
+
+	in := cmd.InOrStdin()
+	interactive := false
+	if f, ok := in.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil {
+			interactive = fi.Mode()&os.ModeCharDevice != 0
+		}
+	}
+	if interactive {
+		fmt.Fprintln(os.Stderr, "Exa shell. Type a query to search, ':help' for commands, ':quit' to leave.")
+	}
+
+	var commands, failed int
+	var firstErr error
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for {
+		if interactive {
+			fmt.Fprint(os.Stderr, "exa> ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest := "search", line
+		if cmd, ok := strings.CutPrefix(line, ":"); ok {
+			name, rest, _ = strings.Cut(cmd, " ")
+		} else if shellBareCommands[strings.ToLower(line)] {
+			name, rest = line, ""
+		}
+		name = strings.ToLower(name)
+		if name == "quit" || name == "exit" {
+			break
+		}
+		commands++
+		if err := s.run(name, strings.TrimSpace(rest)); err != nil {
+			if errors.Is(err, output.ErrNoResults) {
+				fmt.Fprintln(os.Stderr, "No results.")
+				continue
+			}
+			output.RenderError(err, GetOutputOptions())
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read commands: %w", err)
+	}
+
+	if shellExport != "" {
+		if err := s.export(shellExport, ""); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Session: %d API calls, $%.4f, %d results collected\n", s.calls, s.cost, len(s.collected))
+	if !interactive && firstErr != nil {
+		return fmt.Errorf("%d of %d shell commands failed: %w", failed, commands, firstErr)
+	}
+	return nil
+}
+
+// shellBareCommands may be typed without the ':' prefix when they are the
+// whole line.
+var shellBareCommands = map[string]bool{"help": true, "quit": true, "exit": true}
+
+// run executes one shell command.
+func (s *shellSession) run(name, rest string) error {
+	switch name {
+	case "help", "?":
+		fmt.Println(shellHelp)
+		return nil
+	case "search":
+		return s.search(rest)
+	case "results", "ls":
+		return s.list("")
+	case "open", "contents":
+		return s.open(strings.Fields(rest))
+	case "similar":
+		return s.similar(rest)
+	case "answer", "ask":
+		return s.answer(rest)
+	case "set":
+		flag, value, _ := strings.Cut(rest, " ")
+		return s.set(flag, strings.TrimSpace(value))
+	case "unset":
+		return s.unset(rest)
+	case "filters":
+		s.showFilters()
+		return nil
+	case "export":
+		fields := strings.Fields(rest)
+		if len(fields) == 0 || len(fields) > 2 {
+			return &output.UsageError{Err: fmt.Errorf("usage: :export FILE [FORMAT]")}
+		}
+		format := ""
+		if len(fields) == 2 {
+			format = fields[1]
+		}
+		return s.export(fields[0], format)
+	}
+	return &output.UsageError{Err: fmt.Errorf("unknown command :%s (see :help)", name)}
+}
+
+func (s *shellSession) search(query string) error {
+	if query == "" {
+		return &output.UsageError{Err: fmt.Errorf("usage: :search QUERY")}
+	}
+	if err := s.prepare(searchCmd); err != nil {
+		return err
+	}
+	commandName = "shell search"
+	applyProfileSearchType(searchCmd)
+	resp, err := s.client.Search(newContext(), buildSearchRequest(query))
+	if err != nil {
+		return err
+	}
+	s.setResults(resp.Results)
+	if !GetOutputOptions().Mode.IsHuman() {
+		return renderSearch(query, resp)
+	}
+	return s.list(costFooter(resp.CostDollars, resp.Cached,
+		fmt.Sprintf("%d results | Type: %s", len(resp.Results), searchType)))
+}
+
+func (s *shellSession) similar(target string) error {
+	url, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+	if err := s.prepare(similarCmd); err != nil {
+		return err
+	}
+	commandName = "shell similar"
+	resp, err := s.client.FindSimilar(newContext(), buildSimilarRequest(url))
+	if err != nil {
+		return err
+	}
+	s.setResults(resp.Results)
+	if !GetOutputOptions().Mode.IsHuman() {
+		return renderSimilar(url, resp)
+	}
+	return s.list(costFooter(resp.CostDollars, resp.Cached,
+		fmt.Sprintf("%d similar pages", len(resp.Results))))
+}
+
+func (s *shellSession) open(targets []string) error {
+	if len(targets) == 0 {
+		return &output.UsageError{Err: fmt.Errorf("usage: :open N|URL ...")}
+	}
+	urls := make([]string, len(targets))
+	for i, t := range targets {
+		url, err := s.resolve(t)
+		if err != nil {
+			return err
+		}
+		urls[i] = url
+	}
+	if err := s.prepare(contentsCmd); err != nil {
+		return err
+	}
+	commandName = "shell contents"
+	resp, err := s.client.GetContents(newContext(), buildContentsRequest(urls))
+	if err != nil {
+		return err
+	}
+	// Fetched text, summaries and highlights are kept for export.
+	for _, r := range resp.Results {
+		if i, ok := s.index[r.URL]; ok {
+			c := &s.collected[i]
+			if r.Text != "" {
+				c.Text = r.Text
+			}
+			if r.Summary != "" {
+				c.Summary = r.Summary
+			}
+			if len(r.Highlights) > 0 {
+				c.Highlights = r.Highlights
+			}
+			if c.Title == "" {
+				c.Title = r.Title
+			}
+		} else {
+			s.collect(r)
+		}
+	}
+	return renderContents(resp)
+}
+
+func (s *shellSession) answer(question string) error {
+	if question == "" {
+		return &output.UsageError{Err: fmt.Errorf("usage: :answer QUESTION")}
+	}
+	if err := s.prepare(answerCmd); err != nil {
+		return err
+	}
+	turn, err := newAnswerTurn(question)
+	if err != nil {
+		return err
+	}
+	req, schema, err := buildAnswerRequest(turn.query)
+	if err != nil {
+		return err
+	}
+	commandName = "shell answer"
+	resp, structured, err := askAnswer(newContext(), s.client, req, schema)
+	if err != nil {
+		return err
+	}
+	if err := turn.save(resp); err != nil {
+		return err
+	}
+	for _, c := range resp.Citations {
+		s.collect(c)
+	}
+	return renderAnswer(resp, structured)
+}
+
+// setResults makes results the current listing and collects them.
+func (s *shellSession) setResults(results []api.SearchResult) {
+	s.results = results
+	for _, r := range results {
+		s.collect(r)
+	}
+}
+
+// collect adds r to the session's results unless its URL was seen before.
+func (s *shellSession) collect(r api.SearchResult) {
+	if _, ok := s.index[r.URL]; ok {
+		return
+	}
+	s.index[r.URL] = len(s.collected)
+	s.collected = append(s.collected, r)
+}
+
+// resolve turns a result number from the current listing into its URL.
+// Anything else is taken as a URL.
+func (s *shellSession) resolve(target string) (string, error) {
+	if target == "" {
+		return "", &output.UsageError{Err: fmt.Errorf("expected a result number or URL")}
+	}
+	n, err := strconv.Atoi(target)
+	if err != nil {
+		return target, nil
+	}
+	if n < 1 || n > len(s.results) {
+		return "", &output.UsageError{Err: fmt.Errorf("no result %d (the current listing has %d)", n, len(s.results))}
+	}
+	return s.results[n-1].URL, nil
+}
+
+// list prints the current listing with result numbers for open and similar.
+func (s *shellSession) list(footer string) error {
+	if len(s.results) == 0 {
+		return output.ErrNoResults
+	}
+	td := output.TableData{Headers: []string{"#", "TITLE", "URL", "DATE"}, Footer: footer}
+	for i, r := range s.results {
+		date := r.PublishedDate
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		td.Rows = append(td.Rows, []string{strconv.Itoa(i + 1), truncateStr(r.Title, 50), r.URL, date})
+	}
+	return output.RenderTable(td, &api.SearchResponse{Results: s.results}, GetOutputOptions())
+}
+
+// shellCommands are the commands whose flags ':set' changes.
+var shellCommands = []*cobra.Command{searchCmd, similarCmd, contentsCmd, answerCmd}
+
+// shellFlags returns the flags named name on the commands the shell runs.
+func shellFlags(name string) []*pflag.Flag {
+	var flags []*pflag.Flag
+	if shellSkipFlags[name] {
+		return nil
+	}
+	for _, c := range shellCommands {
+		if f := c.LocalNonPersistentFlags().Lookup(name); f != nil {
+			flags = append(flags, f)
+		}
+	}
+	return flags
+}
+
+// prepare sets cmd's flags for one shell command: defaults, then the
+// environment and config file, then the session's filters. The filters
+// count as given (see flagGiven) but are not marked changed.
+func (s *shellSession) prepare(cmd *cobra.Command) error {
+	flags := cmd.LocalNonPersistentFlags()
+	flags.VisitAll(resetFlag)
+	if err := applyConfig(cmd); err != nil {
+		return err
+	}
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if shellSkipFlags[f.Name] {
+			resetFlag(f)
+			delete(configuredFlags, f)
+			return
+		}
+		if v, ok := s.filters[f.Name]; ok && err == nil {
+			if err = setFlagString(f, v); err == nil {
+				configuredFlags[f] = true
+			}
+		}
+	})
+	return err
+}
+
+// setFlagString sets f from a ':set' value; slices take commas.
+func setFlagString(f *pflag.Flag, v string) error {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.Replace(strings.Split(v, ","))
+	}
+	return f.Value.Set(v)
+}
+
+func (s *shellSession) set(name, value string) error {
+	flags := shellFlags(name)
+	if len(flags) == 0 {
+		return &output.UsageError{Err: fmt.Errorf("unknown filter %q (see 'exa search --help' for names)", name)}
+	}
+	if value == "" {
+		if flags[0].Value.Type() != "bool" {
+			return &output.UsageError{Err: fmt.Errorf("usage: :set %s VALUE", name)}
+		}
+		value = "true"
+	}
+	// Check the value now; the flags are set again before each command.
+	for _, f := range flags {
+		err := setFlagString(f, value)
+		resetFlag(f)
+		if err != nil {
+			return &output.UsageError{Err: fmt.Errorf("%s: %w", name, err)}
+		}
+	}
+	s.filters[name] = value
+	return nil
+}
+
+func (s *shellSession) unset(name string) error {
+	if len(shellFlags(name)) == 0 {
+		return &output.UsageError{Err: fmt.Errorf("unknown filter %q", name)}
+	}
+	delete(s.filters, name)
+	return nil
+}
+
+// showFilters prints the filters set with ':set'.
+func (s *shellSession) showFilters() {
+	if len(s.filters) == 0 {
+		fmt.Println("No filters set.")
+		return
+	}
+	names := make([]string, 0, len(s.filters))
+	for name := range s.filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s = %s\n", name, s.filters[name])
+	}
+}
+
+// export writes the collected results to path in format, or in the format
+// implied by the file extension.
+func (s *shellSession) export(path, format string) error {
+	mode, ok := shellExportModes[strings.ToLower(filepath.Ext(path))]
+	if format != "" {
+		m, err := output.ParseMode(format)
+		if err != nil {
+			return &output.UsageError{Err: err}
+		}
+		mode, ok = m, true
+	}
+	if !ok || mode.IsHuman() {
+		return &output.UsageError{Err: fmt.Errorf("cannot tell the export format of %s; give one, e.g. 'export %s json'", path, path)}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err := s.writeExport(f, mode); err != nil {
+		return fmt.Errorf("export: %w", errors.Join(err, f.Close()))
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d results to %s\n", len(s.collected), path)
+	return nil
+}
+
+func (s *shellSession) writeExport(w io.Writer, mode output.Mode) error {
+	results := s.collected
+	if results == nil {
+		results = []api.SearchResult{}
+	}
+	td := output.TableData{Headers: []string{"TITLE", "URL", "DATE", "AUTHOR"}}
+	for _, r := range results {
+		td.Rows = append(td.Rows, []string{r.Title, r.URL, r.PublishedDate, r.Author})
+	}
+	opts := output.Options{Mode: mode, FeedTitle: "Exa shell session"}
+	return output.Render(w, td, &api.SearchResponse{Results: results}, opts)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/roboalchemist/exa-cli/pkg/api"
@@ -32,9 +33,12 @@ Examples:
   exa similar "https://example.com" -n 20
   exa similar "https://blog.example.com" --exclude-source
   exa similar "https://example.com" --include-domains arxiv.org,scholar.google.com
-  exa similar "https://example.com" --json`,
-	Args: cobra.ExactArgs(1),
-	RunE: runSimilar,
+  exa similar "https://example.com" --json
+  exa similar --batch urls.txt -n 5
+  exa similar "https://example.com" --format rss > similar.xml`,
+	Args:        batchArgs(cobra.ExactArgs(1)),
+	Annotations: map[string]string{formatsAnnotation: feedFormats + "," + citationFormats},
+	RunE:        runSimilar,
 }
 
 func init() {
@@ -48,6 +52,7 @@ func init() {
 	f.BoolVar(&similarText, "text", false, "Include full text")
 	f.BoolVar(&similarHighlights, "highlights", false, "Include highlights")
 	f.StringVar(&similarCategory, "category", "", "Category filter")
+	addBatchFlags(similarCmd)
 
 	rootCmd.AddCommand(similarCmd)
 }
@@ -58,8 +63,32 @@ func runSimilar(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if batchFile != "" {
+		return runBatch(client, func(ctx context.Context, item batchItem) (interface{}, *api.CostInfo, error) {
+			req := buildSimilarRequest(item.Query)
+			if err := item.apply(req); err != nil {
+				return nil, nil, err
+			}
+			resp, err := client.FindSimilar(ctx, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			return resp, batchCost(resp.CostDollars, resp.Cached), nil
+		})
+	}
+
+	resp, err := client.FindSimilar(newContext(), buildSimilarRequest(args[0]))
+	if err != nil {
+		return err
+	}
+
+	return renderSimilar(args[0], resp)
+}
+
+// buildSimilarRequest builds a FindSimilarRequest for url from the similar flags.
+func buildSimilarRequest(url string) *api.FindSimilarRequest {
 	req := &api.FindSimilarRequest{
-		URL:                 args[0],
+		URL:                 url,
 		NumResults:          similarNumResults,
 		ExcludeSourceDomain: similarExcludeSource,
 	}
@@ -91,15 +120,24 @@ func runSimilar(cmd *cobra.Command, args []string) error {
 		req.Contents = contents
 	}
 
-	resp, err := client.FindSimilar(newContext(), req)
-	if err != nil {
-		return err
-	}
+	return req
+}
 
+// renderSimilar prints a findSimilar response for url in the selected
+// output mode.
+func renderSimilar(url string, resp *api.FindSimilarResponse) error {
 	opts := GetOutputOptions()
+	opts.FeedTitle = "Exa: pages similar to " + url
+	opts.FeedLink = url
+	if opts.Mode.IsFeed() {
+		opts.FeedSeen = firstSeen()
+	}
 
-	if opts.Mode == output.ModeJSON {
-		return output.RenderJSON(resp, opts)
+	if opts.Mode.IsJSON() {
+		if err := output.RenderJSON(resp, opts); err != nil {
+			return err
+		}
+		return resultsErr(len(resp.Results))
 	}
 
 	td := output.TableData{
@@ -114,11 +152,10 @@ func runSimilar(cmd *cobra.Command, args []string) error {
 		td.Rows = append(td.Rows, []string{truncateStr(r.Title, 50), r.URL, date, fmt.Sprintf("%.2f", r.Score)})
 	}
 
-	footer := fmt.Sprintf("%d similar pages", len(resp.Results))
-	if resp.CostDollars != nil {
-		footer = fmt.Sprintf("Cost: $%.4f | %s", resp.CostDollars.Total, footer)
-	}
-	td.Footer = footer
+	td.Footer = costFooter(resp.CostDollars, resp.Cached, fmt.Sprintf("%d similar pages", len(resp.Results)))
 
-	return output.RenderTable(td, resp, opts)
+	if err := output.RenderTable(td, resp, opts); err != nil {
+		return err
+	}
+	return resultsErr(len(resp.Results))
 }
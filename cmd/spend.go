@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/auth"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/roboalchemist/exa-cli/pkg/spend"
+	"github.com/spf13/cobra"
+)
+
+var (
+	spendBy    string
+	spendSince string
+)
+
+var spendCmd = &cobra.Command{
+	Use:   "spend",
+	Short: "Show locally recorded API spending",
+	Long: `Show the spend ledger: the costDollars of every billed call made by this
+CLI, recorded in $XDG_DATA_HOME/exa/spend.jsonl (~/.local/share by default).
+Cached and replayed responses are not recorded.
+
+Spending can be capped with --max-cost (per invocation), --daily-budget and
+--monthly-budget. Budgets are usually set in the config file, e.g.
+'exa config set daily-budget 2'. Once a limit is reached further calls fail
+with BUDGET_EXCEEDED (exit code 9).
+
+Examples:
+  exa spend
+  exa spend --by command
+  exa spend --by profile --since 2026-01-01 --json
+  exa spend --by client        # calls made through 'exa serve'`,
+	Args: cobra.NoArgs,
+	RunE: runSpend,
+}
+
+func init() {
+	f := spendCmd.Flags()
+	f.StringVar(&spendBy, "by", "day", "Group by: day, command, profile or client")
+	f.StringVar(&spendSince, "since", "", "Start date YYYY-MM-DD (default: 30 days ago)")
+
+	rootCmd.AddCommand(spendCmd)
+}
+
+// spendReport is the JSON form of 'exa spend'.
+type spendReport struct {
+	By            string        `json:"by"`
+	Since         string        `json:"since"`
+	Calls         int           `json:"calls"`
+	TotalDollars  float64       `json:"totalDollars"`
+	TodayDollars  float64       `json:"todayDollars"`
+	MonthDollars  float64       `json:"monthDollars"`
+	DailyBudget   float64       `json:"dailyBudget,omitempty"`
+	MonthlyBudget float64       `json:"monthlyBudget,omitempty"`
+	Groups        []spend.Group `json:"groups"`
+	Ledger        string        `json:"ledger"`
+}
+
+func runSpend(cmd *cobra.Command, args []string) error {
+	keys := map[string]func(spend.Entry) string{
+		"day":     spend.ByDay,
+		"command": spend.ByCommand,
+		"profile": spend.ByProfile,
+		"client":  spend.ByClient,
+	}
+	key, ok := keys[spendBy]
+	if !ok {
+		return &output.UsageError{Err: fmt.Errorf("invalid --by %q (want day, command, profile or client)", spendBy)}
+	}
+
+	now := time.Now()
+	since := spend.StartOfDay(now.AddDate(0, 0, -30))
+	if spendSince != "" {
+		t, err := time.ParseInLocation("2006-01-02", spendSince, time.Local)
+		if err != nil {
+			return &output.UsageError{Err: fmt.Errorf("invalid --since %q (want YYYY-MM-DD)", spendSince)}
+		}
+		since = t
+	}
+
+	ledger, err := openLedger()
+	if err != nil {
+		return err
+	}
+	from := since
+	if month := spend.StartOfMonth(now); month.Before(from) {
+		from = month
+	}
+	all, err := ledger.Entries(from)
+	if err != nil {
+		return err
+	}
+	var entries []spend.Entry
+	for _, e := range all {
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+
+	report := spendReport{
+		By:            spendBy,
+		Since:         since.Format("2006-01-02"),
+		Calls:         len(entries),
+		TotalDollars:  spend.Total(entries, since),
+		TodayDollars:  spend.Total(all, spend.StartOfDay(now)),
+		MonthDollars:  spend.Total(all, spend.StartOfMonth(now)),
+		DailyBudget:   flagDailyBdgt,
+		MonthlyBudget: flagMonthBdgt,
+		Groups:        spend.GroupBy(entries, key),
+		Ledger:        ledger.Path(),
+	}
+	if report.Groups == nil {
+		report.Groups = []spend.Group{}
+	}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(report, opts)
+	}
+
+	td := output.TableData{Headers: []string{strings.ToUpper(spendBy), "CALLS", "COST"}}
+	for _, g := range report.Groups {
+		name := g.Key
+		if name == "" {
+			name = "-"
+		}
+		td.Rows = append(td.Rows, []string{name, fmt.Sprintf("%d", g.Calls), fmt.Sprintf("$%.4f", g.Cost)})
+	}
+	td.Footer = fmt.Sprintf("Total: $%.4f over %d calls since %s | Today: %s | Month: %s",
+		report.TotalDollars, report.Calls, report.Since,
+		budgetStatus(report.TodayDollars, report.DailyBudget),
+		budgetStatus(report.MonthDollars, report.MonthlyBudget))
+	return output.RenderTable(td, report, opts)
+}
+
+func budgetStatus(spent, budget float64) string {
+	if budget <= 0 {
+		return fmt.Sprintf("$%.4f", spent)
+	}
+	return fmt.Sprintf("$%.4f of $%.2f", spent, budget)
+}
+
+// openLedger opens the spend ledger in the XDG data directory.
+func openLedger() (*spend.Ledger, error) {
+	path, err := spend.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("locate spend ledger: %w", err)
+	}
+	return spend.Open(path), nil
+}
+
+// spendTracker enforces spending limits and records billed calls. It is
+// shared by every client in the process so --max-cost covers them all.
+type spendTracker struct {
+	mu     sync.Mutex
+	run    float64 // Spent by this invocation
+	ledger *spend.Ledger
+}
+
+var tracker spendTracker
+
+// trackSpend installs the budget guard and ledger observer on client.
+func trackSpend(client *api.Client) {
+	profile := ""
+	if creds, err := auth.Resolve(); err == nil {
+		profile = creds.Profile
+		if creds.Source == auth.SourceEnv {
+			profile = auth.SourceEnv
+		}
+	}
+	client.AddGuard(tracker.check)
+	client.AddObserver(func(call *api.Call) { tracker.record(call, profile) })
+}
+
+// openLedger opens the ledger once, so concurrent calls share its lock.
+func (t *spendTracker) openLedger() (*spend.Ledger, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ledger == nil {
+		l, err := openLedger()
+		if err != nil {
+			return nil, err
+		}
+		t.ledger = l
+	}
+	return t.ledger, nil
+}
+
+// check refuses the call once --max-cost or a daily or monthly budget has
+// been reached.
+func (t *spendTracker) check(ctx context.Context, call *api.Call) error {
+	t.mu.Lock()
+	run := t.run
+	t.mu.Unlock()
+	if flagMaxCost > 0 && run >= flagMaxCost {
+		return fmt.Errorf("%w: this invocation has spent $%.4f, --max-cost is $%.4f",
+			output.ErrBudgetExceeded, run, flagMaxCost)
+	}
+	if flagDailyBdgt <= 0 && flagMonthBdgt <= 0 {
+		return nil
+	}
+
+	ledger, err := t.openLedger()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	entries, err := ledger.Entries(spend.StartOfMonth(now))
+	if err != nil {
+		return fmt.Errorf("read spend ledger: %w", err)
+	}
+	if today := spend.Total(entries, spend.StartOfDay(now)); flagDailyBdgt > 0 && today >= flagDailyBdgt {
+		return fmt.Errorf("%w: spent $%.4f today, daily budget is $%.2f",
+			output.ErrBudgetExceeded, today, flagDailyBdgt)
+	}
+	if month := spend.Total(entries, spend.StartOfMonth(now)); flagMonthBdgt > 0 && month >= flagMonthBdgt {
+		return fmt.Errorf("%w: spent $%.4f this month, monthly budget is $%.2f",
+			output.ErrBudgetExceeded, month, flagMonthBdgt)
+	}
+	return nil
+}
+
+// record adds a billed call to the invocation total and the ledger.
+// Cache hits and replayed responses cost nothing and are skipped.
+func (t *spendTracker) record(call *api.Call, profile string) {
+	if call.Cached || call.Cost == nil || os.Getenv("EXA_REPLAY") != "" {
+		return
+	}
+	t.mu.Lock()
+	t.run += call.Cost.Total
+	t.mu.Unlock()
+
+	ledger, err := t.openLedger()
+	if err != nil {
+		DebugLog("Spend ledger unavailable: %s", err)
+		return
+	}
+	err = ledger.Append(spend.Entry{
+		Time:      time.Now(),
+		Command:   commandName,
+		Profile:   profile,
+		Client:    call.Caller,
+		Endpoint:  call.Endpoint,
+		Cost:      call.Cost.Total,
+		RequestID: call.RequestID,
+	})
+	if err != nil {
+		DebugLog("Spend ledger write failed: %s", err)
+	}
+}
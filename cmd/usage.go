@@ -73,7 +73,7 @@ func runUsage(cmd *cobra.Command, args []string) error {
 
 	opts := GetOutputOptions()
 
-	if opts.Mode == output.ModeJSON {
+	if opts.Mode.IsJSON() {
 		return output.RenderJSON(resp, opts)
 	}
 
@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+	"github.com/roboalchemist/exa-cli/pkg/watch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchWindow    string
+	watchDateField string
+	watchAtomFile  string
+)
+
+// watchFeedLimit caps the entries kept in an --atom feed file.
+const watchFeedLimit = 200
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Saved searches that report only new results",
+	Long: `Save searches and re-run them to see only results not reported before.
+
+Each run searches a rolling window (--window) of published or crawl dates
+ending now, and reports URLs the watch has not seen. The window sets the
+start date itself, so --start-date can only be combined with
+--date-field crawl. Watches are stored in
+$XDG_DATA_HOME/exa/watches.json (~/.local/share by default).
+
+New results are printed in the selected output format: --format ndjson for
+one JSON line per hit, --format markdown for a digest, or the default table.
+--atom also writes them to an Atom feed file that feed readers can poll.
+
+Examples:
+  exa watch add competitors "Acme Corp product launch" --category news --window 3d
+  exa watch add papers "retrieval augmented generation" --category research_paper
+  exa watch run
+  exa watch run papers --format markdown
+  exa watch run --format ndjson --atom ~/public/exa.xml`,
+}
+
+var watchAddCmd = &cobra.Command{
+	Use:   "add <name> <query> [search flags]",
+	Short: "Save a search to watch",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runWatchAdd,
+}
+
+var watchListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved watches",
+	Args:  cobra.NoArgs,
+	RunE:  runWatchList,
+}
+
+var watchRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Delete a saved watch",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runWatchRemove,
+}
+
+var watchRunCmd = &cobra.Command{
+	Use:   "run [name]",
+	Short: "Run saved watches and report new results",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runWatchRun,
+}
+
+func init() {
+	addSearchFlags(watchAddCmd)
+	watchAddCmd.Flags().StringVar(&watchWindow, "window", "7d", "Rolling date window searched on each run, e.g. 24h or 7d")
+	watchAddCmd.Flags().StringVar(&watchDateField, "date-field", watch.DatePublished, "Date the window applies to: published or crawl")
+	watchRunCmd.Flags().StringVar(&watchAtomFile, "atom", "", "Also add new results to this Atom feed file")
+
+	watchCmd.AddCommand(watchAddCmd, watchListCmd, watchRemoveCmd, watchRunCmd)
+	rootCmd.AddCommand(watchCmd)
+}
+
+// loadWatches reads the watches file from the XDG data directory.
+func loadWatches() (*watch.File, error) {
+	path, err := watch.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("locate watches: %w", err)
+	}
+	return watch.Load(path)
+}
+
+func runWatchAdd(cmd *cobra.Command, args []string) error {
+	applyProfileSearchType(cmd)
+	w := &watch.Watch{
+		Name:      args[0],
+		Request:   *buildSearchRequest(strings.Join(args[1:], " ")),
+		Window:    watchWindow,
+		DateField: watchDateField,
+		Created:   time.Now(),
+	}
+	if err := w.Validate(); err != nil {
+		return &output.UsageError{Err: err}
+	}
+
+	f, err := loadWatches()
+	if err != nil {
+		return err
+	}
+	if err := f.Add(w); err != nil {
+		if errors.Is(err, watch.ErrExists) {
+			return &output.UsageError{Err: fmt.Errorf("%w; remove it first with 'exa watch remove %s'", err, w.Name)}
+		}
+		return err
+	}
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("save watches: %w", err)
+	}
+	output.Success(fmt.Sprintf("Added watch %q", w.Name), GetOutputOptions())
+	return nil
+}
+
+// watchView is the JSON form of a watch in 'exa watch list'.
+type watchView struct {
+	Name      string            `json:"name"`
+	Request   api.SearchRequest `json:"request"`
+	Window    string            `json:"window"`
+	DateField string            `json:"dateField"`
+	Created   time.Time         `json:"created"`
+	LastRun   *time.Time        `json:"lastRun,omitempty"`
+	SeenURLs  int               `json:"seenUrls"`
+}
+
+func runWatchList(cmd *cobra.Command, args []string) error {
+	f, err := loadWatches()
+	if err != nil {
+		return err
+	}
+
+	views := []watchView{}
+	td := output.TableData{Headers: []string{"NAME", "QUERY", "WINDOW", "LAST RUN", "SEEN"}}
+	for _, w := range f.Watches {
+		v := watchView{
+			Name:      w.Name,
+			Request:   w.Request,
+			Window:    w.Window,
+			DateField: w.DateField,
+			Created:   w.Created,
+			SeenURLs:  len(w.Seen),
+		}
+		lastRun := "never"
+		if !w.LastRun.IsZero() {
+			v.LastRun = &w.LastRun
+			lastRun = w.LastRun.Local().Format("2006-01-02 15:04")
+		}
+		views = append(views, v)
+		td.Rows = append(td.Rows, []string{
+			w.Name,
+			truncateStr(w.Request.Query, 50),
+			w.Window + " " + w.DateField,
+			lastRun,
+			strconv.Itoa(len(w.Seen)),
+		})
+	}
+
+	opts := GetOutputOptions()
+	if opts.Mode.IsJSON() {
+		return output.RenderJSON(views, opts)
+	}
+	td.Footer = f.Path()
+	return output.RenderTable(td, views, opts)
+}
+
+func runWatchRemove(cmd *cobra.Command, args []string) error {
+	f, err := loadWatches()
+	if err != nil {
+		return err
+	}
+	if err := f.Remove(args[0]); err != nil {
+		if errors.Is(err, watch.ErrNotFound) {
+			return &output.UsageError{Err: err}
+		}
+		return err
+	}
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("save watches: %w", err)
+	}
+	output.Success(fmt.Sprintf("Removed watch %q", args[0]), GetOutputOptions())
+	return nil
+}
+
+// watchHit is a new result reported by a watch.
+type watchHit struct {
+	Watch string `json:"watch"`
+	api.SearchResult
+}
+
+func runWatchRun(cmd *cobra.Command, args []string) error {
+	f, err := loadWatches()
+	if err != nil {
+		return err
+	}
+	watches := f.Watches
+	if len(args) == 1 {
+		w, err := f.Get(args[0])
+		if err != nil {
+			return &output.UsageError{Err: err}
+		}
+		watches = []*watch.Watch{w}
+	}
+	if len(watches) == 0 {
+		return &output.UsageError{Err: fmt.Errorf("no watches saved; add one with 'exa watch add <name> <query>'")}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	ctx := newContext()
+
+	now := time.Now()
+	hits := []watchHit{}
+	digest := &output.Digest{Title: "Watch digest " + now.Format("2006-01-02")}
+	var entries []output.FeedEntry
+	var names []string
+	var errs []error
+	for _, w := range watches {
+		names = append(names, w.Name)
+		req, err := w.SearchRequest(now)
+		if err == nil {
+			var resp *api.SearchResponse
+			if resp, err = client.Search(ctx, req); err == nil {
+				fresh := w.Unseen(resp.Results, now)
+				for _, r := range fresh {
+					hits = append(hits, watchHit{Watch: w.Name, SearchResult: r})
+				}
+				digest.Sections = append(digest.Sections, output.DigestSection{Heading: w.Name, Results: fresh})
+				entries = append(entries, output.FeedEntries(fresh, w.Name, w.Seen, now)...)
+				DebugLog("Watch %s: %d new of %d results", w.Name, len(fresh), len(resp.Results))
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("watch %q: %w", w.Name, err))
+		}
+	}
+	if flagDryRun != "" {
+		return errors.Join(errs...)
+	}
+
+	if err := renderWatchHits(hits, digest, watches); err != nil {
+		return err
+	}
+	if watchAtomFile != "" {
+		if err := appendAtom(watchAtomFile, names, entries, now); err != nil {
+			return fmt.Errorf("write feed: %w", err)
+		}
+	}
+	// Results count as seen only once they have been reported.
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("save watches: %w", err)
+	}
+	return errors.Join(errs...)
+}
+
+// renderWatchHits prints the new results of a watch run.
+func renderWatchHits(hits []watchHit, digest *output.Digest, watches []*watch.Watch) error {
+	opts := GetOutputOptions()
+	if opts.Mode == output.ModeJSON {
+		return output.RenderJSON(hits, opts)
+	}
+
+	td := output.TableData{Headers: []string{"WATCH", "TITLE", "URL", "DATE"}}
+	for _, h := range hits {
+		date := h.PublishedDate
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		td.Rows = append(td.Rows, []string{h.Watch, truncateStr(h.Title, 50), h.URL, date})
+	}
+	td.Footer = fmt.Sprintf("%d new results from %d watches", len(hits), len(watches))
+	if len(watches) == 1 {
+		td.Footer = fmt.Sprintf("%d new results from watch %s", len(hits), watches[0].Name)
+	}
+
+	var data interface{} = hits
+	if opts.Mode == output.ModeMarkdown {
+		data = digest
+	}
+	return output.RenderTable(td, data, opts)
+}
+
+// appendAtom adds entries to the Atom feed at path, creating it if needed.
+// Newest entries come first and entries already in the feed are skipped.
+func appendAtom(path string, names []string, entries []output.FeedEntry, now time.Time) error {
+	feed := output.Feed{
+		Title: "exa watch: " + strings.Join(names, ", "),
+		ID:    "urn:exa-cli:watch:" + strings.Join(names, "+"),
+	}
+	if r, err := os.Open(path); err == nil {
+		existing, err := output.ReadAtom(r)
+		_ = r.Close()
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		feed = existing
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	known := make(map[string]bool, len(feed.Entries))
+	for _, e := range feed.Entries {
+		known[e.ID] = true
+	}
+	var merged []output.FeedEntry
+	for _, e := range entries {
+		if !known[e.ID] {
+			known[e.ID] = true
+			merged = append(merged, e)
+		}
+	}
+	feed.Entries = append(merged, feed.Entries...)
+	if len(feed.Entries) > watchFeedLimit {
+		feed.Entries = feed.Entries[:watchFeedLimit]
+	}
+	feed.Updated = now
+
+	tmp := path + ".tmp"
+	w, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = output.WriteAtom(w, feed)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
@@ -1,14 +1,77 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/exatest"
 )
 
+// TestMain runs the suite against an in-process fake Exa API unless a real
+// key (EXA_API_KEY) or a cassette (EXA_REPLAY) is provided.
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+// runTests runs the suite in a fresh data directory and returns its exit
+// code.
+func runTests(m *testing.M) int {
+	tmp, err := os.MkdirTemp("", "exa-test-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+	// Keep a developer's own config file out of the tests.
+	os.Setenv("EXA_CONFIG", filepath.Join(tmp, "no-config", "config.yaml"))
+	// Likewise keep history, spend, watches and sessions out of the real
+	// data directory and apart from other runs.
+	os.Setenv("XDG_DATA_HOME", filepath.Join(tmp, "data"))
+
+	if os.Getenv("EXA_API_KEY") != "" || os.Getenv("EXA_REPLAY") != "" {
+		return m.Run()
+	}
+
+	srv := exatest.NewServer(exatest.WithAPIKey("exatest-key"))
+	srv.OnAnswer(func(req *api.AnswerRequest) *api.AnswerResponse {
+		answer := "Fake answer to: " + req.Query
+		if strings.Contains(req.Query, "France") {
+			answer = "The capital of France is Paris."
+		}
+		if req.OutputSchema != nil {
+			// Structured answers match the schema only for France.
+			answer = `{"city": "Paris", "population": 2100000}`
+			if !strings.Contains(req.Query, "France") {
+				answer = `{"city": "Atlantis", "population": "unknown"}`
+			}
+		}
+		return &api.AnswerResponse{
+			Answer:      answer,
+			Citations:   []api.SearchResult{{Title: "Paris", URL: "https://en.wikipedia.org/wiki/Paris", ID: "paris"}},
+			CostDollars: &api.CostInfo{Total: 0.005},
+		}
+	})
+	os.Setenv("EXA_API_KEY", "exatest-key")
+	os.Setenv("EXA_API_URL", srv.URL)
+
+	defer srv.Close()
+	return m.Run()
+}
+
 // run executes the exa binary with args and returns stdout, stderr, and error.
 func run(t *testing.T, args ...string) (string, string, error) {
 	t.Helper()
@@ -31,10 +94,12 @@ func mustRun(t *testing.T, args ...string) string {
 	return stdout
 }
 
-// requireAPIKey skips the test if EXA_API_KEY is not set.
+// requireAPIKey skips the test unless EXA_API_KEY is set or responses are
+// replayed from a cassette via EXA_REPLAY. TestMain sets EXA_API_KEY when it
+// starts the fake server, so these tests always run by default.
 func requireAPIKey(t *testing.T) {
 	t.Helper()
-	if os.Getenv("EXA_API_KEY") == "" {
+	if os.Getenv("EXA_API_KEY") == "" && os.Getenv("EXA_REPLAY") == "" {
 		t.Skip("EXA_API_KEY not set")
 	}
 }
@@ -161,7 +226,529 @@ func TestSmoke_UnknownCommand(t *testing.T) {
 	}
 }
 
-// --- Integration tests (require EXA_API_KEY) ---
+// exitCode returns the process exit code from a run error.
+func exitCode(t *testing.T, err error) int {
+	t.Helper()
+	if err == nil {
+		return 0
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		t.Fatalf("unexpected error type %T: %v", err, err)
+	}
+	return exitErr.ExitCode()
+}
+
+func TestSmoke_ExitCodeUsage(t *testing.T) {
+	_, _, err := run(t, "search")
+	if code := exitCode(t, err); code != 2 {
+		t.Errorf("missing argument: exit code %d, want 2", code)
+	}
+	_, _, err = run(t, "nonexistent")
+	if code := exitCode(t, err); code != 2 {
+		t.Errorf("unknown command: exit code %d, want 2", code)
+	}
+}
+
+func TestSmoke_ExitCodeEnvironment(t *testing.T) {
+	// Unreadable input files are runtime failures, not usage errors.
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte("json: [unclosed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("./exa", "search", "golang")
+	cmd.Env = append(os.Environ(), "EXA_CONFIG="+config)
+	if code := exitCode(t, cmd.Run()); code != 1 {
+		t.Errorf("malformed config: exit code %d, want 1", code)
+	}
+	_, _, err := run(t, "search", "golang", "--template-file", filepath.Join(t.TempDir(), "missing.tmpl"))
+	if code := exitCode(t, err); code != 1 {
+		t.Errorf("missing template file: exit code %d, want 1", code)
+	}
+
+	_, _, err = run(t, "search", "golang", "--template", "{{.Nope")
+	if code := exitCode(t, err); code != 2 {
+		t.Errorf("bad template: exit code %d, want 2", code)
+	}
+	_, _, err = run(t, "search", "golang", "--dry-run=yaml")
+	if code := exitCode(t, err); code != 2 {
+		t.Errorf("bad --dry-run: exit code %d, want 2", code)
+	}
+	_, stderr, err := run(t, "search", "--dry-run", "curl", "golang")
+	if code := exitCode(t, err); code != 2 || !strings.Contains(stderr, "--dry-run=curl") {
+		t.Errorf("detached --dry-run format: exit code %d, want 2 with a hint:\n%s", code, stderr)
+	}
+	_, _, err = run(t, "search", "golang", "--no-such-flag")
+	if code := exitCode(t, err); code != 2 {
+		t.Errorf("unknown flag: exit code %d, want 2", code)
+	}
+}
+
+func TestSmoke_TemplateCheckedBeforeRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("bad template sent %s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	for _, tmpl := range []string{"{{.Nope}}", "{{range .Results}}{{.Titel}}{{end}}"} {
+		cmd := exec.Command("./exa", "search", "golang", "--template", tmpl)
+		cmd.Env = append(os.Environ(), "EXA_API_URL="+srv.URL, "EXA_API_KEY=test")
+		if code := exitCode(t, cmd.Run()); code != 2 {
+			t.Errorf("template %q: exit code %d, want 2", tmpl, code)
+		}
+	}
+}
+
+func TestSmoke_UnknownFormat(t *testing.T) {
+	_, stderr, err := run(t, "search", "golang", "--format", "xml")
+	if code := exitCode(t, err); code != 2 {
+		t.Errorf("exit code %d, want 2", code)
+	}
+	if !strings.Contains(stderr, "unknown format") {
+		t.Errorf("unexpected stderr: %s", stderr)
+	}
+}
+
+func TestSmoke_ExitCodeAuthRequired(t *testing.T) {
+	cmd := exec.Command("./exa", "search", "test", "--json")
+	cmd.Env = append(os.Environ(), "EXA_API_KEY=", "HOME="+t.TempDir())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if code := exitCode(t, err); code != 3 {
+		t.Errorf("exit code %d, want 3", code)
+	}
+
+	var cliErr map[string]interface{}
+	if err := json.Unmarshal(stderr.Bytes(), &cliErr); err != nil {
+		t.Fatalf("stderr is not a JSON error: %v\n%s", err, stderr.String())
+	}
+	if cliErr["code"] != "AUTH_REQUIRED" || cliErr["exitCode"] != float64(3) {
+		t.Errorf("unexpected error object: %v", cliErr)
+	}
+}
+
+func TestSmoke_CacheStats(t *testing.T) {
+	cmd := exec.Command("./exa", "cache", "stats", "--json")
+	cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+t.TempDir())
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("cache stats failed: %v", err)
+	}
+	var st map[string]interface{}
+	if err := json.Unmarshal(out, &st); err != nil {
+		t.Fatalf("cache stats --json invalid JSON: %v", err)
+	}
+	if st["entries"] != float64(0) {
+		t.Errorf("empty cache reported %v entries", st["entries"])
+	}
+}
+
+func TestSmoke_AuthProfiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "team-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"results":[{"title":"Team","url":"https://team.example","id":"1","score":0.5}]}`)
+	}))
+	defer srv.Close()
+
+	home := t.TempDir()
+	if err := os.WriteFile(filepath.Join(home, ".exa-auth.json"), []byte(`{"api_key":"personal-key"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "HOME="+home, "EXA_API_KEY=", "EXA_API_URL=", "EXA_PROFILE=")
+	exa := func(args ...string) (string, error) {
+		cmd := exec.Command("./exa", args...)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	if out, err := exa("auth", "add", "team", "--api-key", "team-key", "--base-url", srv.URL); err != nil {
+		t.Fatalf("auth add failed: %v\n%s", err, out)
+	}
+	out, err := exa("auth", "list", "--json")
+	if err != nil {
+		t.Fatalf("auth list failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, `"name": "default"`) || !strings.Contains(out, `"name": "team"`) || strings.Contains(out, "team-key") {
+		t.Errorf("unexpected profile list (legacy key should migrate, keys should be masked):\n%s", out)
+	}
+
+	if out, err := exa("search", "anything", "--profile", "team", "--json"); err != nil || !strings.Contains(out, "team.example") {
+		t.Errorf("search with --profile team: %v\n%s", err, out)
+	}
+	if _, err := exa("search", "anything", "--profile", "missing"); exitCode(t, err) != 2 {
+		t.Errorf("unknown profile: exit code %d, want 2", exitCode(t, err))
+	}
+}
+
+func TestIntegration_AuthLoginStatusLogout(t *testing.T) {
+	requireAPIKey(t)
+	key := os.Getenv("EXA_API_KEY")
+	env := append(os.Environ(), "HOME="+t.TempDir(), "EXA_API_KEY=", "EXA_PROFILE=")
+	exa := func(stdin string, args ...string) (string, error) {
+		cmd := exec.Command("./exa", args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		err := cmd.Run()
+		return stdout.String(), err
+	}
+
+	if _, err := exa("not-a-valid-key\n", "auth", "--api-key-stdin", "--verify"); exitCode(t, err) != 4 {
+		t.Errorf("invalid key: exit code %d, want 4", exitCode(t, err))
+	}
+	if _, err := exa(key+"\n", "auth", "--api-key-stdin", "--verify"); err != nil {
+		t.Fatalf("auth --api-key-stdin failed: %v", err)
+	}
+
+	out, err := exa("", "auth", "status", "--json")
+	if err != nil {
+		t.Fatalf("auth status failed: %v\n%s", err, out)
+	}
+	var st struct {
+		Source   string `json:"source"`
+		Profile  string `json:"profile"`
+		Verified bool   `json:"verified"`
+	}
+	if err := json.Unmarshal([]byte(out), &st); err != nil {
+		t.Fatalf("auth status --json invalid JSON: %v", err)
+	}
+	if st.Source != "file" || st.Profile != "default" || !st.Verified {
+		t.Errorf("status = %+v", st)
+	}
+
+	if _, err := exa("", "auth", "logout"); err != nil {
+		t.Fatalf("auth logout failed: %v", err)
+	}
+	if _, err := exa("", "auth", "status", "--json"); exitCode(t, err) != 3 {
+		t.Errorf("after logout: exit code %d, want 3", exitCode(t, err))
+	}
+}
+
+func TestIntegration_ConfigDefaults(t *testing.T) {
+	requireAPIKey(t)
+	cfg := filepath.Join(t.TempDir(), "config.yaml")
+	exa := func(env []string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("./exa", args...)
+		cmd.Env = append(append(os.Environ(), "EXA_CONFIG="+cfg), env...)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("exa %v: %v", args, err)
+		}
+		return string(out)
+	}
+	count := func(out string) int {
+		return len(strings.Split(strings.TrimSpace(out), "\n"))
+	}
+
+	exa(nil, "config", "set", "search.num-results", "3")
+	exa(nil, "config", "set", "format", "ndjson")
+	if got := exa(nil, "config", "get", "search.num-results"); strings.TrimSpace(got) != "3" {
+		t.Errorf("config get = %q", got)
+	}
+
+	if n := count(exa(nil, "search", "golang", "--no-contents")); n != 3 {
+		t.Errorf("config default: %d results, want 3", n)
+	}
+	if n := count(exa([]string{"EXA_SEARCH_NUM_RESULTS=2"}, "search", "golang", "--no-contents")); n != 2 {
+		t.Errorf("env over config: %d results, want 2", n)
+	}
+	if n := count(exa([]string{"EXA_SEARCH_NUM_RESULTS=2"}, "search", "golang", "--no-contents", "-n", "1")); n != 1 {
+		t.Errorf("flag over env: %d results, want 1", n)
+	}
+	if n := count(exa([]string{"EXA_JSON=1", "EXA_DRY_RUN=json"}, "search", "golang", "--no-contents")); n != 3 {
+		t.Errorf("EXA_JSON and EXA_DRY_RUN should be ignored, got %d lines", n)
+	}
+	if out := exa(nil, "search", "golang", "--no-contents", "--json"); !strings.HasPrefix(out, "{\n") {
+		t.Errorf("--json on the command line should override format from config, got:\n%s", out)
+	}
+
+	// MCP tool calls start from the config defaults too, and an argument
+	// for one call does not carry over to the next.
+	mcpCall := func(id int, args string) string {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"search","arguments":%s}}`, id, args)
+	}
+	cmd := exec.Command("./exa", "mcp", "serve")
+	cmd.Env = append(os.Environ(), "EXA_CONFIG="+cfg)
+	cmd.Stdin = strings.NewReader(strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`,
+		mcpCall(2, `{"query":"golang","no-contents":true}`),
+		mcpCall(3, `{"query":"golang","no-contents":true,"num-results":1}`),
+		mcpCall(4, `{"query":"golang","no-contents":true}`),
+	}, "\n"))
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("exa mcp serve: %v", err)
+	}
+	var got []int
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n")[1:] {
+		var r struct {
+			Result struct {
+				StructuredContent struct {
+					Results []json.RawMessage `json:"results"`
+				} `json:"structuredContent"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("stdout line is not JSON-RPC: %q", line)
+		}
+		got = append(got, len(r.Result.StructuredContent.Results))
+	}
+	if fmt.Sprint(got) != "[3 1 3]" {
+		t.Errorf("mcp search results = %v, want [3 1 3] from the config default", got)
+	}
+}
+
+func TestIntegration_SpendBudget(t *testing.T) {
+	requireAPIKey(t)
+	if os.Getenv("EXA_REPLAY") != "" {
+		t.Skip("replayed calls are not recorded")
+	}
+	data := t.TempDir()
+	exa := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command("./exa", args...)
+		cmd.Env = append(os.Environ(), "XDG_DATA_HOME="+data)
+		cmd.Stdin = strings.NewReader(stdin)
+		out, err := cmd.Output()
+		return string(out), err
+	}
+
+	if _, err := exa("", "search", "golang", "-n", "1", "--no-contents", "--json"); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	out, err := exa("", "spend", "--by", "command", "--json")
+	if err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	var report struct {
+		Calls  int     `json:"calls"`
+		Total  float64 `json:"totalDollars"`
+		Groups []struct {
+			Key string `json:"key"`
+		} `json:"groups"`
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("spend JSON: %v\n%s", err, out)
+	}
+	if report.Calls != 1 || report.Total <= 0 || len(report.Groups) != 1 || report.Groups[0].Key != "search" {
+		t.Errorf("spend report = %+v", report)
+	}
+
+	_, err = exa("", "search", "golang", "--no-contents", "--daily-budget", "0.0001")
+	if code := exitCode(t, err); code != 9 {
+		t.Errorf("over daily budget: exit %d, want 9", code)
+	}
+
+	// The first call exhausts --max-cost, so the remaining ones are refused.
+	out, err = exa("a\nb\nc\n", "search", "--batch", "-", "--concurrency", "1", "--no-contents", "--max-cost", "0.0001")
+	if code := exitCode(t, err); code != 9 {
+		t.Errorf("over --max-cost: exit %d, want 9", code)
+	}
+	if n := strings.Count(out, "BUDGET_EXCEEDED"); n != 2 {
+		t.Errorf("%d budget errors in batch output, want 2:\n%s", n, out)
+	}
+}
+
+func TestIntegration_History(t *testing.T) {
+	requireAPIKey(t)
+	if os.Getenv("EXA_REPLAY") != "" {
+		t.Skip("replayed calls are not logged")
+	}
+	data := t.TempDir()
+	exa := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("./exa", args...)
+		cmd.Env = append(os.Environ(), "XDG_DATA_HOME="+data)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("exa %v: %v", args, err)
+		}
+		return string(out)
+	}
+
+	exa("search", "golang", "-n", "3", "--no-contents", "--json")
+	exa("search", "golang", "-n", "5", "--no-contents", "--json")
+	exa("history", "rerun", "1", "--json")
+
+	var entries []struct {
+		ID      int      `json:"id"`
+		Command string   `json:"command"`
+		URLs    []string `json:"urls"`
+	}
+	if err := json.Unmarshal([]byte(exa("history", "list", "--json")), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[2].Command != "search" || len(entries[1].URLs) != 5 {
+		t.Fatalf("history list = %+v", entries)
+	}
+
+	type diff struct {
+		From     int `json:"from"`
+		Appeared []struct {
+			URL string `json:"url"`
+			To  int    `json:"to"`
+		} `json:"appeared"`
+		Unchanged int `json:"unchanged"`
+	}
+	var d diff
+	if err := json.Unmarshal([]byte(exa("history", "diff", "1", "2", "--json")), &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Appeared) != 2 || d.Appeared[0].To != 4 || d.Unchanged != 3 {
+		t.Errorf("diff 1 2 = %+v", d)
+	}
+
+	// With one ID, diff compares against the previous run of the same request.
+	d = diff{}
+	if err := json.Unmarshal([]byte(exa("history", "diff", "3", "--json")), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.From != 1 || len(d.Appeared) != 0 || d.Unchanged != 3 {
+		t.Errorf("diff 3 = %+v", d)
+	}
+}
+
+func TestIntegration_Watch(t *testing.T) {
+	requireAPIKey(t)
+	data := t.TempDir()
+	feed := filepath.Join(data, "feed.xml")
+	exa := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("./exa", args...)
+		cmd.Env = append(os.Environ(), "XDG_DATA_HOME="+data)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("exa %v: %v", args, err)
+		}
+		return string(out)
+	}
+
+	fail := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("./exa", args...)
+		cmd.Env = append(os.Environ(), "XDG_DATA_HOME="+data)
+		if err := cmd.Run(); err == nil {
+			t.Errorf("exa %v should fail", args)
+		}
+	}
+
+	fail("watch", "add", "dated", "golang", "--start-date", "2025-01-01")
+	exa("watch", "add", "golang", "golang release", "-n", "3", "--no-contents", "--window", "2d")
+	// A run whose output fails does not mark its results seen.
+	fail("watch", "run", "--json", "--jq", ".[")
+	out := exa("watch", "run", "--format", "ndjson", "--atom", feed)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("first run: %d hits, want 3:\n%s", len(lines), out)
+	}
+	var hit struct {
+		Watch string `json:"watch"`
+		URL   string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &hit); err != nil || hit.Watch != "golang" || hit.URL == "" {
+		t.Errorf("hit = %+v, %v", hit, err)
+	}
+
+	if out := exa("watch", "run", "--format", "ndjson"); strings.TrimSpace(out) != "" {
+		t.Errorf("second run should report nothing new, got:\n%s", out)
+	}
+
+	atom, err := os.ReadFile(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(atom), "<entry>"); n != 3 {
+		t.Errorf("feed has %d entries, want 3:\n%s", n, atom)
+	}
+}
+
+func TestSmoke_DryRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dry run sent %s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	exa := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("./exa", args...)
+		cmd.Env = append(os.Environ(), "EXA_API_URL="+srv.URL, "EXA_API_KEY=")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("exa %v: %v", args, err)
+		}
+		return string(out)
+	}
+
+	out := exa("search", "golang", "-n", "5", "--summary", "--dry-run")
+	var req struct {
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+		Body    struct {
+			NumResults int `json:"numResults"`
+		} `json:"body"`
+		EstimatedCost struct {
+			Total float64 `json:"total"`
+		} `json:"estimatedCost"`
+	}
+	if err := json.Unmarshal([]byte(out), &req); err != nil {
+		t.Fatalf("dry-run JSON: %v\n%s", err, out)
+	}
+	if req.URL != srv.URL+"/search" || req.Body.NumResults != 5 || req.Headers["x-api-key"] != "REDACTED" {
+		t.Errorf("dry-run request = %+v", req)
+	}
+	if req.EstimatedCost.Total != 0.01 {
+		t.Errorf("estimated cost = %v, want 0.01", req.EstimatedCost.Total)
+	}
+
+	out = exa("contents", "https://example.com", "--dry-run=curl")
+	if !strings.HasPrefix(out, "curl -X POST '"+srv.URL+"/contents'") || !strings.Contains(out, `"x-api-key: $EXA_API_KEY"`) {
+		t.Errorf("curl output:\n%s", out)
+	}
+	if !strings.Contains(out, "# Estimated cost: $") {
+		t.Errorf("curl output missing estimate:\n%s", out)
+	}
+}
+
+func TestSmoke_RecordReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results":[{"title":"Recorded","url":"https://recorded.example","id":"1","score":0.5}]}`)
+	}))
+	cassette := filepath.Join(t.TempDir(), "search.json")
+
+	record := exec.Command("./exa", "search", "cassette test", "--json")
+	record.Env = append(os.Environ(), "EXA_API_KEY=secret-key", "EXA_API_URL="+srv.URL, "EXA_RECORD="+cassette)
+	if out, err := record.CombinedOutput(); err != nil {
+		t.Fatalf("record failed: %v\n%s", err, out)
+	}
+	srv.Close()
+
+	raw, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "secret-key") {
+		t.Error("cassette leaked the API key")
+	}
+
+	replay := exec.Command("./exa", "search", "cassette test", "--json")
+	replay.Env = append(os.Environ(), "EXA_API_KEY=", "HOME="+t.TempDir(), "EXA_API_URL="+srv.URL, "EXA_REPLAY="+cassette)
+	out, err := replay.Output()
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if !strings.Contains(string(out), "https://recorded.example") {
+		t.Errorf("replayed output missing recorded result: %s", out)
+	}
+}
+
+// --- Integration tests (live API, cassette replay, or the exatest fake) ---
 
 func TestIntegration_SearchBasic(t *testing.T) {
 	requireAPIKey(t)
@@ -416,6 +1003,234 @@ func TestIntegration_AnswerJSON(t *testing.T) {
 	}
 }
 
+func TestIntegration_AnswerStream(t *testing.T) {
+	requireAPIKey(t)
+	out := mustRun(t, "answer", "What is the capital of France?", "--stream")
+	if !strings.Contains(strings.ToLower(out), "paris") {
+		t.Errorf("streamed answer should mention Paris, got: %s", out[:min(200, len(out))])
+	}
+}
+
+func TestIntegration_UsageJSON(t *testing.T) {
+	requireAPIKey(t)
+	out := mustRun(t, "usage", "--json")
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("usage --json invalid JSON: %v", err)
+	}
+	if _, ok := resp["usage"]; !ok {
+		t.Error("usage --json missing usage field")
+	}
+}
+
+func TestIntegration_SearchBatch(t *testing.T) {
+	requireAPIKey(t)
+	cmd := exec.Command("./exa", "search", "--batch", "-", "-n", "2", "--no-contents", "--concurrency", "2")
+	cmd.Env = os.Environ()
+	cmd.Stdin = strings.NewReader("golang testing\n# skipped\n{\"query\": \"rust testing\", \"numResults\": 1}\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("batch failed: %v\n%s", err, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 NDJSON lines, got %d:\n%s", len(lines), stdout.String())
+	}
+	for i, want := range []string{"golang testing", "rust testing"} {
+		var line struct {
+			Index  int    `json:"index"`
+			Query  string `json:"query"`
+			Result struct {
+				Results []interface{} `json:"results"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &line); err != nil {
+			t.Fatalf("line %d invalid JSON: %v", i, err)
+		}
+		if line.Index != i || line.Query != want || len(line.Result.Results) == 0 {
+			t.Errorf("line %d = %+v", i, line)
+		}
+	}
+	if !strings.Contains(stderr.String(), "2 succeeded") {
+		t.Errorf("missing batch summary on stderr: %s", stderr.String())
+	}
+}
+
+func TestIntegration_SearchNDJSON(t *testing.T) {
+	requireAPIKey(t)
+	out := mustRun(t, "search", "golang testing", "-n", "3", "--no-contents", "--ndjson", "--fields", "title,url")
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 NDJSON lines, got %d:\n%s", len(lines), out)
+	}
+	for i, l := range lines {
+		var r map[string]interface{}
+		if err := json.Unmarshal([]byte(l), &r); err != nil {
+			t.Fatalf("line %d invalid JSON: %v", i, err)
+		}
+		if len(r) != 2 || r["url"] == nil || r["title"] == nil {
+			t.Errorf("line %d = %v, want only title and url", i, r)
+		}
+	}
+}
+
+func TestIntegration_SearchCSV(t *testing.T) {
+	requireAPIKey(t)
+	out := mustRun(t, "search", "golang testing", "-n", "2", "--no-contents", "--format", "csv", "--fields", "url,costDollars.total")
+
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v\n%s", err, out)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != "url,costDollars.total" {
+		t.Fatalf("unexpected CSV:\n%s", out)
+	}
+	if !strings.HasPrefix(records[1][0], "http") || records[1][1] == "" {
+		t.Errorf("row 1 = %v", records[1])
+	}
+}
+
+func TestIntegration_AnswerMarkdown(t *testing.T) {
+	requireAPIKey(t)
+	out := mustRun(t, "answer", "What is the capital of France?", "--format", "markdown")
+	if !strings.Contains(out, "[^1]: [") {
+		t.Errorf("expected footnote citations, got:\n%s", out)
+	}
+}
+
+func TestIntegration_SearchFeeds(t *testing.T) {
+	requireAPIKey(t)
+	type atomFeed struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+		} `xml:"entry"`
+	}
+	readAtom := func() atomFeed {
+		t.Helper()
+		out := mustRun(t, "search", "golang testing", "-n", "3", "--format", "atom")
+		var atom atomFeed
+		if err := xml.Unmarshal([]byte(out), &atom); err != nil {
+			t.Fatalf("invalid atom: %v\n%s", err, out)
+		}
+		return atom
+	}
+	atom := readAtom()
+	if atom.Title != "Exa search: golang testing" || len(atom.Entries) != 3 || atom.Entries[0].ID == "" {
+		t.Errorf("unexpected atom feed: %+v", atom)
+	}
+	// Undated entries keep the time they were first seen across runs.
+	time.Sleep(1100 * time.Millisecond)
+	if again := readAtom(); fmt.Sprint(again.Entries) != fmt.Sprint(atom.Entries) {
+		t.Errorf("entries changed between runs:\n%+v\n%+v", atom.Entries, again.Entries)
+	}
+
+	out := mustRun(t, "similar", "https://go.dev", "-n", "2", "--format", "rss")
+	var rss struct {
+		Channel struct {
+			Link  string `xml:"link"`
+			Items []struct {
+				GUID string `xml:"guid"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal([]byte(out), &rss); err != nil {
+		t.Fatalf("invalid rss: %v\n%s", err, out)
+	}
+	if rss.Channel.Link != "https://go.dev" || len(rss.Channel.Items) != 2 {
+		t.Errorf("unexpected rss feed: %+v", rss)
+	}
+}
+
+func TestIntegration_CitationFormats(t *testing.T) {
+	requireAPIKey(t)
+	out := mustRun(t, "search", "attention is all you need", "-n", "2", "--format", "bibtex")
+	if n := strings.Count(out, "@misc{") + strings.Count(out, "@article{"); n != 2 {
+		t.Errorf("expected 2 bibtex entries, got:\n%s", out)
+	}
+	if !strings.Contains(out, "url = {") || !strings.Contains(out, "urldate = {") {
+		t.Errorf("bibtex entry missing url fields:\n%s", out)
+	}
+
+	out = mustRun(t, "answer", "Who introduced transformers?", "--format", "ris")
+	if !strings.HasPrefix(out, "TY  - ") || !strings.Contains(out, "ER  - ") {
+		t.Errorf("unexpected RIS output:\n%s", out)
+	}
+
+	out = mustRun(t, "contents", "https://arxiv.org/abs/1706.03762", "--format", "csljson")
+	var items []map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &items); err != nil {
+		t.Fatalf("invalid CSL-JSON: %v\n%s", err, out)
+	}
+	if len(items) != 1 || items[0]["id"] == "" || items[0]["URL"] != "https://arxiv.org/abs/1706.03762" {
+		t.Errorf("unexpected CSL-JSON items: %v", items)
+	}
+}
+
+func TestSmoke_FeedFormatUnsupported(t *testing.T) {
+	_, stderr, err := run(t, "contents", "https://go.dev", "--format", "atom")
+	if code := exitCode(t, err); code != 2 {
+		t.Errorf("exit code %d, want 2", code)
+	}
+	if !strings.Contains(stderr, "not supported by 'exa contents'") {
+		t.Errorf("unexpected stderr: %s", stderr)
+	}
+}
+
+func TestIntegration_SearchTemplate(t *testing.T) {
+	requireAPIKey(t)
+	out := mustRun(t, "search", "golang testing", "-n", "2", "--no-contents",
+		"--template", `{{range .Results}}[{{printf "%.2f" .Score}}] {{.Title | truncate 30}} <{{.URL}}>{{"\n"}}{{end}}`)
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got:\n%s", out)
+	}
+	for _, l := range lines {
+		if !strings.HasPrefix(l, "[") || !strings.HasSuffix(l, ">") {
+			t.Errorf("unexpected line %q", l)
+		}
+	}
+}
+
+func TestIntegration_AnswerStreamNDJSON(t *testing.T) {
+	requireAPIKey(t)
+	out := mustRun(t, "answer", "What is the capital of France?", "--stream", "--ndjson")
+
+	var types []string
+	var text strings.Builder
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		var ev struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal([]byte(l), &ev); err != nil {
+			t.Fatalf("invalid event %q: %v", l, err)
+		}
+		types = append(types, ev.Type)
+		text.WriteString(ev.Text)
+	}
+	if len(types) < 2 || types[0] != "text" || types[len(types)-1] != "done" {
+		t.Errorf("unexpected event sequence: %v", types)
+	}
+	if !strings.Contains(strings.ToLower(text.String()), "paris") {
+		t.Errorf("streamed text should mention Paris, got: %s", text.String())
+	}
+
+	// Formats written only once the answer is complete cannot stream.
+	for _, format := range []string{"json", "csv", "markdown"} {
+		if _, _, err := run(t, "answer", "What is the capital of France?", "--stream", "--format", format); exitCode(t, err) != 2 {
+			t.Errorf("--stream --format %s: exit code %d, want 2", format, exitCode(t, err))
+		}
+	}
+}
+
 func TestIntegration_ContextBasic(t *testing.T) {
 	requireAPIKey(t)
 	out := mustRun(t, "context", "Python list comprehension", "--tokens", "1000")
@@ -468,6 +1283,22 @@ func TestIntegration_SearchDateFilter(t *testing.T) {
 	}
 }
 
+func TestIntegration_SearchNoResults(t *testing.T) {
+	requireAPIKey(t)
+	args := []string{"search", "golang", "--exclude-domains", "example.com,example.org,example.net,test.example"}
+	if _, _, err := run(t, args...); exitCode(t, err) != 8 {
+		t.Errorf("table output: exit code %d, want 8", exitCode(t, err))
+	}
+	stdout, stderr, err := run(t, append(args, "--json")...)
+	if err != nil {
+		t.Fatalf("--json with no results should succeed: %v\n%s", err, stderr)
+	}
+	var resp api.SearchResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil || len(resp.Results) != 0 {
+		t.Errorf("--json output = %q, %v", stdout, err)
+	}
+}
+
 // --- Error handling tests ---
 
 func TestIntegration_SearchNoQuery(t *testing.T) {
@@ -516,3 +1347,422 @@ func TestIntegration_InvalidAPIKey(t *testing.T) {
 		t.Error("invalid API key should produce an error")
 	}
 }
+
+func TestIntegration_MCPServe(t *testing.T) {
+	requireAPIKey(t)
+	cmd := exec.Command("./exa", "mcp", "serve")
+	cmd.Env = os.Environ()
+	cmd.Stdin = strings.NewReader(strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search","arguments":{"query":"golang","num-results":2,"include-domains":["go.dev"]}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"contents","arguments":{"urls":["https://go.dev"],"num-results":2}}}`,
+	}, "\n"))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("exa mcp serve failed: %v\n%s", err, stderr.String())
+	}
+
+	type rpcResponse struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+	}
+	var resps []rpcResponse
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		var r rpcResponse
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("stdout line is not JSON-RPC: %q", line)
+		}
+		resps = append(resps, r)
+	}
+	if len(resps) != 4 {
+		t.Fatalf("got %d responses, want 4:\n%s", len(resps), out)
+	}
+
+	var list struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			InputSchema struct {
+				Properties map[string]map[string]interface{} `json:"properties"`
+				Required   []string                          `json:"required"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(resps[1].Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+		if tool.Name == "search" {
+			props := tool.InputSchema.Properties
+			if props["num-results"]["type"] != "integer" || props["include-domains"]["type"] != "array" || props["batch"] != nil {
+				t.Errorf("search schema not derived from flags: %v", props)
+			}
+		}
+	}
+	if strings.Join(names, ",") != "search,contents,similar,answer,context" {
+		t.Errorf("tools = %v", names)
+	}
+
+	var call struct {
+		StructuredContent struct {
+			Results     []api.SearchResult `json:"results"`
+			CostDollars *api.CostInfo      `json:"costDollars"`
+		} `json:"structuredContent"`
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(resps[2].Result, &call); err != nil {
+		t.Fatal(err)
+	}
+	if call.IsError || len(call.StructuredContent.Results) != 2 || call.StructuredContent.CostDollars == nil {
+		t.Errorf("search call = %s", resps[2].Result)
+	}
+
+	// contents has no num-results flag, so the call fails as a tool error.
+	var failed struct {
+		IsError           bool                   `json:"isError"`
+		StructuredContent map[string]interface{} `json:"structuredContent"`
+	}
+	_ = json.Unmarshal(resps[3].Result, &failed)
+	if !failed.IsError || failed.StructuredContent["code"] != "USAGE_ERROR" {
+		t.Errorf("contents call with unknown argument should fail: %s", resps[3].Result)
+	}
+}
+
+func TestIntegration_Serve(t *testing.T) {
+	requireAPIKey(t)
+	if os.Getenv("EXA_REPLAY") != "" {
+		t.Skip("the proxy needs a live or fake API")
+	}
+	cmd := exec.Command("./exa", "serve", "--listen", "127.0.0.1:0", "--no-cache")
+	cmd.Env = os.Environ()
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	logs := bufio.NewScanner(stderr)
+	if !logs.Scan() {
+		t.Fatal("exa serve exited before listening")
+	}
+	banner := logs.Text()
+	_, addr, ok := strings.Cut(banner, "listening on ")
+	if !ok {
+		t.Fatalf("unexpected banner: %q", banner)
+	}
+	addr, _, _ = strings.Cut(addr, " ")
+
+	// The CLI itself works against the proxy with a placeholder key.
+	search := exec.Command("./exa", "search", "golang", "-n", "2", "--json")
+	search.Env = append(os.Environ(), "EXA_API_URL="+addr, "EXA_API_KEY=integration")
+	out, err := search.Output()
+	if err != nil {
+		t.Fatalf("search through proxy failed: %v", err)
+	}
+	var resp api.SearchResponse
+	if err := json.Unmarshal(out, &resp); err != nil || len(resp.Results) != 2 {
+		t.Fatalf("search through proxy: %v\n%s", err, out)
+	}
+
+	stats, err := http.Get(addr + "/_proxy/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stats.Body.Close()
+	var body struct {
+		Clients []struct {
+			Client      string  `json:"client"`
+			Requests    int     `json:"requests"`
+			CostDollars float64 `json:"costDollars"`
+		} `json:"clients"`
+	}
+	if err := json.NewDecoder(stats.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Clients) != 1 || !strings.HasPrefix(body.Clients[0].Client, "key-") || body.Clients[0].Requests != 1 {
+		t.Fatalf("stats = %+v", body.Clients)
+	}
+	client := body.Clients[0].Client
+
+	_ = cmd.Process.Signal(os.Interrupt)
+	var rest []string
+	for logs.Scan() {
+		rest = append(rest, logs.Text())
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("exa serve did not exit cleanly: %v", err)
+	}
+	if summary := strings.Join(rest, "\n"); !strings.Contains(summary, client+": 1 requests") {
+		t.Errorf("missing per-client summary:\n%s", summary)
+	}
+
+	// The proxied call is in history for its client, hidden from the
+	// default listing and not replayed.
+	historyIDs := func(args ...string) map[int]string {
+		t.Helper()
+		var entries []struct {
+			ID     int    `json:"id"`
+			Client string `json:"client"`
+		}
+		if err := json.Unmarshal([]byte(mustRun(t, append([]string{"history", "list", "-n", "0", "--json"}, args...)...)), &entries); err != nil {
+			t.Fatal(err)
+		}
+		ids := make(map[int]string)
+		for _, e := range entries {
+			ids[e.ID] = e.Client
+		}
+		return ids
+	}
+	proxied := 0
+	for id, c := range historyIDs("--all") {
+		if c == client {
+			proxied = id
+		}
+	}
+	if proxied == 0 {
+		t.Fatalf("no history entry for client %s", client)
+	}
+	if _, ok := historyIDs()[proxied]; ok {
+		t.Errorf("history list shows the proxy client's entry %d without --all", proxied)
+	}
+	if _, _, err := run(t, "history", "rerun", strconv.Itoa(proxied)); exitCode(t, err) != 2 {
+		t.Errorf("history rerun of a proxy client's call: exit code %d, want 2", exitCode(t, err))
+	}
+}
+
+func TestIntegration_Shell(t *testing.T) {
+	requireAPIKey(t)
+	export := filepath.Join(t.TempDir(), "session.json")
+	cmd := exec.Command("./exa", "shell", "--no-color", "--export", export)
+	cmd.Env = os.Environ()
+	cmd.Stdin = strings.NewReader(strings.Join([]string{
+		"# scripted session",
+		":set num-results 3",
+		":set include-domains go.dev",
+		":filters",
+		"golang generics",
+		":open 2",
+		":open 7",
+		":similar 1",
+		":unset include-domains",
+		":answer what is go",
+		"open source databases",
+		"QUIT",
+		":search never runs",
+	}, "\n"))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// ':open 7' fails, so the scripted session exits non-zero.
+	if err := cmd.Run(); exitCode(t, err) != 2 {
+		t.Fatalf("exa shell: exit code %d, want 2\n%s", exitCode(t, err), stderr.String())
+	}
+
+	out := stdout.String()
+	for _, want := range []string{
+		"include-domains = go.dev",
+		"1   Result 1 for golang generics",
+		"--- https://go.dev/golang-generics/2 ---",
+		"3   Result 3 for similar to",
+		"Fake answer to: what is go",
+		"Result 1 for open source databases",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "never runs") {
+		t.Error("commands after quit were run")
+	}
+	if !strings.Contains(stderr.String(), "no result 7 (the current listing has 3)") {
+		t.Errorf("bad result number should be reported and skipped:\n%s", stderr.String())
+	}
+
+	data, err := os.ReadFile(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session api.SearchResponse
+	if err := json.Unmarshal(data, &session); err != nil {
+		t.Fatal(err)
+	}
+	// 2 × 3 search results, 3 similar pages and the answer's citation.
+	if len(session.Results) != 10 {
+		t.Fatalf("exported %d results, want 10:\n%s", len(session.Results), data)
+	}
+	if !strings.HasPrefix(session.Results[1].Text, "Fake contents of https://go.dev/golang-generics/2") {
+		t.Errorf("opened result should carry its text: %+v", session.Results[1])
+	}
+}
+
+func TestIntegration_ShellConfigDefaults(t *testing.T) {
+	requireAPIKey(t)
+	cmd := exec.Command("./exa", "shell", "--no-color")
+	cmd.Env = append(os.Environ(), "EXA_SEARCH_NUM_RESULTS=2")
+	cmd.Stdin = strings.NewReader("golang\n:set num-results 4\nrust\n:unset num-results\npython\n:filters\n")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("exa shell: %v", err)
+	}
+	for _, want := range []string{"Result 2 for golang", "Result 4 for rust", "Result 2 for python", "No filters set."} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"Result 3 for golang", "Result 3 for python"} {
+		if strings.Contains(string(out), unwanted) {
+			t.Errorf("environment default not applied, found %q:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestIntegration_AnswerSession(t *testing.T) {
+	requireAPIKey(t)
+	name := fmt.Sprintf("it-%d", os.Getpid())
+	defer run(t, "answer", "sessions", "delete", name)
+
+	mustRun(t, "answer", "What is the capital of France?", "--session", name)
+	mustRun(t, "answer", "How big is it?", "--session", name, "--stream")
+
+	// A follow-up carries the earlier turns; dry runs are not recorded.
+	out := mustRun(t, "answer", "And its history?", "--session", name, "--dry-run")
+	var dry struct {
+		Body api.AnswerRequest `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(out), &dry); err != nil {
+		t.Fatalf("dry-run JSON: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"Q: What is the capital of France?\nA: The capital of France is Paris.",
+		"Q: How big is it?",
+		"Follow-up question: And its history?",
+	} {
+		if !strings.Contains(dry.Body.Query, want) {
+			t.Errorf("composed query missing %q:\n%s", want, dry.Body.Query)
+		}
+	}
+
+	var list []struct {
+		Name         string `json:"name"`
+		Turns        int    `json:"turns"`
+		LastQuestion string `json:"lastQuestion"`
+	}
+	if err := json.Unmarshal([]byte(mustRun(t, "answer", "sessions", "list", "--json")), &list); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, s := range list {
+		if s.Name == name {
+			found = true
+			if s.Turns != 2 || s.LastQuestion != "How big is it?" {
+				t.Errorf("session = %+v", s)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("session %s not listed: %+v", name, list)
+	}
+
+	md := mustRun(t, "answer", "sessions", "export", name)
+	if !strings.Contains(md, "## 1. What is the capital of France?\n\nThe capital of France is Paris.") {
+		t.Errorf("markdown export:\n%s", md)
+	}
+	// Both turns cite the same source, which is exported once.
+	if bib := mustRun(t, "answer", "sessions", "export", name, "--format", "bibtex"); strings.Count(bib, "@") != 1 {
+		t.Errorf("bibtex export:\n%s", bib)
+	}
+	if alias := mustRun(t, "sessions", "export", name); alias != md {
+		t.Errorf("'exa sessions export' differs from 'exa answer sessions export':\n%s", alias)
+	}
+
+	// 'sessions' is a subcommand only when one follows.
+	if _, stderr, err := run(t, "answer", "sessions", "of", "parliament"); exitCode(t, err) != 2 || !strings.Contains(stderr, "exa answer -- sessions of parliament") {
+		t.Errorf("unquoted question starting with sessions: exit %d, stderr %q", exitCode(t, err), stderr)
+	}
+	if out := mustRun(t, "answer", "--", "sessions", "of", "parliament"); !strings.Contains(out, "Fake answer to: sessions of parliament") {
+		t.Errorf("question after --:\n%s", out)
+	}
+
+	mustRun(t, "answer", "sessions", "delete", name)
+	if _, _, err := run(t, "answer", "sessions", "show", name); err == nil {
+		t.Error("show succeeded after delete")
+	} else if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Errorf("show after delete: %v, want exit code 2", err)
+	}
+}
+
+func TestIntegration_AnswerOutputSchema(t *testing.T) {
+	requireAPIKey(t)
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "city.json")
+	if err := os.WriteFile(schema, []byte(`{
+		"type": "object",
+		"required": ["city", "population"],
+		"additionalProperties": false,
+		"properties": {"city": {"type": "string"}, "population": {"type": "integer"}}
+	}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// JSON output carries the answer as an object.
+	out := mustRun(t, "answer", "What is the capital of France?", "--output-schema", schema, "--json")
+	var resp struct {
+		Answer struct {
+			City       string `json:"city"`
+			Population int    `json:"population"`
+		} `json:"answer"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		t.Fatalf("JSON output: %v\n%s", err, out)
+	}
+	if resp.Answer.City != "Paris" || resp.Answer.Population != 2100000 {
+		t.Errorf("answer = %+v", resp.Answer)
+	}
+	if out := mustRun(t, "answer", "What is the capital of France?", "--output-schema", schema); !strings.Contains(out, `"city": "Paris"`) {
+		t.Errorf("human output:\n%s", out)
+	}
+
+	// An answer that does not match is retried, then reported by path.
+	stdout, stderr, err := run(t, "answer", "Where is Atlantis?", "--output-schema", schema, "--retry-on-invalid", "1", "--json")
+	if code := exitCode(t, err); code != 7 {
+		t.Fatalf("invalid answer: exit code %d, want 7\nstdout: %s\nstderr: %s", code, stdout, stderr)
+	}
+	if strings.Contains(stderr, "Note:") {
+		t.Errorf("--json should not print notes:\n%s", stderr)
+	}
+	if _, human, _ := run(t, "answer", "Where is Atlantis?", "--output-schema", schema, "--retry-on-invalid", "1"); !strings.Contains(human, "Note: answer does not match schema") || !strings.Contains(human, "retrying (1/1)") {
+		t.Errorf("no retry note:\n%s", human)
+	}
+	var cliErr struct {
+		Code   string `json:"code"`
+		Issues []struct {
+			Path    string `json:"path"`
+			Message string `json:"message"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal([]byte(stderr[strings.LastIndex(stderr, "{\"code\""):]), &cliErr); err != nil {
+		t.Fatalf("error JSON: %v\n%s", err, stderr)
+	}
+	if cliErr.Code != "INVALID_ANSWER" || len(cliErr.Issues) != 1 ||
+		cliErr.Issues[0].Path != "$.population" || cliErr.Issues[0].Message != "expected integer, got string" {
+		t.Errorf("error = %+v", cliErr)
+	}
+
+	// The schema itself is checked before any request is sent.
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"properties": {"city": {"type": "text"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, err = run(t, "answer", "anything", "--output-schema", bad)
+	if code := exitCode(t, err); code != 2 || !strings.Contains(stderr, `#/properties/city/type: unknown type "text"`) {
+		t.Errorf("bad schema: exit code %d\n%s", code, stderr)
+	}
+}
@@ -27,6 +27,6 @@ func main() {
 	cmd.SetReadmeContents(readmeContents)
 	cmd.SetSkillData(skillMD, commandsRef, skillFS)
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
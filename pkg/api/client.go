@@ -5,9 +5,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"time"
 )
@@ -17,9 +19,21 @@ var Version = "dev"
 // Client is the Exa API client.
 type Client struct {
 	httpClient *http.Client
+	transport  http.RoundTripper
 	apiKey     string
 	baseURL    string
+	retry      RetryPolicy
+	cache      ResponseCache
 	debug      func(string, ...interface{})
+	guards     []Guard
+	observers  []Observer
+}
+
+// ResponseCache stores successful responses keyed on endpoint and request
+// body. Only idempotent lookups (search, contents, findSimilar) are cached.
+type ResponseCache interface {
+	Get(endpoint string, body []byte) ([]byte, bool)
+	Put(endpoint string, body, resp []byte) error
 }
 
 // NewClient creates a new API client.
@@ -30,9 +44,27 @@ func NewClient(baseURL, apiKey string) *Client {
 		},
 		apiKey:  apiKey,
 		baseURL: strings.TrimRight(baseURL, "/"),
+		retry:   DefaultRetryPolicy(),
 	}
 }
 
+// SetRetryPolicy replaces the client's retry policy.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
+}
+
+// SetTransport replaces the HTTP transport used for all requests,
+// including streaming ones. A nil transport restores the default.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.transport = rt
+	c.httpClient.Transport = rt
+}
+
+// SetCache enables response caching for search, contents and findSimilar.
+func (c *Client) SetCache(rc ResponseCache) {
+	c.cache = rc
+}
+
 // SetDebug enables debug logging.
 func (c *Client) SetDebug(fn func(string, ...interface{})) {
 	c.debug = fn
@@ -45,80 +77,192 @@ func (c *Client) debugLog(format string, args ...interface{}) {
 }
 
 func (c *Client) doJSON(ctx context.Context, method, endpoint string, body, result interface{}) error {
+	_, err := c.do(ctx, method, endpoint, body, result, false)
+	return err
+}
+
+// doCached is doJSON for cacheable POST endpoints. It reports whether the
+// result was served from the cache.
+func (c *Client) doCached(ctx context.Context, endpoint string, body, result interface{}) (bool, error) {
+	call, err := c.do(ctx, http.MethodPost, endpoint, body, result, c.cache != nil)
+	if err != nil {
+		return false, err
+	}
+	return call.Cached, nil
+}
+
+// cacheableEndpoints are the idempotent lookups Forward may cache.
+var cacheableEndpoints = map[string]bool{"/search": true, "/contents": true, "/findSimilar": true}
+
+// Forward posts a raw JSON body to endpoint and returns the completed call,
+// whose Response holds the raw response body. Search, contents and
+// findSimilar requests use the cache like the typed methods.
+func (c *Client) Forward(ctx context.Context, endpoint string, body []byte) (*Call, error) {
+	return c.do(ctx, http.MethodPost, endpoint, json.RawMessage(body), nil, c.cache != nil && cacheableEndpoints[endpoint])
+}
+
+func (c *Client) do(ctx context.Context, method, endpoint string, body, result interface{}, cacheable bool) (*Call, error) {
 	url := fmt.Sprintf("%s/%s", c.baseURL, strings.TrimLeft(endpoint, "/"))
 
-	var reqBody io.Reader
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
-			return fmt.Errorf("marshal request: %w", err)
+			return nil, fmt.Errorf("marshal request: %w", err)
 		}
 		c.debugLog("%s %s body=%s", method, url, string(jsonBody))
-		reqBody = bytes.NewBuffer(jsonBody)
 	} else {
 		c.debugLog("%s %s", method, url)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+	call := &Call{Method: method, URL: url, Endpoint: endpoint, Body: jsonBody, Caller: CallerFrom(ctx)}
+	if cacheable {
+		if cached, ok := c.cache.Get(endpoint, jsonBody); ok {
+			c.debugLog("Cache hit for %s", endpoint)
+			if result != nil {
+				if err := json.Unmarshal(cached, result); err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrDecode, err)
+				}
+			}
+			c.observe(call, cached, true)
+			return call, nil
+		}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", c.apiKey)
-	req.Header.Set("User-Agent", "exa-cli/"+Version)
-
-	resp, err := c.httpClient.Do(req)
+	if err := c.guard(ctx, call); err != nil {
+		return nil, err
+	}
+	resp, err := c.send(ctx, c.httpClient, method, url, jsonBody, "", idempotent(method, endpoint))
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("read response: %w", err)
+		return nil, transportError(err)
 	}
 
 	c.debugLog("Response status: %d", resp.StatusCode)
 	c.debugLog("Response body: %s", truncate(string(respBody), 2000))
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, truncate(string(respBody), 500))
-	}
-
 	if result != nil {
 		if err := json.Unmarshal(respBody, result); err != nil {
-			return fmt.Errorf("parse response: %w", err)
+			return nil, fmt.Errorf("%w: %w", ErrDecode, err)
 		}
+	} else if !json.Valid(respBody) {
+		return nil, fmt.Errorf("%w: invalid JSON response", ErrDecode)
 	}
 
-	return nil
+	if cacheable {
+		if err := c.cache.Put(endpoint, jsonBody, respBody); err != nil {
+			c.debugLog("Cache write failed: %s", err)
+		}
+	}
+
+	c.observe(call, respBody, false)
+	return call, nil
+}
+
+// send performs a request, retrying transient failures according to the
+// client's retry policy. A request that is not idempotent is retried only
+// on 429 or when it failed before being written to the server. On success
+// the caller owns the response body.
+func (c *Client) send(ctx context.Context, hc *http.Client, method, url string, body []byte, accept string, idempotent bool) (*http.Response, error) {
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		var wrote bool
+		traced := httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			WroteHeaders: func() { wrote = true },
+		})
+		req, err := http.NewRequestWithContext(traced, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", c.apiKey)
+		req.Header.Set("User-Agent", "exa-cli/"+Version)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		resp, err := hc.Do(req)
+		if err != nil {
+			var permanent *PermanentError
+			if ctx.Err() != nil || attempt >= attempts || errors.As(err, &permanent) || (wrote && !idempotent) {
+				return nil, transportError(err)
+			}
+			wait := c.retry.delay(attempt, "")
+			c.debugLog("Request failed (%s), retrying in %s (attempt %d/%d)", err, wait, attempt+1, attempts)
+			if err := sleepCtx(ctx, wait); err != nil {
+				return nil, transportError(err)
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		respBody, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		c.debugLog("Response status: %d", resp.StatusCode)
+
+		apiErr := newAPIError(resp, respBody)
+		retry := apiErr.Retryable && (idempotent || resp.StatusCode == http.StatusTooManyRequests)
+		if !retry || attempt >= attempts {
+			return nil, apiErr
+		}
+
+		wait := c.retry.delay(attempt, resp.Header.Get("Retry-After"))
+		c.debugLog("Status %d, retrying in %s (attempt %d/%d)", resp.StatusCode, wait, attempt+1, attempts)
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, transportError(err)
+		}
+	}
 }
 
 // Search performs a web search.
 func (c *Client) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
 	var resp SearchResponse
-	if err := c.doJSON(ctx, http.MethodPost, "/search", req, &resp); err != nil {
+	cached, err := c.doCached(ctx, "/search", req, &resp)
+	if err != nil {
 		return nil, err
 	}
+	resp.Cached = cached
 	return &resp, nil
 }
 
 // GetContents retrieves page contents by URL or ID.
 func (c *Client) GetContents(ctx context.Context, req *ContentsRequest) (*ContentsResponse, error) {
 	var resp ContentsResponse
-	if err := c.doJSON(ctx, http.MethodPost, "/contents", req, &resp); err != nil {
+	cached, err := c.doCached(ctx, "/contents", req, &resp)
+	if err != nil {
 		return nil, err
 	}
+	resp.Cached = cached
 	return &resp, nil
 }
 
 // FindSimilar finds pages similar to a URL.
 func (c *Client) FindSimilar(ctx context.Context, req *FindSimilarRequest) (*FindSimilarResponse, error) {
 	var resp FindSimilarResponse
-	if err := c.doJSON(ctx, http.MethodPost, "/findSimilar", req, &resp); err != nil {
+	cached, err := c.doCached(ctx, "/findSimilar", req, &resp)
+	if err != nil {
 		return nil, err
 	}
+	resp.Cached = cached
 	return &resp, nil
 }
 
@@ -135,68 +279,98 @@ func (c *Client) Answer(ctx context.Context, req *AnswerRequest) (*AnswerRespons
 // It calls textFn for each text chunk and doneFn with the final response.
 func (c *Client) AnswerStream(ctx context.Context, req *AnswerRequest, textFn func(string), doneFn func(*AnswerResponse)) error {
 	req.StreamOutput = true
-	url := fmt.Sprintf("%s/answer", c.baseURL)
-
 	jsonBody, err := json.Marshal(req)
 	if err != nil {
 		return fmt.Errorf("marshal request: %w", err)
 	}
-	c.debugLog("POST %s body=%s", url, string(jsonBody))
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+	_, err = c.stream(ctx, jsonBody, func(_ string, chunk *AnswerStreamChunk) {
+		if chunk == nil {
+			return
+		}
+		if chunk.Text != "" && textFn != nil {
+			textFn(chunk.Text)
+		}
+		// Final chunk with citations
+		if chunk.Citations != nil && doneFn != nil {
+			doneFn(&AnswerResponse{
+				Answer:      chunk.Answer,
+				Citations:   chunk.Citations,
+				CostDollars: chunk.CostDollars,
+			})
+		}
+	})
+	return err
+}
+
+// ForwardAnswerStream posts a raw streaming /answer body and passes each
+// server-sent event line to lineFn unchanged. The returned call holds the
+// assembled answer.
+func (c *Client) ForwardAnswerStream(ctx context.Context, body []byte, lineFn func(line string)) (*Call, error) {
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("marshal request: invalid JSON body")
 	}
+	return c.stream(ctx, body, func(line string, _ *AnswerStreamChunk) { lineFn(line) })
+}
+
+// stream sends a streaming /answer request and calls fn for every line of
+// the event stream, with the parsed chunk for data lines.
+func (c *Client) stream(ctx context.Context, jsonBody []byte, fn func(line string, chunk *AnswerStreamChunk)) (*Call, error) {
+	url := fmt.Sprintf("%s/answer", c.baseURL)
+	c.debugLog("POST %s body=%s", url, string(jsonBody))
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", c.apiKey)
-	httpReq.Header.Set("User-Agent", "exa-cli/"+Version)
-	httpReq.Header.Set("Accept", "text/event-stream")
+	call := &Call{Method: http.MethodPost, URL: url, Endpoint: "/answer", Body: jsonBody, Stream: true, Caller: CallerFrom(ctx)}
+	if err := c.guard(ctx, call); err != nil {
+		return nil, err
+	}
 
-	// Use a separate client without timeout for streaming
-	streamClient := &http.Client{}
-	resp, err := streamClient.Do(httpReq)
+	// Use a separate client without timeout for streaming. Retries only
+	// apply until a successful response starts streaming.
+	streamClient := &http.Client{Transport: c.transport}
+	resp, err := c.send(ctx, streamClient, http.MethodPost, url, jsonBody, "text/event-stream", false)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, truncate(string(body), 500))
-	}
+	// The assembled answer is reported to observers once the stream ends.
+	var text strings.Builder
+	final := &AnswerResponse{}
 
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			fn(line, nil)
+			if data == "[DONE]" {
+				break
+			}
 			continue
 		}
-		data := strings.TrimPrefix(line, "data: ")
-		if data == "[DONE]" {
-			break
-		}
 
 		var chunk AnswerStreamChunk
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			c.debugLog("Failed to parse SSE chunk: %s", err)
+			fn(line, nil)
 			continue
 		}
-
-		if chunk.Text != "" && textFn != nil {
-			textFn(chunk.Text)
-		}
-		// Final chunk with citations
-		if chunk.Citations != nil && doneFn != nil {
-			doneFn(&AnswerResponse{
-				Answer:      chunk.Answer,
-				Citations:   chunk.Citations,
-				CostDollars: chunk.CostDollars,
-			})
+		text.WriteString(chunk.Text)
+		if chunk.Citations != nil {
+			final.Citations = chunk.Citations
+			final.CostDollars = chunk.CostDollars
 		}
+		fn(line, &chunk)
 	}
 
-	return scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return nil, transportError(err)
+	}
+	final.Answer = text.String()
+	if data, err := json.Marshal(final); err == nil {
+		c.observe(call, data, false)
+	}
+	return call, nil
 }
 
 // GetContext retrieves code context.
@@ -227,6 +401,27 @@ func (c *Client) GetUsage(ctx context.Context, keyID, startDate, endDate string)
 	return &resp, nil
 }
 
+// VerifyKey checks that the API accepts the client's key without running a
+// billed request. It posts a search with no query, which every key may call:
+// the API rejects it with 401 for an unknown key, 403 for a key that may not
+// search, and only checks the body once the key is accepted, so a 400 naming
+// the missing query means the key is valid. Any other 400, or a success,
+// returns ErrUnverified.
+func (c *Client) VerifyKey(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodPost, "/search", struct{}{}, nil, false)
+	if err == nil {
+		return ErrUnverified
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return err
+	}
+	if !strings.Contains(strings.ToLower(apiErr.Message), "query") {
+		return fmt.Errorf("%w: %v", ErrUnverified, err)
+	}
+	return nil
+}
+
 func truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
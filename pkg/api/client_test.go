@@ -0,0 +1,362 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sequenceServer replies with the given status codes in order, then 200s.
+func sequenceServer(t *testing.T, statuses []int, headers map[string]string) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			for k, v := range headers {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(statuses[n-1])
+			fmt.Fprint(w, `{"error":"transient"}`)
+			return
+		}
+		if r.URL.Path == "/answer" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"text\":\"hello\"}\n\n")
+			fmt.Fprint(w, "data: {\"citations\":[],\"costDollars\":{\"total\":0.005}}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"results":[{"title":"ok","url":"https://example.com","id":"1"}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func fastRetry(attempts int) RetryPolicy {
+	return RetryPolicy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+}
+
+func TestRetry_RecoversFromTransientStatuses(t *testing.T) {
+	srv, calls := sequenceServer(t, []int{429, 503}, nil)
+	c := NewClient(srv.URL, "key")
+	c.SetRetryPolicy(fastRetry(3))
+
+	resp, err := c.Search(context.Background(), &SearchRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Errorf("got %d results, want 1", len(resp.Results))
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("server saw %d calls, want 3", got)
+	}
+}
+
+func TestRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	srv, calls := sequenceServer(t, []int{503, 503, 503, 503}, nil)
+	c := NewClient(srv.URL, "key")
+	c.SetRetryPolicy(fastRetry(2))
+
+	_, err := c.Search(context.Background(), &SearchRequest{Query: "q"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 503 || !apiErr.Retryable {
+		t.Fatalf("expected retryable 503 APIError, got %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("server saw %d calls, want 2", got)
+	}
+}
+
+func TestRetry_DoesNotRetryClientErrors(t *testing.T) {
+	srv, calls := sequenceServer(t, []int{400}, nil)
+	c := NewClient(srv.URL, "key")
+	c.SetRetryPolicy(fastRetry(3))
+
+	if _, err := c.Search(context.Background(), &SearchRequest{Query: "q"}); err == nil {
+		t.Fatal("expected error for 400")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("server saw %d calls, want 1", got)
+	}
+}
+
+func TestRetry_HonorsRetryAfterCappedByMaxDelay(t *testing.T) {
+	srv, _ := sequenceServer(t, []int{429}, map[string]string{"Retry-After": "120"})
+	c := NewClient(srv.URL, "key")
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 20 * time.Millisecond})
+
+	start := time.Now()
+	if _, err := c.Search(context.Background(), &SearchRequest{Query: "q"}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("elapsed %s, want ~20ms (Retry-After capped by MaxDelay)", elapsed)
+	}
+}
+
+func TestRetry_AnswerStreamRetriesRateLimits(t *testing.T) {
+	srv, calls := sequenceServer(t, []int{429, 429}, nil)
+	c := NewClient(srv.URL, "key")
+	c.SetRetryPolicy(fastRetry(3))
+
+	var text strings.Builder
+	var done *AnswerResponse
+	err := c.AnswerStream(context.Background(), &AnswerRequest{Query: "q"},
+		func(s string) { text.WriteString(s) },
+		func(r *AnswerResponse) { done = r },
+	)
+	if err != nil {
+		t.Fatalf("AnswerStream: %v", err)
+	}
+	if text.String() != "hello" {
+		t.Errorf("streamed text = %q, want %q", text.String(), "hello")
+	}
+	if done == nil || done.CostDollars == nil {
+		t.Error("expected final response with cost")
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("server saw %d calls, want 3", got)
+	}
+}
+
+func TestRetry_AnswerNotRetriedAfterServerError(t *testing.T) {
+	// The answer may have been generated and billed before the 5xx.
+	srv, calls := sequenceServer(t, []int{502}, nil)
+	c := NewClient(srv.URL, "key")
+	c.SetRetryPolicy(fastRetry(3))
+
+	var apiErr *APIError
+	if _, err := c.Answer(context.Background(), &AnswerRequest{Query: "q"}); !errors.As(err, &apiErr) || apiErr.StatusCode != 502 {
+		t.Fatalf("expected 502 APIError, got %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("server saw %d calls, want 1", got)
+	}
+}
+
+func TestRetry_AnswerRetriedWhenNotSent(t *testing.T) {
+	var calls int32
+	c := NewClient("http://exa.invalid", "key")
+	c.SetRetryPolicy(fastRetry(3))
+	c.SetTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("connection refused")
+	}))
+
+	if _, err := c.Answer(context.Background(), &AnswerRequest{Query: "q"}); !errors.Is(err, ErrNetwork) {
+		t.Fatalf("expected network error, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("transport saw %d attempts, want 3", got)
+	}
+}
+
+func TestRetry_PermanentErrorNotRetried(t *testing.T) {
+	var calls int32
+	c := NewClient("http://exa.invalid", "key")
+	c.SetRetryPolicy(fastRetry(3))
+	c.SetTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, &PermanentError{Err: errors.New("not recorded")}
+	}))
+
+	if _, err := c.Search(context.Background(), &SearchRequest{Query: "q"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("transport saw %d attempts, want 1", got)
+	}
+}
+
+func TestVerifyKey(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.Header.Get("x-api-key") {
+		case "good":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"query is required"}`)
+		case "other-400":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"request body too large"}`)
+		case "ok":
+			fmt.Fprint(w, `{"results":[]}`)
+		case "restricted":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	if err := NewClient(srv.URL, "good").VerifyKey(context.Background()); err != nil {
+		t.Errorf("valid key: %v", err)
+	}
+	for key, status := range map[string]int{"bad": 401, "restricted": 403} {
+		var apiErr *APIError
+		if err := NewClient(srv.URL, key).VerifyKey(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != status {
+			t.Errorf("%s key: %v, want status %d", key, err, status)
+		}
+	}
+	for _, key := range []string{"other-400", "ok"} {
+		if err := NewClient(srv.URL, key).VerifyKey(context.Background()); !errors.Is(err, ErrUnverified) {
+			t.Errorf("%s: %v, want ErrUnverified", key, err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 5 {
+		t.Errorf("server saw %d calls, want 5 (no retries)", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.in, now)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAPIError_ParsesBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"requestId":"req_123","error":"Invalid API key","tag":"INVALID_API_KEY"}`)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, "bad").Search(context.Background(), &SearchRequest{Query: "q"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T: %v", err, err)
+	}
+	if apiErr.StatusCode != 401 || apiErr.Code != "INVALID_API_KEY" || apiErr.Message != "Invalid API key" || apiErr.RequestID != "req_123" {
+		t.Errorf("unexpected APIError fields: %+v", apiErr)
+	}
+	if apiErr.Retryable {
+		t.Error("401 should not be retryable")
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results": not-json`)
+	}))
+	c := NewClient(srv.URL, "key")
+	c.SetRetryPolicy(fastRetry(1))
+
+	if _, err := c.Search(context.Background(), &SearchRequest{Query: "q"}); !errors.Is(err, ErrDecode) {
+		t.Errorf("malformed body: expected ErrDecode, got %v", err)
+	}
+
+	srv.Close()
+	if _, err := c.Search(context.Background(), &SearchRequest{Query: "q"}); !errors.Is(err, ErrNetwork) {
+		t.Errorf("closed server: expected ErrNetwork, got %v", err)
+	}
+}
+
+type memCache map[string][]byte
+
+func (m memCache) Get(endpoint string, body []byte) ([]byte, bool) {
+	v, ok := m[endpoint+string(body)]
+	return v, ok
+}
+
+func (m memCache) Put(endpoint string, body, resp []byte) error {
+	m[endpoint+string(body)] = resp
+	return nil
+}
+
+func TestCache_ServesRepeatedSearch(t *testing.T) {
+	srv, calls := sequenceServer(t, nil, nil)
+	c := NewClient(srv.URL, "key")
+	c.SetCache(memCache{})
+
+	first, err := c.Search(context.Background(), &SearchRequest{Query: "q"})
+	if err != nil || first.Cached {
+		t.Fatalf("first Search = cached:%v, err:%v", first != nil && first.Cached, err)
+	}
+	second, err := c.Search(context.Background(), &SearchRequest{Query: "q"})
+	if err != nil || !second.Cached || len(second.Results) != 1 {
+		t.Fatalf("second Search should be a cache hit, got %+v, %v", second, err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("server saw %d calls, want 1", got)
+	}
+}
+
+func TestHooks_GuardBlocksAndObserverSeesCost(t *testing.T) {
+	srv, calls := sequenceServer(t, nil, nil)
+	c := NewClient(srv.URL, "key")
+
+	var seen []*Call
+	c.AddObserver(func(call *Call) { seen = append(seen, call) })
+	if err := c.AnswerStream(context.Background(), &AnswerRequest{Query: "q"}, nil, nil); err != nil {
+		t.Fatalf("AnswerStream: %v", err)
+	}
+	if len(seen) != 1 || seen[0].Endpoint != "/answer" || seen[0].Cost == nil || seen[0].Cost.Total != 0.005 {
+		t.Fatalf("observer saw %+v", seen)
+	}
+	if !strings.Contains(string(seen[0].Response), `"answer":"hello"`) {
+		t.Errorf("stream response = %s, want assembled answer", seen[0].Response)
+	}
+
+	blocked := errors.New("blocked")
+	c.AddGuard(func(ctx context.Context, call *Call) error { return blocked })
+	if _, err := c.Search(context.Background(), &SearchRequest{Query: "q"}); !errors.Is(err, blocked) {
+		t.Fatalf("Search err = %v, want guard error", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("server saw %d calls, want 1", got)
+	}
+}
+
+func TestParseCost(t *testing.T) {
+	for _, raw := range []string{`{"total":0.01}`, `"{\"total\":0.01}"`} {
+		if c := ParseCost([]byte(raw)); c == nil || c.Total != 0.01 {
+			t.Errorf("ParseCost(%s) = %+v", raw, c)
+		}
+	}
+	if c := ParseCost([]byte(`null`)); c != nil {
+		t.Errorf("ParseCost(null) = %+v, want nil", c)
+	}
+}
+
+func TestAnswerResponse_StructuredAnswer(t *testing.T) {
+	for raw, want := range map[string]string{
+		`{"answer":"Paris","costDollars":{"total":0.005}}`:        "Paris",
+		`{"answer":{"city": "Paris", "rank": 1},"requestId":"r"}`: `{"city":"Paris","rank":1}`,
+		`{"answer":null}`: "",
+	} {
+		var resp AnswerResponse
+		if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+			t.Fatalf("%s: %v", raw, err)
+		}
+		if resp.Answer != want {
+			t.Errorf("%s: Answer = %q, want %q", raw, resp.Answer, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Sentinel errors for failures that never produced an API response.
+// Use errors.Is to test for them; the underlying cause is wrapped as well.
+var (
+	ErrNetwork = errors.New("request failed")
+	ErrTimeout = errors.New("request timed out")
+	ErrDecode  = errors.New("parse response")
+)
+
+// ErrUnverified is returned by VerifyKey when the API answered but its
+// response neither accepts nor rejects the key.
+var ErrUnverified = errors.New("the API response did not confirm the key")
+
+// ErrDryRun is returned by a guard that printed a request instead of
+// sending it. Callers treat it as success.
+var ErrDryRun = errors.New("dry run: request not sent")
+
+// PermanentError wraps a transport failure that retrying cannot fix, such
+// as a request missing from a replayed cassette.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// APIError is returned when the Exa API responds with a non-2xx status.
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Code       string // Exa error code (tag), if provided
+	Message    string // Human-readable message from the API
+	RequestID  string // Exa request ID, if provided
+	Retryable  bool   // Whether the request may succeed if retried
+	Body       []byte // Raw response body
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = truncate(string(e.Body), 500)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("API error (status %d, %s): %s", e.StatusCode, e.Code, msg)
+	}
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, msg)
+}
+
+// newAPIError builds an APIError from a failed response and its body.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Retryable:  retryableStatus(resp.StatusCode),
+		Body:       body,
+		RequestID:  resp.Header.Get("x-request-id"),
+	}
+
+	var payload struct {
+		RequestID string          `json:"requestId"`
+		Error     json.RawMessage `json:"error"`
+		Message   string          `json:"message"`
+		Tag       string          `json:"tag"`
+		Code      string          `json:"code"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return e
+	}
+
+	if payload.RequestID != "" {
+		e.RequestID = payload.RequestID
+	}
+	e.Code = payload.Tag
+	if e.Code == "" {
+		e.Code = payload.Code
+	}
+	e.Message = payload.Message
+
+	// "error" is usually a string but some endpoints nest an object.
+	var s string
+	if err := json.Unmarshal(payload.Error, &s); err == nil && s != "" {
+		e.Message = s
+	} else {
+		var nested struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		}
+		if err := json.Unmarshal(payload.Error, &nested); err == nil {
+			if nested.Message != "" {
+				e.Message = nested.Message
+			}
+			if e.Code == "" {
+				e.Code = nested.Code
+			}
+		}
+	}
+	return e
+}
+
+// transportError classifies an error from http.Client.Do.
+func transportError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	default:
+		return fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Call describes one API call, as seen by a Guard before it is sent and by
+// an Observer after it succeeds.
+type Call struct {
+	Method   string
+	URL      string
+	Endpoint string
+	Body     []byte // JSON request body, nil for GET requests
+	Stream   bool   // Response is read as server-sent events
+	Caller   string // Set from the request context by WithCaller
+
+	// Set for observers only.
+	Response  []byte    // JSON response body; the assembled answer for streams
+	Cost      *CostInfo // Parsed costDollars, nil if the response has none
+	RequestID string
+	Cached    bool // Served from the response cache, nothing was sent
+}
+
+// Guard is consulted before a request goes out. Returning an error aborts
+// the call with that error. Cache hits do not consult the guard.
+type Guard func(ctx context.Context, call *Call) error
+
+// Observer is notified of every successful call, including cache hits.
+// Observers may run concurrently when the client is shared.
+type Observer func(call *Call)
+
+// AddGuard registers a guard; guards run in registration order.
+func (c *Client) AddGuard(g Guard) {
+	c.guards = append(c.guards, g)
+}
+
+// AddObserver registers an observer.
+func (c *Client) AddObserver(o Observer) {
+	c.observers = append(c.observers, o)
+}
+
+func (c *Client) guard(ctx context.Context, call *Call) error {
+	for _, g := range c.guards {
+		if err := g(ctx, call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callerKey is the context key for WithCaller.
+type callerKey struct{}
+
+// WithCaller labels calls made with ctx as made on behalf of caller, such
+// as a client of a proxy. The label is visible to guards and observers.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the label set by WithCaller, or "".
+func CallerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// observe fills in the response details of call and notifies observers.
+func (c *Client) observe(call *Call, resp []byte, cached bool) {
+	var meta struct {
+		RequestID   string          `json:"requestId"`
+		CostDollars json.RawMessage `json:"costDollars"`
+	}
+	_ = json.Unmarshal(resp, &meta)
+	call.Response = resp
+	call.RequestID = meta.RequestID
+	call.Cost = ParseCost(meta.CostDollars)
+	call.Cached = cached
+	for _, o := range c.observers {
+		o(call)
+	}
+}
+
+// ParseCost decodes a costDollars value, which the API sends either as an
+// object or as a string containing JSON. It returns nil if raw is empty or
+// unparseable.
+func ParseCost(raw json.RawMessage) *CostInfo {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var cost CostInfo
+	if err := json.Unmarshal(raw, &cost); err == nil {
+		return &cost
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if err := json.Unmarshal([]byte(s), &cost); err == nil {
+			return &cost
+		}
+	}
+	return nil
+}
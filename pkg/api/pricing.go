@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+)
+
+// PriceTable holds the list prices, in dollars, used to estimate the cost
+// of a request before it is sent. Actual charges come from costDollars.
+type PriceTable struct {
+	Search      map[string]float64 // Per search request by type; "" is the default
+	SearchLarge float64            // Per search request with more than LargeResults results
+	Text        float64            // Per page with text
+	Highlights  float64            // Per page with highlights
+	Summary     float64            // Per page with a summary
+	Answer      float64            // Per answer
+	Context     float64            // Per code context request
+}
+
+// LargeResults is the result count above which SearchLarge applies.
+const LargeResults = 25
+
+// defaultNumResults is the API's numResults when none is given.
+const defaultNumResults = 10
+
+// DefaultPrices is Exa's published pricing.
+var DefaultPrices = PriceTable{
+	Search:      map[string]float64{"": 0.005, "deep": 0.015},
+	SearchLarge: 0.025,
+	Text:        0.001,
+	Highlights:  0.001,
+	Summary:     0.001,
+	Answer:      0.005,
+	Context:     0.015,
+}
+
+// Estimate is the expected cost of a request.
+type Estimate struct {
+	Total float64        `json:"total"`
+	Items []EstimateItem `json:"items"`
+}
+
+// EstimateItem is one priced component of an Estimate.
+type EstimateItem struct {
+	Item    string  `json:"item"`
+	Count   int     `json:"count"`
+	Unit    float64 `json:"unit"`
+	Dollars float64 `json:"dollars"`
+}
+
+func (e *Estimate) add(item string, count int, unit float64) {
+	if count <= 0 || unit <= 0 {
+		return
+	}
+	dollars := math.Round(unit*float64(count)*1e6) / 1e6
+	e.Items = append(e.Items, EstimateItem{Item: item, Count: count, Unit: unit, Dollars: dollars})
+	e.Total = math.Round((e.Total+dollars)*1e6) / 1e6
+}
+
+// Estimate prices call from its endpoint and request body. Content is
+// priced per requested result, so searches that return fewer results cost
+// less than estimated. Endpoints without a list price estimate as zero.
+func (p PriceTable) Estimate(call *Call) Estimate {
+	e := Estimate{Items: []EstimateItem{}}
+	if call.Method != http.MethodPost {
+		return e
+	}
+
+	switch call.Endpoint {
+	case "/search":
+		var req SearchRequest
+		_ = json.Unmarshal(call.Body, &req)
+		n := numResults(req.NumResults)
+		p.addSearch(&e, req.Type, n)
+		p.addContents(&e, req.Contents, n)
+	case "/findSimilar":
+		var req FindSimilarRequest
+		_ = json.Unmarshal(call.Body, &req)
+		n := numResults(req.NumResults)
+		p.addSearch(&e, "", n)
+		p.addContents(&e, req.Contents, n)
+	case "/contents":
+		var req ContentsRequest
+		_ = json.Unmarshal(call.Body, &req)
+		p.addContents(&e, &ContentsSpec{Text: req.Text, Highlights: req.Highlights, Summary: req.Summary},
+			len(req.URLs)+len(req.IDs))
+	case "/answer":
+		e.add("answer", 1, p.Answer)
+	case "/context":
+		e.add("context", 1, p.Context)
+	}
+	return e
+}
+
+func numResults(n int) int {
+	if n <= 0 {
+		return defaultNumResults
+	}
+	return n
+}
+
+func (p PriceTable) addSearch(e *Estimate, searchType string, n int) {
+	price, ok := p.Search[searchType]
+	if !ok {
+		price = p.Search[""]
+	}
+	label := "search"
+	if searchType != "" {
+		label += " (" + searchType + ")"
+	}
+	if n > LargeResults && searchType != "deep" {
+		price = p.SearchLarge
+		label += " 26-100 results"
+	}
+	e.add(label, 1, price)
+}
+
+func (p PriceTable) addContents(e *Estimate, spec *ContentsSpec, pages int) {
+	if spec == nil {
+		return
+	}
+	if spec.Text != nil {
+		e.add("text", pages, p.Text)
+	}
+	if spec.Highlights != nil {
+		e.add("highlights", pages, p.Highlights)
+	}
+	if spec.Summary != nil {
+		e.add("summary", pages, p.Summary)
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	body := func(v interface{}) []byte {
+		data, _ := json.Marshal(v)
+		return data
+	}
+	tests := []struct {
+		name string
+		call Call
+		want float64
+	}{
+		{"default search", Call{Method: http.MethodPost, Endpoint: "/search", Body: body(SearchRequest{Query: "q"})}, 0.005},
+		{"deep search with text", Call{Method: http.MethodPost, Endpoint: "/search", Body: body(SearchRequest{
+			Query: "q", Type: "deep", NumResults: 5, Contents: &ContentsSpec{Text: &TextSpec{}},
+		})}, 0.02},
+		{"large search with summary", Call{Method: http.MethodPost, Endpoint: "/search", Body: body(SearchRequest{
+			Query: "q", NumResults: 50, Contents: &ContentsSpec{Summary: &SummarySpec{}},
+		})}, 0.075},
+		{"contents", Call{Method: http.MethodPost, Endpoint: "/contents", Body: body(ContentsRequest{
+			URLs: []string{"a", "b"}, Text: &TextSpec{}, Highlights: &HighlightsSpec{},
+		})}, 0.004},
+		{"answer", Call{Method: http.MethodPost, Endpoint: "/answer", Body: body(AnswerRequest{Query: "q"})}, 0.005},
+		{"team management", Call{Method: http.MethodGet, Endpoint: "/team-management/api-keys"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultPrices.Estimate(&tt.call).Total; got != tt.want {
+				t.Errorf("Estimate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the client retries transient failures
+// (HTTP 429, 5xx and network errors). Requests that are not idempotent,
+// such as answers, are only retried on 429 or when the request never
+// reached the server.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 1 are treated as 1 (no retries).
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry; it doubles on each
+	// subsequent attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps any single wait, including waits requested via Retry-After.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0-1) of each backoff delay that is randomized.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns the policy used by NewClient.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// retryableStatus reports whether an HTTP status code is worth retrying.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// idempotentEndpoints are the POST endpoints that only look things up, so a
+// request that may have reached the server can be sent again.
+var idempotentEndpoints = map[string]bool{"/search": true, "/contents": true, "/findSimilar": true, "/context": true}
+
+// idempotent reports whether a request may be repeated after it could have
+// been processed upstream.
+func idempotent(method, endpoint string) bool {
+	return method == http.MethodGet || idempotentEndpoints[endpoint]
+}
+
+// delay returns how long to wait before retry number attempt (1-based),
+// preferring the server's Retry-After value when present.
+func (p RetryPolicy) delay(attempt int, retryAfter string) time.Duration {
+	if d, ok := parseRetryAfter(retryAfter, time.Now()); ok {
+		return p.clamp(d)
+	}
+
+	d := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.Jitter > 0 {
+		j := math.Min(p.Jitter, 1)
+		d = d*(1-j) + d*j*rand.Float64()
+	}
+	return p.clamp(time.Duration(d))
+}
+
+func (p RetryPolicy) clamp(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header given either as
+// delta-seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now), true
+	}
+	return 0, false
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
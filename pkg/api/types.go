@@ -1,6 +1,9 @@
 package api
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+)
 
 // SearchRequest is the request body for POST /search
 type SearchRequest struct {
@@ -62,6 +65,7 @@ type SearchResponse struct {
 	Results         []SearchResult `json:"results"`
 	AutopromptString string       `json:"autopromptString,omitempty"`
 	CostDollars     *CostInfo      `json:"costDollars,omitempty"`
+	Cached          bool           `json:"cached,omitempty"`
 }
 
 // SearchResult is a single search result.
@@ -115,6 +119,7 @@ type ContentsRequest struct {
 type ContentsResponse struct {
 	Results     []SearchResult `json:"results"`
 	CostDollars *CostInfo      `json:"costDollars,omitempty"`
+	Cached      bool           `json:"cached,omitempty"`
 }
 
 // FindSimilarRequest is the request body for POST /findSimilar
@@ -136,6 +141,7 @@ type FindSimilarResponse struct {
 	Results         []SearchResult `json:"results"`
 	AutopromptString string       `json:"autopromptString,omitempty"`
 	CostDollars     *CostInfo      `json:"costDollars,omitempty"`
+	Cached          bool           `json:"cached,omitempty"`
 }
 
 // AnswerRequest is the request body for POST /answer
@@ -155,6 +161,32 @@ type AnswerResponse struct {
 	CostDollars *CostInfo      `json:"costDollars,omitempty"`
 }
 
+// UnmarshalJSON accepts a structured answer (returned for requests with an
+// outputSchema) as well as text, keeping a structured answer as compact JSON.
+func (r *AnswerResponse) UnmarshalJSON(data []byte) error {
+	type plain AnswerResponse
+	aux := struct {
+		*plain
+		Answer json.RawMessage `json:"answer"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Answer = ""
+	if len(aux.Answer) == 0 || string(aux.Answer) == "null" {
+		return nil
+	}
+	if aux.Answer[0] == '"' {
+		return json.Unmarshal(aux.Answer, &r.Answer)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, aux.Answer); err != nil {
+		return err
+	}
+	r.Answer = buf.String()
+	return nil
+}
+
 // AnswerStreamChunk represents a chunk from a streaming answer response.
 type AnswerStreamChunk struct {
 	Type        string         `json:"type,omitempty"`
@@ -187,24 +219,8 @@ func (r *ContextResponse) GetCost() *CostInfo {
 	if r.ParsedCost != nil {
 		return r.ParsedCost
 	}
-	if len(r.CostDollars) == 0 {
-		return nil
-	}
-	var cost CostInfo
-	// Try direct object first
-	if err := json.Unmarshal(r.CostDollars, &cost); err == nil {
-		r.ParsedCost = &cost
-		return r.ParsedCost
-	}
-	// Try string-encoded JSON
-	var s string
-	if err := json.Unmarshal(r.CostDollars, &s); err == nil {
-		if err := json.Unmarshal([]byte(s), &cost); err == nil {
-			r.ParsedCost = &cost
-			return r.ParsedCost
-		}
-	}
-	return nil
+	r.ParsedCost = ParseCost(r.CostDollars)
+	return r.ParsedCost
 }
 
 // UsageResponse is the response from GET /team-management/api-keys/{id}/usage
@@ -2,38 +2,208 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
+// ErrNoAPIKey is returned when no API key is configured anywhere.
+var ErrNoAPIKey error = missingKeyError{}
+
+// ErrProfileNotFound is returned when the requested profile does not exist.
+var ErrProfileNotFound error = missingProfileError{}
+
+type missingKeyError struct{}
+
+func (missingKeyError) Error() string { return "EXA_API_KEY not set and no config file found" }
+
+// AuthRequired marks the error for classification by package output.
+func (missingKeyError) AuthRequired() bool { return true }
+
+type missingProfileError struct{}
+
+func (missingProfileError) Error() string { return "profile not found" }
+
+// ProfileNotFound marks the error for classification by package output.
+func (missingProfileError) ProfileNotFound() bool { return true }
+
+// DefaultProfile is the profile used when none is selected.
+const DefaultProfile = "default"
+
+// DefaultBaseURL is the Exa API endpoint used when no override is set.
+const DefaultBaseURL = "https://api.exa.ai"
+
+// AuthConfig is the content of ~/.exa-auth.json.
 type AuthConfig struct {
-	APIKey string `json:"api_key"`
+	// APIKey is the legacy single key. It is migrated into the default
+	// profile on load.
+	APIKey   string             `json:"api_key,omitempty"`
+	Current  string             `json:"current,omitempty"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Profile is a named set of credentials and defaults.
+type Profile struct {
+	APIKey     string `json:"api_key"`
+	BaseURL    string `json:"base_url,omitempty"`
+	Output     string `json:"output,omitempty"`      // Default --format
+	SearchType string `json:"search_type,omitempty"` // Default search --type
+}
+
+// Endpoint returns the profile's base URL. A profile without one uses
+// EXA_API_URL, or the public endpoint if that is not set either.
+func (p Profile) Endpoint() string {
+	if p.BaseURL != "" {
+		return p.BaseURL
+	}
+	return envBaseURL()
+}
+
+// envBaseURL returns EXA_API_URL, or the public endpoint if it is not set.
+func envBaseURL() string {
+	if url := os.Getenv("EXA_API_URL"); url != "" {
+		return url
+	}
+	return DefaultBaseURL
+}
+
+// selectedProfile is the profile named by the --profile flag.
+var selectedProfile string
+
+// SetProfile selects a profile for this process, overriding EXA_PROFILE.
+func SetProfile(name string) {
+	selectedProfile = name
+}
+
+// requestedProfile returns the profile asked for by --profile or
+// EXA_PROFILE, or "" when neither is set.
+func requestedProfile() string {
+	if selectedProfile != "" {
+		return selectedProfile
+	}
+	return os.Getenv("EXA_PROFILE")
+}
+
+// ActiveName returns the profile in effect: --profile, EXA_PROFILE, the
+// file's current profile, or "default".
+func (c *AuthConfig) ActiveName() string {
+	if name := requestedProfile(); name != "" {
+		return name
+	}
+	if c.Current != "" {
+		return c.Current
+	}
+	return DefaultProfile
+}
+
+// Names returns the profile names in sorted order.
+func (c *AuthConfig) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// migrate moves a legacy single api_key into the default profile.
+func (c *AuthConfig) migrate() {
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]Profile)
+	}
+	if c.APIKey == "" {
+		return
+	}
+	if _, ok := c.Profiles[DefaultProfile]; !ok {
+		c.Profiles[DefaultProfile] = Profile{APIKey: c.APIKey}
+	}
+	if c.Current == "" {
+		c.Current = DefaultProfile
+	}
+	c.APIKey = ""
+}
+
+// Credential sources reported by Resolve.
+const (
+	SourceEnv     = "env"     // EXA_API_KEY
+	SourceProfile = "profile" // Profile chosen by --profile or EXA_PROFILE
+	SourceFile    = "file"    // Current profile in the config file
+)
+
+// Credentials is a resolved API key and where it came from.
+type Credentials struct {
+	APIKey  string
+	Source  string
+	Profile string // Empty for SourceEnv
 }
 
 // GetAPIKey returns the API key from env var or config file.
-// Priority: EXA_API_KEY env var → ~/.exa-auth.json
+// Priority: --profile/EXA_PROFILE → EXA_API_KEY env var → current profile
+// in ~/.exa-auth.json
 func GetAPIKey() (string, error) {
-	if key := os.Getenv("EXA_API_KEY"); key != "" {
-		return key, nil
+	creds, err := Resolve()
+	if err != nil {
+		return "", err
+	}
+	return creds.APIKey, nil
+}
+
+// Resolve finds the API key in effect, in GetAPIKey's priority order.
+func Resolve() (Credentials, error) {
+	requested := requestedProfile()
+	if requested == "" {
+		if key := os.Getenv("EXA_API_KEY"); key != "" {
+			return Credentials{APIKey: key, Source: SourceEnv}, nil
+		}
 	}
 
 	config, err := loadAuth()
 	if err != nil {
-		return "", fmt.Errorf("EXA_API_KEY not set and no config file found.\nRun 'exa auth' to configure or set EXA_API_KEY environment variable")
+		if requested != "" {
+			return Credentials{}, fmt.Errorf("%w: %q (no config file)", ErrProfileNotFound, requested)
+		}
+		return Credentials{}, fmt.Errorf("%w.\nRun 'exa auth' to configure or set EXA_API_KEY environment variable", ErrNoAPIKey)
+	}
+
+	name := config.ActiveName()
+	profile, ok := config.Profiles[name]
+	if !ok && requested != "" {
+		return Credentials{}, fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+	}
+	if profile.APIKey == "" {
+		return Credentials{}, fmt.Errorf("no valid authentication found: %w", ErrNoAPIKey)
 	}
-	if config.APIKey != "" {
-		return config.APIKey, nil
+	source := SourceFile
+	if requested != "" {
+		source = SourceProfile
+	}
+	return Credentials{APIKey: profile.APIKey, Source: source, Profile: name}, nil
+}
+
+// ActiveProfile returns the profile in effect and its name. A missing
+// config file yields an empty default profile.
+func ActiveProfile() (Profile, string, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return Profile{}, "", err
 	}
-	return "", fmt.Errorf("no valid authentication found")
+	name := config.ActiveName()
+	return config.Profiles[name], name, nil
 }
 
-// GetBaseURL returns the API base URL, with env var override.
+// GetBaseURL returns the API base URL for the key Resolve picks: the
+// profile's base_url for a profile key that has one, so the key is only
+// sent to its own endpoint, and otherwise EXA_API_URL or the public
+// endpoint.
 func GetBaseURL() string {
-	if url := os.Getenv("EXA_API_URL"); url != "" {
-		return url
+	creds, err := Resolve()
+	if err != nil || creds.Source == SourceEnv {
+		return envBaseURL()
 	}
-	return "https://api.exa.ai"
+	p, _, _ := ActiveProfile()
+	return p.Endpoint()
 }
 
 func ConfigPath() (string, error) {
@@ -44,6 +214,18 @@ func ConfigPath() (string, error) {
 	return filepath.Join(home, ".exa-auth.json"), nil
 }
 
+// LoadConfig reads the config file, returning an empty config if it does
+// not exist yet.
+func LoadConfig() (*AuthConfig, error) {
+	config, err := loadAuth()
+	if errors.Is(err, os.ErrNotExist) {
+		config = &AuthConfig{}
+		config.migrate()
+		return config, nil
+	}
+	return config, err
+}
+
 func loadAuth() (*AuthConfig, error) {
 	path, err := ConfigPath()
 	if err != nil {
@@ -54,7 +236,11 @@ func loadAuth() (*AuthConfig, error) {
 		return nil, err
 	}
 	var config AuthConfig
-	return &config, json.Unmarshal(data, &config)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	config.migrate()
+	return &config, nil
 }
 
 func SaveAuth(config AuthConfig) error {
@@ -62,9 +248,30 @@ func SaveAuth(config AuthConfig) error {
 	if err != nil {
 		return err
 	}
+	config.migrate()
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(path, data, 0600)
 }
+
+// RemoveConfig deletes the config file. A missing file is not an error.
+func RemoveConfig() error {
+	path, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// MaskKey shortens a key for display, keeping only its ends.
+func MaskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "…" + key[len(key)-4:]
+}
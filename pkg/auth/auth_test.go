@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupHome(t *testing.T, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("EXA_API_KEY", "")
+	t.Setenv("EXA_API_URL", "")
+	t.Setenv("EXA_PROFILE", "")
+	SetProfile("")
+	t.Cleanup(func() { SetProfile("") })
+	if content != "" {
+		if err := os.WriteFile(filepath.Join(home, ".exa-auth.json"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+func TestLegacyConfigMigrates(t *testing.T) {
+	setupHome(t, `{"api_key":"legacy"}`)
+
+	key, err := GetAPIKey()
+	if err != nil || key != "legacy" {
+		t.Fatalf("GetAPIKey = %q, %v", key, err)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Current != DefaultProfile || config.Profiles[DefaultProfile].APIKey != "legacy" || config.APIKey != "" {
+		t.Errorf("not migrated: %+v", config)
+	}
+	if err := SaveAuth(*config); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := GetAPIKey(); key != "legacy" {
+		t.Errorf("after save GetAPIKey = %q", key)
+	}
+}
+
+func TestProfileSelection(t *testing.T) {
+	setupHome(t, "")
+	err := SaveAuth(AuthConfig{
+		Current: "personal",
+		Profiles: map[string]Profile{
+			"personal": {APIKey: "p-key"},
+			"staging":  {APIKey: "s-key", BaseURL: "https://staging.example"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key, _ := GetAPIKey(); key != "p-key" {
+		t.Errorf("current profile key = %q", key)
+	}
+	if url := GetBaseURL(); url != DefaultBaseURL {
+		t.Errorf("current profile base URL = %q", url)
+	}
+
+	// EXA_API_KEY beats the current profile, but not an explicit one.
+	t.Setenv("EXA_API_KEY", "env-key")
+	if key, _ := GetAPIKey(); key != "env-key" {
+		t.Errorf("env key = %q", key)
+	}
+	t.Setenv("EXA_PROFILE", "staging")
+	if key, _ := GetAPIKey(); key != "s-key" {
+		t.Errorf("EXA_PROFILE key = %q", key)
+	}
+	if url := GetBaseURL(); url != "https://staging.example" {
+		t.Errorf("EXA_PROFILE base URL = %q", url)
+	}
+
+	SetProfile("missing")
+	if _, err := GetAPIKey(); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+func TestResolveSource(t *testing.T) {
+	setupHome(t, `{"current":"a","profiles":{"a":{"api_key":"a-key"},"b":{"api_key":"b-key"}}}`)
+
+	for _, tc := range []struct {
+		env, profile string
+		want         Credentials
+	}{
+		{"", "", Credentials{APIKey: "a-key", Source: SourceFile, Profile: "a"}},
+		{"env-key", "", Credentials{APIKey: "env-key", Source: SourceEnv}},
+		{"env-key", "b", Credentials{APIKey: "b-key", Source: SourceProfile, Profile: "b"}},
+	} {
+		t.Setenv("EXA_API_KEY", tc.env)
+		SetProfile(tc.profile)
+		got, err := Resolve()
+		if err != nil || got != tc.want {
+			t.Errorf("env=%q profile=%q: Resolve = %+v, %v; want %+v", tc.env, tc.profile, got, err, tc.want)
+		}
+	}
+}
+
+func TestBaseURLFollowsKeySource(t *testing.T) {
+	setupHome(t, `{"current":"dev","profiles":{"dev":{"api_key":"dev-key"},"prod":{"api_key":"prod-key","base_url":"https://prod.example"}}}`)
+	t.Setenv("EXA_API_KEY", "env-key")
+	t.Setenv("EXA_API_URL", "http://127.0.0.1:8787")
+
+	// The environment key goes to the environment URL.
+	if url := GetBaseURL(); url != "http://127.0.0.1:8787" {
+		t.Errorf("env key base URL = %q", url)
+	}
+	// A profile's own base_url beats EXA_API_URL.
+	SetProfile("prod")
+	if key, _ := GetAPIKey(); key != "prod-key" {
+		t.Fatalf("profile key = %q", key)
+	}
+	if url := GetBaseURL(); url != "https://prod.example" {
+		t.Errorf("profile base URL = %q", url)
+	}
+	// A profile without base_url follows EXA_API_URL.
+	SetProfile("dev")
+	if url := GetBaseURL(); url != "http://127.0.0.1:8787" {
+		t.Errorf("profile without base_url = %q, want EXA_API_URL", url)
+	}
+}
+
+func TestBaseURLFileKeyWithEnvURL(t *testing.T) {
+	setupHome(t, `{"api_key":"file-key"}`)
+	if url := GetBaseURL(); url != DefaultBaseURL {
+		t.Errorf("file key base URL = %q, want %q", url, DefaultBaseURL)
+	}
+	t.Setenv("EXA_API_URL", "http://localhost:8080")
+	if creds, err := Resolve(); err != nil || creds.Source != SourceFile {
+		t.Fatalf("Resolve = %+v, %v", creds, err)
+	}
+	if url := GetBaseURL(); url != "http://localhost:8080" {
+		t.Errorf("file key with EXA_API_URL: base URL = %q", url)
+	}
+}
+
+func TestNoConfig(t *testing.T) {
+	setupHome(t, "")
+	if _, err := GetAPIKey(); !errors.Is(err, ErrNoAPIKey) {
+		t.Errorf("expected ErrNoAPIKey, got %v", err)
+	}
+}
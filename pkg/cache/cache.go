@@ -0,0 +1,248 @@
+// Package cache implements a content-addressed on-disk cache for Exa API
+// responses, keyed on the backend, the credential, the endpoint and the
+// canonicalized request body.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache is a directory of cached responses. It implements api.ResponseCache.
+type Cache struct {
+	dir   string
+	ttl   time.Duration
+	now   func() time.Time
+	scope string // Backend and credential the entries belong to
+}
+
+// entry is the on-disk representation of a cached response.
+type entry struct {
+	Endpoint string          `json:"endpoint"`
+	Created  time.Time       `json:"created"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Stats summarizes the contents of a cache directory.
+type Stats struct {
+	Dir     string    `json:"dir"`
+	Entries int       `json:"entries"`
+	Expired int       `json:"expired"`
+	Bytes   int64     `json:"bytes"`
+	Oldest  time.Time `json:"oldest,omitempty"`
+	Newest  time.Time `json:"newest,omitempty"`
+}
+
+// DefaultDir returns the XDG cache directory for exa ($XDG_CACHE_HOME/exa).
+func DefaultDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "exa"), nil
+}
+
+// New returns a cache rooted at dir whose entries expire after ttl.
+// A ttl of zero means entries never expire.
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// Dir returns the cache directory.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Scoped returns a view of the cache for requests sent to baseURL with
+// apiKey, so responses from another backend, profile or key are never
+// served. The key itself is not stored, only a short hash of it.
+func (c *Cache) Scoped(baseURL, apiKey string) *Cache {
+	sum := sha256.Sum256([]byte(apiKey))
+	s := *c
+	s.scope = strings.TrimRight(baseURL, "/") + " " + hex.EncodeToString(sum[:8])
+	return &s
+}
+
+// Key returns the cache key for a request: the SHA-256 of the scope (see
+// Scoped), the endpoint and the request JSON with object keys sorted.
+func Key(scope, endpoint string, body []byte) (string, error) {
+	canonical, err := canonicalize(body)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(scope))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(strings.TrimLeft(endpoint, "/")))
+	h.Write([]byte{'\n'})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func canonicalize(body []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("canonicalize request: %w", err)
+	}
+	// encoding/json writes map keys in sorted order.
+	return json.Marshal(v)
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get returns the cached response for a request, if present and fresh.
+func (c *Cache) Get(endpoint string, body []byte) ([]byte, bool) {
+	key, err := Key(c.scope, endpoint, body)
+	if err != nil {
+		return nil, false
+	}
+	e, err := readEntry(c.path(key))
+	if err != nil || c.expired(e) {
+		return nil, false
+	}
+	return e.Response, true
+}
+
+// Put stores a response for a request.
+func (c *Cache) Put(endpoint string, body, resp []byte) error {
+	key, err := Key(c.scope, endpoint, body)
+	if err != nil {
+		return err
+	}
+	e := entry{
+		Endpoint: endpoint,
+		Created:  c.now().UTC(),
+		Request:  json.RawMessage(body),
+		Response: json.RawMessage(resp),
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Write atomically so concurrent readers never see a partial entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Stats walks the cache directory and reports its size.
+func (c *Cache) Stats() (Stats, error) {
+	st := Stats{Dir: c.dir}
+	err := c.walk(func(path string, e *entry, size int64) error {
+		st.Entries++
+		st.Bytes += size
+		if c.expired(e) {
+			st.Expired++
+		}
+		if st.Oldest.IsZero() || e.Created.Before(st.Oldest) {
+			st.Oldest = e.Created
+		}
+		if e.Created.After(st.Newest) {
+			st.Newest = e.Created
+		}
+		return nil
+	})
+	return st, err
+}
+
+// Prune removes expired entries and returns how many were removed.
+func (c *Cache) Prune() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, e *entry, size int64) error {
+		if !c.expired(e) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// Clear removes every entry and returns how many were removed.
+func (c *Cache) Clear() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, e *entry, size int64) error {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+func (c *Cache) expired(e *entry) bool {
+	return c.ttl > 0 && c.now().Sub(e.Created) > c.ttl
+}
+
+// walk calls fn for every readable entry. Unreadable files are skipped.
+func (c *Cache) walk(fn func(path string, e *entry, size int64) error) error {
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		e, err := readEntry(path)
+		if err != nil {
+			return nil
+		}
+		return fn(path, e, info.Size())
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func readEntry(path string) (*entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
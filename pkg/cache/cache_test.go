@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKey_CanonicalizesJSON(t *testing.T) {
+	a, err := Key("", "/search", []byte(`{"query":"go","numResults":5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Key("", "search", []byte(`{ "numResults": 5, "query": "go" }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("equivalent requests produced different keys: %s vs %s", a, b)
+	}
+
+	c, _ := Key("", "/contents", []byte(`{"query":"go","numResults":5}`))
+	if a == c {
+		t.Error("different endpoints produced the same key")
+	}
+}
+
+func TestCache_ScopedByBackendAndKey(t *testing.T) {
+	c := New(t.TempDir(), 0)
+	prod := c.Scoped("https://api.exa.ai", "prod-key")
+	staging := c.Scoped("https://staging.example.com", "prod-key")
+	other := c.Scoped("https://api.exa.ai/", "other-key")
+
+	req := []byte(`{"query":"q"}`)
+	if err := prod.Put("/search", req, []byte(`{"results":[]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := staging.Get("/search", req); ok {
+		t.Error("another base URL hit the entry")
+	}
+	if _, ok := other.Get("/search", req); ok {
+		t.Error("another API key hit the entry")
+	}
+	if _, ok := c.Scoped("https://api.exa.ai/", "prod-key").Get("/search", req); !ok {
+		t.Error("same backend and key missed")
+	}
+}
+
+func TestCache_GetPutExpiry(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := New(t.TempDir(), time.Hour)
+	c.now = func() time.Time { return now }
+
+	req := []byte(`{"query":"q"}`)
+	if _, ok := c.Get("/search", req); ok {
+		t.Fatal("unexpected hit on empty cache")
+	}
+	if err := c.Put("/search", req, []byte(`{"results":[]}`)); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.Get("/search", req)
+	if !ok || string(got) != `{"results":[]}` {
+		t.Fatalf("Get = %q, %v", got, ok)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.Get("/search", req); ok {
+		t.Error("expected expired entry to miss")
+	}
+
+	st, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Entries != 1 || st.Expired != 1 {
+		t.Errorf("Stats = %+v, want 1 entry, 1 expired", st)
+	}
+
+	n, err := c.Prune()
+	if err != nil || n != 1 {
+		t.Errorf("Prune = %d, %v; want 1, nil", n, err)
+	}
+	if st, _ := c.Stats(); st.Entries != 0 {
+		t.Errorf("entries after prune = %d", st.Entries)
+	}
+}
+
+func TestCache_ClearMissingDir(t *testing.T) {
+	c := New(t.TempDir()+"/missing", 0)
+	if n, err := c.Clear(); err != nil || n != 0 {
+		t.Errorf("Clear on missing dir = %d, %v", n, err)
+	}
+}
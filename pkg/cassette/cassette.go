@@ -0,0 +1,286 @@
+// Package cassette records HTTP interactions to a JSON file and replays them
+// later, so CLI runs and tests can be reproduced without network access.
+//
+// Recorder and Replayer are http.RoundTrippers; install them with
+// api.Client.SetTransport. Credentials are scrubbed before anything is
+// written to disk.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+// ErrNoInteraction is returned by a Replayer for a request the cassette did
+// not record. It is wrapped in an api.PermanentError so it is not retried.
+var ErrNoInteraction = errors.New("cassette: no recorded interaction")
+
+// Redacted replaces sensitive header values in recorded cassettes.
+const Redacted = "[REDACTED]"
+
+// sensitiveHeaders are scrubbed from recorded requests and responses.
+var sensitiveHeaders = map[string]bool{
+	"x-api-key":     true,
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+}
+
+// Cassette is an ordered list of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request. URL holds only the path and query so
+// cassettes replay against any base URL.
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response. Streaming (SSE) bodies are stored
+// verbatim.
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path with mode 0600.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// Recorder forwards requests to an underlying transport and appends every
+// interaction to a cassette file. An existing cassette is extended, so
+// several processes can record into the same file one after another.
+type Recorder struct {
+	path string
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder returns a Recorder writing to path. If next is nil,
+// http.DefaultTransport is used.
+func NewRecorder(path string, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	c, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c, err = &Cassette{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{path: path, next: next, cassette: c}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	in := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.RequestURI(),
+			Headers: scrub(req.Header),
+			Body:    string(reqBody),
+		},
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: scrub(resp.Header),
+		},
+	}
+
+	// Tee the body so streaming responses still reach the caller
+	// incrementally; the interaction is saved once the body is consumed.
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		done: func(body []byte) {
+			in.Response.Body = string(body)
+			r.add(in)
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) add(in Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	if err := r.cassette.Save(r.path); err != nil {
+		fmt.Fprintf(os.Stderr, "cassette: save %s: %v\n", r.path, err)
+	}
+}
+
+// recordingBody copies everything read into a buffer and reports it once,
+// at EOF or Close, whichever comes first.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	// Drain what the caller did not read so the recording is complete.
+	_, _ = io.Copy(&b.buf, b.ReadCloser)
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() { b.done(b.buf.Bytes()) })
+}
+
+// Replayer serves responses from a cassette without touching the network.
+// Each recorded interaction is used at most once, in order.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer loads the cassette at path.
+func NewReplayer(path string) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{cassette: c, used: make([]bool, len(c.Interactions))}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	uri := req.URL.RequestURI()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || in.Request.Method != req.Method || in.Request.URL != uri {
+			continue
+		}
+		if !sameBody(in.Request.Body, string(body)) {
+			continue
+		}
+		r.used[i] = true
+
+		header := make(http.Header)
+		for k, v := range in.Response.Headers {
+			header.Set(k, v)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, &api.PermanentError{Err: fmt.Errorf("%w for %s %s", ErrNoInteraction, req.Method, uri)}
+}
+
+// readBody reads and restores a request body.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	if err != nil {
+		return nil, err
+	}
+	_ = (*body).Close()
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// sameBody compares request bodies as JSON when possible, so key order
+// and whitespace do not matter.
+func sameBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func scrub(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if sensitiveHeaders[strings.ToLower(k)] {
+			out[k] = Redacted
+			continue
+		}
+		out[k] = strings.Join(v, ", ")
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func fakeAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"requestId":"r1","results":[{"title":"Go","url":"https://go.dev","id":"go"}]}`)
+		case "/answer":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"text\":\"Par\"}\n\n")
+			fmt.Fprint(w, "data: {\"text\":\"is\"}\n\n")
+			fmt.Fprint(w, "data: {\"citations\":[{\"title\":\"Paris\",\"url\":\"https://fr.wikipedia.org\"}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	srv := fakeAPI(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	rec, err := NewRecorder(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := api.NewClient(srv.URL, "secret-key")
+	c.SetTransport(rec)
+
+	if _, err := c.Search(context.Background(), &api.SearchRequest{Query: "golang"}); err != nil {
+		t.Fatalf("record search: %v", err)
+	}
+	var streamed strings.Builder
+	if err := c.AnswerStream(context.Background(), &api.AnswerRequest{Query: "capital of France"},
+		func(s string) { streamed.WriteString(s) }, nil); err != nil {
+		t.Fatalf("record answer: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "secret-key") {
+		t.Error("cassette contains the API key")
+	}
+	if !strings.Contains(string(raw), Redacted) {
+		t.Error("cassette missing redaction marker")
+	}
+
+	// Replay against a dead server: everything must come from the file.
+	srv.Close()
+	rep, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = api.NewClient("http://127.0.0.1:1", "other-key")
+	c.SetTransport(rep)
+
+	resp, err := c.Search(context.Background(), &api.SearchRequest{Query: "golang"})
+	if err != nil {
+		t.Fatalf("replay search: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].URL != "https://go.dev" {
+		t.Errorf("unexpected replayed results: %+v", resp.Results)
+	}
+
+	var replayed strings.Builder
+	var citations int
+	err = c.AnswerStream(context.Background(), &api.AnswerRequest{Query: "capital of France"},
+		func(s string) { replayed.WriteString(s) },
+		func(r *api.AnswerResponse) { citations = len(r.Citations) })
+	if err != nil {
+		t.Fatalf("replay answer: %v", err)
+	}
+	if replayed.String() != "Paris" || replayed.String() != streamed.String() {
+		t.Errorf("replayed stream %q, recorded %q", replayed.String(), streamed.String())
+	}
+	if citations != 1 {
+		t.Errorf("replayed %d citations, want 1", citations)
+	}
+
+	// A miss fails at once under the default retry policy.
+	start := time.Now()
+	if _, err := c.Search(context.Background(), &api.SearchRequest{Query: "unrecorded"}); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("unrecorded request: %v, want ErrNoInteraction", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("replay miss took %s; it should not be retried", elapsed)
+	}
+}
+
+func TestSameBodyIgnoresKeyOrder(t *testing.T) {
+	if !sameBody(`{"a":1,"b":[1,2]}`, `{"b":[1,2], "a":1}`) {
+		t.Error("equivalent JSON bodies should match")
+	}
+	if sameBody(`{"a":1}`, `{"a":2}`) {
+		t.Error("different JSON bodies should not match")
+	}
+}
@@ -0,0 +1,208 @@
+// Package config reads and writes the user config file, by default
+// $XDG_CONFIG_HOME/exa/config.yaml.
+//
+// Top-level keys set global flags; a mapping named after a command sets that
+// command's flags:
+//
+//	no-color: true
+//	search:
+//	  type: deep
+//	  num-results: 10
+//	  exclude-domains: [pinterest.com]
+//
+// Keys are addressed with dots, e.g. "search.num-results".
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// File is a loaded config file.
+type File struct {
+	path   string
+	values map[string]interface{}
+}
+
+// Path returns the config file location: $EXA_CONFIG if set, otherwise
+// exa/config.yaml in the user config directory.
+func Path() (string, error) {
+	if p := os.Getenv("EXA_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "exa", "config.yaml"), nil
+}
+
+// DataDir returns the directory for local data such as the spend ledger
+// and history: $XDG_DATA_HOME/exa, falling back to ~/.local/share/exa.
+func DataDir() (string, error) {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, "exa"), nil
+}
+
+// Load reads the config file at path. A missing file yields an empty config.
+func Load(path string) (*File, error) {
+	f := &File{path: path, values: make(map[string]interface{})}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &f.values); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if f.values == nil {
+		f.values = make(map[string]interface{})
+	}
+	return f, nil
+}
+
+// Path returns the file's location.
+func (f *File) Path() string { return f.path }
+
+// Get returns the value at a dotted key.
+func (f *File) Get(key string) (interface{}, bool) {
+	var v interface{} = f.values
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	if _, isSection := v.(map[string]interface{}); isSection {
+		return nil, false
+	}
+	return v, true
+}
+
+// Set stores value at a dotted key, creating sections as needed.
+func (f *File) Set(key string, value interface{}) error {
+	parts := strings.Split(key, ".")
+	m := f.values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part]
+		if !ok {
+			child := make(map[string]interface{})
+			m[part] = child
+			m = child
+			continue
+		}
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %q is a value, not a section", key, part)
+		}
+		m = child
+	}
+	m[parts[len(parts)-1]] = value
+	return nil
+}
+
+// Unset removes a dotted key and reports whether it was present. Sections
+// left empty are removed too.
+func (f *File) Unset(key string) bool {
+	return unset(f.values, strings.Split(key, "."))
+}
+
+func unset(m map[string]interface{}, parts []string) bool {
+	if len(parts) == 1 {
+		_, ok := m[parts[0]]
+		delete(m, parts[0])
+		return ok
+	}
+	child, ok := m[parts[0]].(map[string]interface{})
+	if !ok || !unset(child, parts[1:]) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(m, parts[0])
+	}
+	return true
+}
+
+// Keys returns every dotted key that holds a value, sorted.
+func (f *File) Keys() []string {
+	var keys []string
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if child, ok := v.(map[string]interface{}); ok {
+				walk(prefix+k+".", child)
+				continue
+			}
+			keys = append(keys, prefix+k)
+		}
+	}
+	walk("", f.values)
+	sort.Strings(keys)
+	return keys
+}
+
+// Save writes the config back to its path.
+func (f *File) Save() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(f.values)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, data, 0o644)
+}
+
+// FormatValue renders a config value as a flag value: lists are joined with
+// commas.
+func FormatValue(v interface{}) string {
+	if list, ok := v.([]interface{}); ok {
+		parts := make([]string, len(list))
+		for i, e := range list {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// starter is written by WriteTemplate for a new config file.
+const starter = `# exa config: defaults for command-line flags.
+# Top-level keys set global flags; a section named after a command sets
+# that command's flags. Flags given on the command line always win.
+#
+# format: table
+# no-color: false
+# search:
+#   type: auto
+#   num-results: 10
+#   exclude-domains: [pinterest.com]
+# answer:
+#   stream: true
+`
+
+// WriteTemplate creates a commented starter config file at path.
+func WriteTemplate(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(starter), 0o644)
+}
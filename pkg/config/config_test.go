@@ -0,0 +1,66 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSetGetSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exa", "config.yaml")
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("load missing file: %v", err)
+	}
+
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(f.Set("no-color", true))
+	must(f.Set("search.num-results", 10))
+	must(f.Set("search.exclude-domains", []interface{}{"pinterest.com", "quora.com"}))
+	must(f.Save())
+
+	f, err = Load(path)
+	must(err)
+	if got, want := f.Keys(), []string{"no-color", "search.exclude-domains", "search.num-results"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+	if v, ok := f.Get("search.num-results"); !ok || FormatValue(v) != "10" {
+		t.Errorf("search.num-results = %v, %v", v, ok)
+	}
+	if v, _ := f.Get("search.exclude-domains"); FormatValue(v) != "pinterest.com,quora.com" {
+		t.Errorf("search.exclude-domains = %v", v)
+	}
+	if _, ok := f.Get("search"); ok {
+		t.Error("a section should not be returned as a value")
+	}
+	if err := f.Set("no-color.x", 1); err == nil {
+		t.Error("expected error setting a key below a value")
+	}
+
+	if !f.Unset("search.num-results") || !f.Unset("search.exclude-domains") {
+		t.Fatal("Unset reported missing keys")
+	}
+	if got := f.Keys(); !reflect.DeepEqual(got, []string{"no-color"}) {
+		t.Errorf("after unset Keys = %v (empty sections should be dropped)", got)
+	}
+	if f.Unset("search.type") {
+		t.Error("Unset of a missing key should report false")
+	}
+}
+
+func TestPathHonorsEnv(t *testing.T) {
+	t.Setenv("EXA_CONFIG", "/tmp/custom.yaml")
+	if p, _ := Path(); p != "/tmp/custom.yaml" {
+		t.Errorf("Path = %q", p)
+	}
+	t.Setenv("EXA_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	if p, _ := Path(); p != "/tmp/xdg/exa/config.yaml" {
+		t.Errorf("Path = %q", p)
+	}
+}
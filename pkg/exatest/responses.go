@@ -0,0 +1,295 @@
+package exatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+// Default canned data. Everything is derived from the request so repeated
+// calls return identical responses.
+
+const defaultNumResults = 10
+
+var fallbackDomains = []string{"example.com", "example.org", "example.net", "test.example"}
+
+func (s *Server) search(req *api.SearchRequest) *api.SearchResponse {
+	s.mu.Lock()
+	fn := s.onSearch
+	s.mu.Unlock()
+	if fn != nil {
+		return fn(req)
+	}
+
+	results := makeResults(req.Query, req.NumResults, req.IncludeDomains, req.ExcludeDomains, req.StartPublishedDate, req.Contents)
+	resolved := req.Type
+	if resolved == "" || resolved == "auto" {
+		resolved = "neural"
+	}
+	return &api.SearchResponse{
+		RequestID:          requestID("search", req.Query),
+		ResolvedSearchType: resolved,
+		Results:            results,
+		CostDollars:        searchCost(len(results), req.Type, req.Contents),
+	}
+}
+
+func (s *Server) findSimilar(req *api.FindSimilarRequest) *api.FindSimilarResponse {
+	s.mu.Lock()
+	fn := s.onSimilar
+	s.mu.Unlock()
+	if fn != nil {
+		return fn(req)
+	}
+
+	exclude := req.ExcludeDomains
+	if req.ExcludeSourceDomain {
+		exclude = append(append([]string(nil), exclude...), hostOf(req.URL))
+	}
+	results := makeResults("similar to "+req.URL, req.NumResults, req.IncludeDomains, exclude, req.StartPublishedDate, req.Contents)
+	return &api.FindSimilarResponse{
+		RequestID:   requestID("similar", req.URL),
+		Results:     results,
+		CostDollars: searchCost(len(results), "", req.Contents),
+	}
+}
+
+func (s *Server) contents(req *api.ContentsRequest) *api.ContentsResponse {
+	s.mu.Lock()
+	fn := s.onContents
+	s.mu.Unlock()
+	if fn != nil {
+		return fn(req)
+	}
+
+	ids := append(append([]string(nil), req.URLs...), req.IDs...)
+	resp := &api.ContentsResponse{}
+	perPage := 0.0
+	for _, u := range ids {
+		r := api.SearchResult{
+			Title:         "Page " + u,
+			URL:           u,
+			ID:            u,
+			PublishedDate: "2025-01-15T00:00:00.000Z",
+		}
+		if req.Text != nil {
+			r.Text = limit(fmt.Sprintf("Fake contents of %s. This page is served by exatest and contains deterministic text for testing.", u), req.Text.MaxCharacters)
+		}
+		if req.Highlights != nil {
+			r.Highlights = []string{"Highlight from " + u}
+			r.HighlightScores = []float64{0.9}
+		}
+		if req.Summary != nil {
+			r.Summary = "Summary of " + u
+		}
+		resp.Results = append(resp.Results, r)
+	}
+	for _, on := range []bool{req.Text != nil, req.Highlights != nil, req.Summary != nil} {
+		if on {
+			perPage += 0.001
+		}
+	}
+	resp.CostDollars = &api.CostInfo{Total: round(perPage * float64(len(ids)))}
+	return resp
+}
+
+func (s *Server) answer(req *api.AnswerRequest) *api.AnswerResponse {
+	s.mu.Lock()
+	fn := s.onAnswer
+	s.mu.Unlock()
+	if fn != nil {
+		return fn(req)
+	}
+
+	spec := &api.ContentsSpec{}
+	if req.Text {
+		spec.Text = &api.TextSpec{}
+	}
+	return &api.AnswerResponse{
+		RequestID:   requestID("answer", req.Query),
+		Answer:      "Fake answer to: " + req.Query,
+		Citations:   makeResults(req.Query, 2, nil, nil, "", spec),
+		CostDollars: &api.CostInfo{Total: 0.005},
+	}
+}
+
+func (s *Server) context(req *api.ContextRequest) *api.ContextResponse {
+	s.mu.Lock()
+	fn := s.onContext
+	s.mu.Unlock()
+	if fn != nil {
+		return fn(req)
+	}
+
+	text := fmt.Sprintf("## %s\n\n```go\n// Fake code context served by exatest.\nfunc Example() string {\n\treturn %q\n}\n```\n", req.Query, req.Query)
+	cost, _ := json.Marshal(api.CostInfo{Total: 0.015})
+	return &api.ContextResponse{
+		RequestID:    requestID("context", req.Query),
+		Query:        req.Query,
+		Context:      text,
+		CostDollars:  cost,
+		ResultsCount: 1,
+		OutputTokens: len(strings.Fields(text)),
+	}
+}
+
+func (s *Server) usageFor(keyID, startDate string) *api.UsageResponse {
+	s.mu.Lock()
+	entries, ok := s.usage[keyID]
+	s.mu.Unlock()
+	if ok {
+		return &api.UsageResponse{Usage: entries}
+	}
+
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	resp := &api.UsageResponse{}
+	for i := 0; i < 3; i++ {
+		resp.Usage = append(resp.Usage, api.UsageEntry{
+			Date:         start.AddDate(0, 0, i).Format("2006-01-02"),
+			RequestCount: 10 * (i + 1),
+			CreditUsage:  0.05 * float64(i+1),
+		})
+	}
+	return resp
+}
+
+// makeResults builds n results for query, honoring domain filters and the
+// requested contents.
+func makeResults(query string, n int, include, exclude []string, startDate string, spec *api.ContentsSpec) []api.SearchResult {
+	if n <= 0 {
+		n = defaultNumResults
+	}
+	if n > 100 {
+		n = 100
+	}
+
+	domains := include
+	if len(domains) == 0 {
+		for _, d := range fallbackDomains {
+			if !containsDomain(exclude, d) {
+				domains = append(domains, d)
+			}
+		}
+	}
+	if len(domains) == 0 {
+		return []api.SearchResult{}
+	}
+
+	date := "2025-01-15T00:00:00.000Z"
+	if startDate != "" {
+		date = startDate
+	}
+	slug := slugify(query)
+
+	results := make([]api.SearchResult, 0, n)
+	for i := 0; i < n; i++ {
+		u := fmt.Sprintf("https://%s/%s/%d", domains[i%len(domains)], slug, i+1)
+		r := api.SearchResult{
+			Title:         fmt.Sprintf("Result %d for %s", i+1, query),
+			URL:           u,
+			ID:            u,
+			PublishedDate: date,
+			Author:        "exatest",
+			Score:         round(0.99 - 0.01*float64(i)),
+		}
+		if spec != nil {
+			if spec.Text != nil {
+				r.Text = limit(fmt.Sprintf("Text of result %d about %s.", i+1, query), spec.Text.MaxCharacters)
+			}
+			if spec.Highlights != nil {
+				r.Highlights = []string{fmt.Sprintf("Highlight %d about %s", i+1, query)}
+				r.HighlightScores = []float64{0.8}
+			}
+			if spec.Summary != nil {
+				r.Summary = fmt.Sprintf("Summary of result %d about %s", i+1, query)
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// searchCost mirrors Exa's published pricing closely enough for tests.
+func searchCost(n int, searchType string, spec *api.ContentsSpec) *api.CostInfo {
+	base := 0.005
+	switch {
+	case searchType == "deep":
+		base = 0.015
+	case n > 25:
+		base = 0.025
+	}
+	contents := 0.0
+	if spec != nil {
+		for _, on := range []bool{spec.Text != nil, spec.Highlights != nil, spec.Summary != nil} {
+			if on {
+				contents += 0.001 * float64(n)
+			}
+		}
+	}
+	cost := &api.CostInfo{
+		Total:  round(base + contents),
+		Search: &api.CostBreakdown{Amount: base},
+	}
+	if contents > 0 {
+		cost.Contents = &api.CostBreakdown{Amount: round(contents)}
+	}
+	return cost
+}
+
+func containsDomain(domains []string, d string) bool {
+	for _, x := range domains {
+		if strings.EqualFold(strings.TrimPrefix(x, "www."), d) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostOf(u string) string {
+	u = strings.TrimPrefix(strings.TrimPrefix(u, "https://"), "http://")
+	if i := strings.IndexAny(u, "/?#"); i >= 0 {
+		u = u[:i]
+	}
+	return strings.TrimPrefix(u, "www.")
+}
+
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	out := strings.TrimSuffix(b.String(), "-")
+	if out == "" {
+		return "q"
+	}
+	return out
+}
+
+func requestID(kind, key string) string {
+	return "exatest-" + kind + "-" + slugify(key)
+}
+
+func limit(s string, max int) string {
+	if max > 0 && len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+func round(f float64) float64 {
+	return float64(int64(f*10000+0.5)) / 10000
+}
@@ -0,0 +1,355 @@
+// Package exatest provides an in-process fake of the Exa API for tests.
+//
+// A Server answers /search, /contents, /findSimilar, /answer (JSON and SSE
+// streaming), /context and the team-management endpoints with deterministic
+// canned data derived from each request. Responses can be scripted per
+// endpoint, faults (latency, error statuses, malformed JSON) can be queued,
+// and every request is recorded for later assertions.
+//
+//	srv := exatest.NewServer(exatest.WithAPIKey("test-key"))
+//	defer srv.Close()
+//	client := api.NewClient(srv.URL, "test-key")
+package exatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+// Endpoint paths served by the fake.
+const (
+	PathSearch      = "/search"
+	PathContents    = "/contents"
+	PathFindSimilar = "/findSimilar"
+	PathAnswer      = "/answer"
+	PathContext     = "/context"
+	PathAPIKeys     = "/team-management/api-keys"
+)
+
+// Request is a request received by the fake server.
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// Decode unmarshals the request body into v.
+func (r Request) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Fault describes a failure injected into a single response.
+type Fault struct {
+	Latency    time.Duration // Delay before responding
+	Status     int           // Respond with this HTTP status and an error body
+	RetryAfter string        // Retry-After header sent with Status
+	Malformed  bool          // Respond 200 with a body that is not valid JSON
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAPIKey makes the server reject requests whose x-api-key header does
+// not match key with 401, like the real API.
+func WithAPIKey(key string) Option {
+	return func(s *Server) { s.apiKey = key }
+}
+
+// WithLatency delays every response by d.
+func WithLatency(d time.Duration) Option {
+	return func(s *Server) { s.latency = d }
+}
+
+// Server is a fake Exa API. The embedded httptest.Server provides URL and
+// Close.
+type Server struct {
+	*httptest.Server
+
+	apiKey  string
+	latency time.Duration
+
+	mu         sync.Mutex
+	requests   []Request
+	faults     map[string][]Fault
+	onSearch   func(*api.SearchRequest) *api.SearchResponse
+	onContents func(*api.ContentsRequest) *api.ContentsResponse
+	onSimilar  func(*api.FindSimilarRequest) *api.FindSimilarResponse
+	onAnswer   func(*api.AnswerRequest) *api.AnswerResponse
+	onContext  func(*api.ContextRequest) *api.ContextResponse
+	apiKeys    []api.APIKeyInfo
+	usage      map[string][]api.UsageEntry
+}
+
+// NewServer starts a fake Exa API server. Call Close when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		faults:  make(map[string][]Fault),
+		apiKeys: []api.APIKeyInfo{{ID: "key_test", Name: "exatest"}},
+		usage:   make(map[string][]api.UsageEntry),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// OnSearch scripts the /search response. A nil fn restores the default.
+func (s *Server) OnSearch(fn func(*api.SearchRequest) *api.SearchResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSearch = fn
+}
+
+// OnContents scripts the /contents response.
+func (s *Server) OnContents(fn func(*api.ContentsRequest) *api.ContentsResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onContents = fn
+}
+
+// OnFindSimilar scripts the /findSimilar response.
+func (s *Server) OnFindSimilar(fn func(*api.FindSimilarRequest) *api.FindSimilarResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSimilar = fn
+}
+
+// OnAnswer scripts the /answer response. Streaming requests receive the
+// same answer split into SSE text chunks followed by the citations.
+func (s *Server) OnAnswer(fn func(*api.AnswerRequest) *api.AnswerResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onAnswer = fn
+}
+
+// OnContext scripts the /context response.
+func (s *Server) OnContext(fn func(*api.ContextRequest) *api.ContextResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onContext = fn
+}
+
+// SetAPIKeys sets the keys returned by the team-management endpoint.
+func (s *Server) SetAPIKeys(keys []api.APIKeyInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKeys = keys
+}
+
+// SetUsage sets the usage entries returned for keyID.
+func (s *Server) SetUsage(keyID string, entries []api.UsageEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage[keyID] = entries
+}
+
+// InjectFault queues faults for path; each request to path consumes one.
+func (s *Server) InjectFault(path string, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = append(s.faults[path], faults...)
+}
+
+// Requests returns every request received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestsTo returns the requests received for path.
+func (s *Server) RequestsTo(path string) []Request {
+	var out []Request
+	for _, r := range s.Requests() {
+		if r.Path == path {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// LastRequest returns the most recent request to path.
+func (s *Server) LastRequest(path string) (Request, bool) {
+	reqs := s.RequestsTo(path)
+	if len(reqs) == 0 {
+		return Request{}, false
+	}
+	return reqs[len(reqs)-1], true
+}
+
+// AssertRequestCount fails t unless exactly n requests were made to path.
+func (s *Server) AssertRequestCount(t testing.TB, path string, n int) {
+	t.Helper()
+	if got := len(s.RequestsTo(path)); got != n {
+		t.Errorf("exatest: %d requests to %s, want %d", got, path, n)
+	}
+}
+
+// Reset clears recorded requests, queued faults and scripted responses.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.faults = make(map[string][]Fault)
+	s.onSearch, s.onContents, s.onSimilar, s.onAnswer, s.onContext = nil, nil, nil, nil, nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	var fault *Fault
+	if q := s.faults[req.Path]; len(q) > 0 {
+		fault = &q[0]
+		s.faults[req.Path] = q[1:]
+	}
+	s.mu.Unlock()
+
+	if s.latency > 0 {
+		time.Sleep(s.latency)
+	}
+	if fault != nil {
+		if fault.Latency > 0 {
+			time.Sleep(fault.Latency)
+		}
+		if fault.Status != 0 {
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			writeError(w, fault.Status, "INJECTED_FAULT", http.StatusText(fault.Status))
+			return
+		}
+		if fault.Malformed {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"results": [ this is not json`)
+			return
+		}
+	}
+
+	if s.apiKey != "" && r.Header.Get("x-api-key") != s.apiKey {
+		writeError(w, http.StatusUnauthorized, "INVALID_API_KEY", "Invalid API key")
+		return
+	}
+
+	switch {
+	case req.Path == PathSearch && r.Method == http.MethodPost:
+		var in api.SearchRequest
+		if !decode(w, req, &in) {
+			return
+		}
+		if strings.TrimSpace(in.Query) == "" {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "query is required")
+			return
+		}
+		writeJSON(w, s.search(&in))
+	case req.Path == PathContents && r.Method == http.MethodPost:
+		var in api.ContentsRequest
+		if !decode(w, req, &in) {
+			return
+		}
+		writeJSON(w, s.contents(&in))
+	case req.Path == PathFindSimilar && r.Method == http.MethodPost:
+		var in api.FindSimilarRequest
+		if !decode(w, req, &in) {
+			return
+		}
+		writeJSON(w, s.findSimilar(&in))
+	case req.Path == PathAnswer && r.Method == http.MethodPost:
+		var in api.AnswerRequest
+		if !decode(w, req, &in) {
+			return
+		}
+		resp := s.answer(&in)
+		if in.StreamOutput {
+			writeStream(w, resp)
+			return
+		}
+		writeJSON(w, resp)
+	case req.Path == PathContext && r.Method == http.MethodPost:
+		var in api.ContextRequest
+		if !decode(w, req, &in) {
+			return
+		}
+		writeJSON(w, s.context(&in))
+	case req.Path == PathAPIKeys && r.Method == http.MethodGet:
+		s.mu.Lock()
+		keys := append([]api.APIKeyInfo(nil), s.apiKeys...)
+		s.mu.Unlock()
+		writeJSON(w, &api.APIKeysResponse{APIKeys: keys})
+	case strings.HasPrefix(req.Path, PathAPIKeys+"/") && strings.HasSuffix(req.Path, "/usage") && r.Method == http.MethodGet:
+		keyID := strings.TrimSuffix(strings.TrimPrefix(req.Path, PathAPIKeys+"/"), "/usage")
+		writeJSON(w, s.usageFor(keyID, r.URL.Query().Get("startDate")))
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "no such endpoint: "+r.Method+" "+req.Path)
+	}
+}
+
+func decode(w http.ResponseWriter, req Request, v interface{}) bool {
+	if err := req.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, tag, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"requestId": "exatest-error",
+		"error":     msg,
+		"tag":       tag,
+	})
+}
+
+// writeStream sends an answer as server-sent events: one text chunk per
+// word, then a final chunk carrying citations and cost.
+func writeStream(w http.ResponseWriter, resp *api.AnswerResponse) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	send := func(v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	words := strings.SplitAfter(resp.Answer, " ")
+	for _, word := range words {
+		if word != "" {
+			send(api.AnswerStreamChunk{Text: word})
+		}
+	}
+	citations := resp.Citations
+	if citations == nil {
+		citations = []api.SearchResult{}
+	}
+	send(api.AnswerStreamChunk{Citations: citations, CostDollars: resp.CostDollars})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
@@ -0,0 +1,128 @@
+package exatest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func newClient(srv *Server, key string) *api.Client {
+	c := api.NewClient(srv.URL, key)
+	c.SetRetryPolicy(api.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})
+	return c
+}
+
+func TestServer_DefaultSearch(t *testing.T) {
+	srv := NewServer(WithAPIKey("k"))
+	defer srv.Close()
+
+	resp, err := newClient(srv, "k").Search(context.Background(), &api.SearchRequest{
+		Query:          "go testing",
+		NumResults:     3,
+		IncludeDomains: []string{"go.dev"},
+		Contents:       &api.ContentsSpec{Highlights: &api.HighlightsSpec{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(resp.Results))
+	}
+	for _, r := range resp.Results {
+		if !strings.HasPrefix(r.URL, "https://go.dev/") || len(r.Highlights) == 0 || r.Text != "" {
+			t.Errorf("unexpected result: %+v", r)
+		}
+	}
+	if resp.CostDollars == nil || resp.CostDollars.Total == 0 {
+		t.Error("missing cost")
+	}
+
+	var sent api.SearchRequest
+	last, ok := srv.LastRequest(PathSearch)
+	if !ok || last.Decode(&sent) != nil || sent.Query != "go testing" {
+		t.Errorf("request not recorded: %+v", last)
+	}
+	srv.AssertRequestCount(t, PathSearch, 1)
+}
+
+func TestServer_RejectsWrongKey(t *testing.T) {
+	srv := NewServer(WithAPIKey("k"))
+	defer srv.Close()
+
+	_, err := newClient(srv, "wrong").Search(context.Background(), &api.SearchRequest{Query: "q"})
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 401 {
+		t.Fatalf("expected 401, got %v", err)
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := newClient(srv, "any")
+
+	srv.InjectFault(PathSearch, Fault{Status: 429, RetryAfter: "0"}, Fault{Status: 503})
+	if _, err := c.Search(context.Background(), &api.SearchRequest{Query: "q"}); err != nil {
+		t.Fatalf("expected retries to recover, got %v", err)
+	}
+	srv.AssertRequestCount(t, PathSearch, 3)
+
+	srv.InjectFault(PathContents, Fault{Malformed: true})
+	if _, err := c.GetContents(context.Background(), &api.ContentsRequest{URLs: []string{"https://a.example"}}); !errors.Is(err, api.ErrDecode) {
+		t.Errorf("malformed fault: expected ErrDecode, got %v", err)
+	}
+
+	srv.InjectFault(PathContext, Fault{Latency: 20 * time.Millisecond})
+	start := time.Now()
+	if _, err := c.GetContext(context.Background(), &api.ContextRequest{Query: "q"}); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("latency fault not applied")
+	}
+}
+
+func TestServer_ScriptedStreamingAnswer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.OnAnswer(func(req *api.AnswerRequest) *api.AnswerResponse {
+		return &api.AnswerResponse{
+			Answer:    "The capital of France is Paris.",
+			Citations: []api.SearchResult{{Title: "Paris", URL: "https://en.wikipedia.org/wiki/Paris"}},
+		}
+	})
+
+	var text strings.Builder
+	var final *api.AnswerResponse
+	err := newClient(srv, "any").AnswerStream(context.Background(), &api.AnswerRequest{Query: "capital of France?"},
+		func(s string) { text.WriteString(s) },
+		func(r *api.AnswerResponse) { final = r })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "The capital of France is Paris." {
+		t.Errorf("streamed %q", text.String())
+	}
+	if final == nil || len(final.Citations) != 1 {
+		t.Errorf("final response = %+v", final)
+	}
+}
+
+func TestServer_TeamManagement(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := newClient(srv, "any")
+
+	keys, err := c.ListAPIKeys(context.Background())
+	if err != nil || len(keys.APIKeys) != 1 {
+		t.Fatalf("ListAPIKeys = %+v, %v", keys, err)
+	}
+	usage, err := c.GetUsage(context.Background(), keys.APIKeys[0].ID, "2025-01-01", "2025-01-31")
+	if err != nil || len(usage.Usage) == 0 || usage.Usage[0].Date != "2025-01-01" {
+		t.Fatalf("GetUsage = %+v, %v", usage, err)
+	}
+}
@@ -0,0 +1,204 @@
+// Package history keeps a local log of API calls, one JSON line per call,
+// so earlier requests can be listed, re-run and compared.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/config"
+)
+
+// ErrNotFound is returned when no entry has the requested ID.
+var ErrNotFound = errors.New("history entry not found")
+
+// Entry is one recorded API call.
+type Entry struct {
+	ID        int             `json:"id"` // Line number in the store, assigned on read
+	Time      time.Time       `json:"time"`
+	Command   string          `json:"command"`
+	Endpoint  string          `json:"endpoint"`
+	Request   json.RawMessage `json:"request,omitempty"`
+	RequestID string          `json:"requestId,omitempty"`
+	URLs      []string        `json:"urls"`
+	Cost      float64         `json:"cost"`
+	Cached    bool            `json:"cached,omitempty"`
+	Client    string          `json:"client,omitempty"` // Proxy or MCP client the call was made for
+}
+
+// SameRequest reports whether e and o sent the same request to the same
+// endpoint for the same client, ignoring key order.
+func (e Entry) SameRequest(o Entry) bool {
+	if e.Endpoint != o.Endpoint || e.Client != o.Client {
+		return false
+	}
+	var a, b interface{}
+	if json.Unmarshal(e.Request, &a) != nil || json.Unmarshal(o.Request, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Store is an append-only JSONL file of entries.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns history.jsonl in the exa data directory.
+func DefaultPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// Open returns the store at path. The file is created on the first Append.
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the store file location.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append records e. Its ID is ignored. It is safe for concurrent use.
+func (s *Store) Append(e Entry) error {
+	e.ID = 0
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// List returns every entry, oldest first. IDs are line numbers, so they
+// stay stable as entries are appended; unparseable lines are skipped.
+func (s *Store) List() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		e.ID = line
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", s.path, err)
+	}
+	return entries, nil
+}
+
+// Get returns the entry with the given ID.
+func (s *Store) Get(id int) (Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("%w: %d", ErrNotFound, id)
+}
+
+// Previous returns the latest entry before e that sent the same request.
+func (s *Store) Previous(e Entry) (Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID < e.ID && entries[i].SameRequest(e) {
+			return entries[i], nil
+		}
+	}
+	return Entry{}, fmt.Errorf("%w: no earlier run of the request in entry %d", ErrNotFound, e.ID)
+}
+
+// Change is a URL whose rank differs between two runs. Ranks are 1-based;
+// 0 means the URL is absent from that run.
+type Change struct {
+	URL  string `json:"url"`
+	From int    `json:"from,omitempty"`
+	To   int    `json:"to,omitempty"`
+}
+
+// Comparison lists how the result URLs changed from one run to another.
+type Comparison struct {
+	Appeared    []Change `json:"appeared"`
+	Disappeared []Change `json:"disappeared"`
+	Moved       []Change `json:"moved"`
+	Unchanged   int      `json:"unchanged"`
+}
+
+// Compare diffs the ranked URL lists of two runs.
+func Compare(from, to []string) Comparison {
+	c := Comparison{Appeared: []Change{}, Disappeared: []Change{}, Moved: []Change{}}
+	oldRank := ranks(from)
+	newRank := ranks(to)
+	for i, u := range to {
+		switch r, ok := oldRank[u]; {
+		case !ok:
+			c.Appeared = append(c.Appeared, Change{URL: u, To: i + 1})
+		case r != i+1:
+			c.Moved = append(c.Moved, Change{URL: u, From: r, To: i + 1})
+		default:
+			c.Unchanged++
+		}
+	}
+	for i, u := range from {
+		if _, ok := newRank[u]; !ok {
+			c.Disappeared = append(c.Disappeared, Change{URL: u, From: i + 1})
+		}
+	}
+	return c
+}
+
+// ranks maps each URL to its first 1-based position.
+func ranks(urls []string) map[string]int {
+	m := make(map[string]int, len(urls))
+	for i, u := range urls {
+		if _, ok := m[u]; !ok {
+			m[u] = i + 1
+		}
+	}
+	return m
+}
@@ -0,0 +1,58 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_AppendListPrevious(t *testing.T) {
+	s := Open(filepath.Join(t.TempDir(), "exa", "history.jsonl"))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, e := range []Entry{
+		{Time: now, Command: "search", Endpoint: "/search", Request: []byte(`{"query":"a","numResults":5}`)},
+		{Time: now, Command: "search", Endpoint: "/search", Request: []byte(`{"query":"b"}`)},
+		{Time: now, Command: "search", Endpoint: "/search", Request: []byte(`{"numResults":5,"query":"a"}`)},
+		{Time: now, Command: "serve", Endpoint: "/search", Request: []byte(`{"query":"a","numResults":5}`), Client: "key-1a2b3c4d"},
+	} {
+		if err := s.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := s.List()
+	if err != nil || len(entries) != 4 || entries[2].ID != 3 {
+		t.Fatalf("List = %+v, %v", entries, err)
+	}
+	prev, err := s.Previous(entries[2])
+	if err != nil || prev.ID != 1 {
+		t.Errorf("Previous(3) = %d, %v, want entry 1", prev.ID, err)
+	}
+	if _, err := s.Previous(entries[1]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Previous(2) err = %v, want ErrNotFound", err)
+	}
+	// A proxy client's run is not compared with the user's own.
+	if _, err := s.Previous(entries[3]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Previous(4) err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Get(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(9) err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	c := Compare([]string{"a", "b", "c", "d"}, []string{"b", "a", "c", "e"})
+	if len(c.Appeared) != 1 || c.Appeared[0] != (Change{URL: "e", To: 4}) {
+		t.Errorf("appeared = %+v", c.Appeared)
+	}
+	if len(c.Disappeared) != 1 || c.Disappeared[0] != (Change{URL: "d", From: 4}) {
+		t.Errorf("disappeared = %+v", c.Disappeared)
+	}
+	if len(c.Moved) != 2 || c.Moved[0] != (Change{URL: "b", From: 2, To: 1}) {
+		t.Errorf("moved = %+v", c.Moved)
+	}
+	if c.Unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", c.Unchanged)
+	}
+}
@@ -0,0 +1,628 @@
+// Package jsonschema validates JSON values against a subset of JSON Schema
+// draft 2020-12: the structural and value keywords used to describe
+// structured answers, local $ref, and boolean combinators. Schemas are
+// checked when compiled, so unsupported or malformed keywords are reported
+// before any request is sent.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// annotations are keywords accepted and ignored during validation.
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$anchor": true,
+	"title": true, "description": true, "default": true, "examples": true,
+	"deprecated": true, "readOnly": true, "writeOnly": true, "format": true,
+	"$defs": true, "definitions": true,
+}
+
+// typeNames are the values allowed in "type".
+var typeNames = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// Schema is a compiled schema.
+type Schema struct {
+	root *node
+}
+
+type node struct {
+	always *bool // Boolean schema: true accepts and false rejects everything
+
+	types      []string
+	properties map[string]*node
+	required   []string
+	additional *node // Nil allows any additional property
+	items      *node
+	prefix     []*node
+	enum       []interface{}
+	konst      interface{}
+	hasConst   bool
+
+	minimum, maximum           *float64
+	exclusiveMin, exclusiveMax *float64
+	multipleOf                 *float64
+	minLength, maxLength       *int
+	pattern                    *regexp.Regexp
+	minItems, maxItems         *int
+	uniqueItems                bool
+	minProps, maxProps         *int
+
+	allOf, anyOf, oneOf []*node
+	not                 *node
+	ref                 *node
+}
+
+// compiler compiles one schema document, resolving local references.
+type compiler struct {
+	doc  interface{}
+	refs map[string]*node
+}
+
+// Compile checks and compiles a decoded JSON schema (an object or a
+// boolean).
+func Compile(schema interface{}) (*Schema, error) {
+	c := &compiler{doc: normalize(schema), refs: make(map[string]*node)}
+	root, err := c.compile(c.doc, "#")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+func (c *compiler) compile(v interface{}, at string) (*node, error) {
+	if b, ok := v.(bool); ok {
+		return &node{always: &b}, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", at)
+	}
+
+	n := &node{}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := c.keyword(n, k, m[k], at+"/"+escape(k)); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (c *compiler) keyword(n *node, k string, v interface{}, at string) error {
+	var err error
+	switch k {
+	case "type":
+		n.types, err = typeList(v, at)
+	case "properties":
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an object", at)
+		}
+		n.properties = make(map[string]*node, len(props))
+		for name, sub := range props {
+			if n.properties[name], err = c.compile(sub, at+"/"+escape(name)); err != nil {
+				return err
+			}
+		}
+	case "required":
+		n.required, err = stringList(v, at)
+	case "additionalProperties":
+		n.additional, err = c.compile(v, at)
+	case "items":
+		n.items, err = c.compile(v, at)
+	case "prefixItems":
+		n.prefix, err = c.compileList(v, at)
+	case "enum":
+		list, ok := v.([]interface{})
+		if !ok || len(list) == 0 {
+			return fmt.Errorf("%s: must be a non-empty array", at)
+		}
+		n.enum = list
+	case "const":
+		n.konst, n.hasConst = v, true
+	case "minimum":
+		n.minimum, err = number(v, at)
+	case "maximum":
+		n.maximum, err = number(v, at)
+	case "exclusiveMinimum":
+		n.exclusiveMin, err = number(v, at)
+	case "exclusiveMaximum":
+		n.exclusiveMax, err = number(v, at)
+	case "multipleOf":
+		if n.multipleOf, err = number(v, at); err == nil && *n.multipleOf <= 0 {
+			err = fmt.Errorf("%s: must be greater than 0", at)
+		}
+	case "minLength":
+		n.minLength, err = count(v, at)
+	case "maxLength":
+		n.maxLength, err = count(v, at)
+	case "pattern":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", at)
+		}
+		if n.pattern, err = regexp.Compile(s); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", at, err)
+		}
+	case "minItems":
+		n.minItems, err = count(v, at)
+	case "maxItems":
+		n.maxItems, err = count(v, at)
+	case "uniqueItems":
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%s: must be a boolean", at)
+		}
+		n.uniqueItems = b
+	case "minProperties":
+		n.minProps, err = count(v, at)
+	case "maxProperties":
+		n.maxProps, err = count(v, at)
+	case "allOf":
+		n.allOf, err = c.compileList(v, at)
+	case "anyOf":
+		n.anyOf, err = c.compileList(v, at)
+	case "oneOf":
+		n.oneOf, err = c.compileList(v, at)
+	case "not":
+		n.not, err = c.compile(v, at)
+	case "$ref":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", at)
+		}
+		n.ref, err = c.resolve(s, at)
+	default:
+		if annotations[k] {
+			if (k == "$defs" || k == "definitions") && !isObject(v) {
+				return fmt.Errorf("%s: must be an object", at)
+			}
+			return nil
+		}
+		return fmt.Errorf("%s: unsupported keyword %q", at, k)
+	}
+	return err
+}
+
+func (c *compiler) compileList(v interface{}, at string) ([]*node, error) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s: must be a non-empty array of schemas", at)
+	}
+	nodes := make([]*node, len(list))
+	for i, sub := range list {
+		var err error
+		if nodes[i], err = c.compile(sub, at+"/"+strconv.Itoa(i)); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// resolve compiles the schema a local reference ("#" or "#/$defs/name")
+// points to. Recursive references share one node.
+func (c *compiler) resolve(ref, at string) (*node, error) {
+	if n, ok := c.refs[ref]; ok {
+		return n, nil
+	}
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("%s: only local references (#/...) are supported, got %q", at, ref)
+	}
+	target := c.doc
+	if ref != "#" {
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			switch t := target.(type) {
+			case map[string]interface{}:
+				target = t[part]
+			case []interface{}:
+				i, err := strconv.Atoi(part)
+				if err != nil || i < 0 || i >= len(t) {
+					target = nil
+				} else {
+					target = t[i]
+				}
+			default:
+				target = nil
+			}
+			if target == nil {
+				return nil, fmt.Errorf("%s: reference %q not found", at, ref)
+			}
+		}
+	}
+
+	// Register the node before compiling it so recursive references resolve.
+	n := &node{}
+	c.refs[ref] = n
+	compiled, err := c.compile(target, ref)
+	if err != nil {
+		return nil, err
+	}
+	*n = *compiled
+	return n, nil
+}
+
+// Issue is one way a value fails a schema. Path locates the value, e.g.
+// "$.people[0].age".
+type Issue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every issue found in a value.
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		parts[i] = is.Path + ": " + is.Message
+	}
+	return "does not match schema: " + strings.Join(parts, "; ")
+}
+
+// Validate checks a decoded JSON value, returning a *ValidationError if it
+// does not match.
+func (s *Schema) Validate(v interface{}) error {
+	issues := s.root.validate(normalize(v), "$")
+	if len(issues) > 0 {
+		return &ValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// ValidateJSON parses data and validates it.
+func (s *Schema) ValidateJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, &ValidationError{Issues: []Issue{{Path: "$", Message: "not valid JSON: " + err.Error()}}}
+	}
+	return v, s.Validate(v)
+}
+
+func (n *node) validate(v interface{}, path string) []Issue {
+	if n.always != nil {
+		if *n.always {
+			return nil
+		}
+		return []Issue{{path, "no value is allowed here"}}
+	}
+
+	var issues []Issue
+	add := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{path, fmt.Sprintf(format, args...)})
+	}
+
+	if n.ref != nil {
+		issues = append(issues, n.ref.validate(v, path)...)
+	}
+	if len(n.types) > 0 && !matchesType(v, n.types) {
+		add("expected %s, got %s", strings.Join(n.types, " or "), typeOf(v))
+		// Value keywords for other types would only repeat the mismatch.
+		return issues
+	}
+	if n.hasConst && !reflect.DeepEqual(v, n.konst) {
+		add("must be %s", encode(n.konst))
+	}
+	if n.enum != nil && !contains(n.enum, v) {
+		values := make([]string, len(n.enum))
+		for i, e := range n.enum {
+			values[i] = encode(e)
+		}
+		add("must be one of %s", strings.Join(values, ", "))
+	}
+
+	switch x := v.(type) {
+	case float64:
+		issues = append(issues, n.validateNumber(x, path)...)
+	case string:
+		length := len([]rune(x))
+		if n.minLength != nil && length < *n.minLength {
+			add("must be at least %d characters, got %d", *n.minLength, length)
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			add("must be at most %d characters, got %d", *n.maxLength, length)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(x) {
+			add("must match pattern %q", n.pattern.String())
+		}
+	case []interface{}:
+		issues = append(issues, n.validateArray(x, path)...)
+	case map[string]interface{}:
+		issues = append(issues, n.validateObject(x, path)...)
+	}
+
+	for _, sub := range n.allOf {
+		issues = append(issues, sub.validate(v, path)...)
+	}
+	if n.anyOf != nil {
+		matched := false
+		for _, sub := range n.anyOf {
+			if len(sub.validate(v, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			add("does not match any of the anyOf schemas")
+		}
+	}
+	if n.oneOf != nil {
+		matches := 0
+		for _, sub := range n.oneOf {
+			if len(sub.validate(v, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			add("must match exactly one of the oneOf schemas, matched %d", matches)
+		}
+	}
+	if n.not != nil && len(n.not.validate(v, path)) == 0 {
+		add("must not match the schema in \"not\"")
+	}
+	return issues
+}
+
+func (n *node) validateNumber(x float64, path string) []Issue {
+	var issues []Issue
+	add := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{path, fmt.Sprintf(format, args...)})
+	}
+	if n.minimum != nil && x < *n.minimum {
+		add("must be >= %s, got %s", encode(*n.minimum), encode(x))
+	}
+	if n.maximum != nil && x > *n.maximum {
+		add("must be <= %s, got %s", encode(*n.maximum), encode(x))
+	}
+	if n.exclusiveMin != nil && x <= *n.exclusiveMin {
+		add("must be > %s, got %s", encode(*n.exclusiveMin), encode(x))
+	}
+	if n.exclusiveMax != nil && x >= *n.exclusiveMax {
+		add("must be < %s, got %s", encode(*n.exclusiveMax), encode(x))
+	}
+	if n.multipleOf != nil {
+		q := x / *n.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			add("must be a multiple of %s", encode(*n.multipleOf))
+		}
+	}
+	return issues
+}
+
+func (n *node) validateArray(items []interface{}, path string) []Issue {
+	var issues []Issue
+	add := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{path, fmt.Sprintf(format, args...)})
+	}
+	if n.minItems != nil && len(items) < *n.minItems {
+		add("must have at least %d items, got %d", *n.minItems, len(items))
+	}
+	if n.maxItems != nil && len(items) > *n.maxItems {
+		add("must have at most %d items, got %d", *n.maxItems, len(items))
+	}
+	if n.uniqueItems {
+		for i := range items {
+			for j := i + 1; j < len(items); j++ {
+				if reflect.DeepEqual(items[i], items[j]) {
+					add("items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i < len(n.prefix):
+			issues = append(issues, n.prefix[i].validate(item, itemPath)...)
+		case n.items != nil:
+			issues = append(issues, n.items.validate(item, itemPath)...)
+		}
+	}
+	return issues
+}
+
+func (n *node) validateObject(obj map[string]interface{}, path string) []Issue {
+	var issues []Issue
+	add := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{path, fmt.Sprintf(format, args...)})
+	}
+	if n.minProps != nil && len(obj) < *n.minProps {
+		add("must have at least %d properties, got %d", *n.minProps, len(obj))
+	}
+	if n.maxProps != nil && len(obj) > *n.maxProps {
+		add("must have at most %d properties, got %d", *n.maxProps, len(obj))
+	}
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			add("missing required property %q", name)
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propPath := propertyPath(path, name)
+		if sub, ok := n.properties[name]; ok {
+			issues = append(issues, sub.validate(obj[name], propPath)...)
+		} else if n.additional != nil {
+			if n.additional.always != nil && !*n.additional.always {
+				issues = append(issues, Issue{propPath, "property is not allowed"})
+			} else {
+				issues = append(issues, n.additional.validate(obj[name], propPath)...)
+			}
+		}
+	}
+	return issues
+}
+
+// propertyPath appends a property to a path, quoting names that are not
+// plain identifiers.
+func propertyPath(path, name string) string {
+	if identifier.MatchString(name) {
+		return path + "." + name
+	}
+	return path + "[" + strconv.Quote(name) + "]"
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func matchesType(v interface{}, types []string) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON type name of a decoded value. Whole numbers are
+// integers.
+func typeOf(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if x == math.Trunc(x) && !math.IsInf(x, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// normalize converts json.Number values to float64 so decoded documents
+// compare consistently.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		f, _ := x.Float64()
+		return f
+	case int:
+		return float64(x)
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = normalize(e)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			out[k] = normalize(e)
+		}
+		return out
+	}
+	return v
+}
+
+func typeList(v interface{}, at string) ([]string, error) {
+	var names []string
+	switch x := v.(type) {
+	case string:
+		names = []string{x}
+	case []interface{}:
+		for _, e := range x {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a type name or an array of type names", at)
+			}
+			names = append(names, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s: must be a type name or an array of type names", at)
+	}
+	for _, name := range names {
+		if !typeNames[name] {
+			return nil, fmt.Errorf("%s: unknown type %q", at, name)
+		}
+	}
+	return names, nil
+}
+
+func stringList(v interface{}, at string) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an array of strings", at)
+	}
+	out := make([]string, len(list))
+	for i, e := range list {
+		s, ok := e.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: must be an array of strings", at)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+func number(v interface{}, at string) (*float64, error) {
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", at)
+	}
+	return &f, nil
+}
+
+func count(v interface{}, at string) (*int, error) {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	n := int(f)
+	return &n, nil
+}
+
+func isObject(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, e := range list {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func encode(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// escape encodes a JSON Pointer token.
+func escape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
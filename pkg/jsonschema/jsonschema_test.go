@@ -0,0 +1,170 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func compile(t *testing.T, src string) *Schema {
+	t.Helper()
+	var raw interface{}
+	if err := json.Unmarshal([]byte(src), &raw); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Compile(raw)
+	if err != nil {
+		t.Fatalf("Compile(%s): %v", src, err)
+	}
+	return s
+}
+
+// issues validates doc and returns its issues as "path: message" strings.
+func issues(t *testing.T, s *Schema, doc string) []string {
+	t.Helper()
+	_, err := s.ValidateJSON([]byte(doc))
+	if err == nil {
+		return nil
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("ValidateJSON(%s) error %T: %v", doc, err, err)
+	}
+	var out []string
+	for _, is := range verr.Issues {
+		out = append(out, is.Path+": "+is.Message)
+	}
+	return out
+}
+
+const people = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["people"],
+	"additionalProperties": false,
+	"properties": {
+		"people": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"$ref": "#/$defs/person"}
+		},
+		"source": {"type": ["string", "null"], "pattern": "^https://"}
+	},
+	"$defs": {
+		"person": {
+			"type": "object",
+			"required": ["name", "age"],
+			"properties": {
+				"name": {"type": "string", "minLength": 1},
+				"age": {"type": "integer", "minimum": 0, "maximum": 150},
+				"role": {"enum": ["author", "editor"]},
+				"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}
+			}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	s := compile(t, people)
+
+	valid := `{"people": [{"name": "Ada", "age": 36, "role": "author", "tags": ["math"]}], "source": null}`
+	if got := issues(t, s, valid); got != nil {
+		t.Errorf("valid document: %v", got)
+	}
+
+	invalid := `{
+		"people": [
+			{"name": "Ada", "age": "36"},
+			{"name": "", "age": 36.5, "role": "reader", "tags": ["a", "a"]},
+			{"age": 200}
+		],
+		"source": "http://example.com",
+		"extra": true
+	}`
+	want := []string{
+		`$.extra: property is not allowed`,
+		`$.people[0].age: expected integer, got string`,
+		`$.people[1].age: expected integer, got number`,
+		`$.people[1].name: must be at least 1 characters, got 0`,
+		`$.people[1].role: must be one of "author", "editor"`,
+		`$.people[1].tags: items 0 and 1 are equal`,
+		`$.people[2]: missing required property "name"`,
+		`$.people[2].age: must be <= 150, got 200`,
+		`$.source: must match pattern "^https://"`,
+	}
+	got := issues(t, s, invalid)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if got := issues(t, s, `{"people": [`); len(got) != 1 || !strings.HasPrefix(got[0], "$: not valid JSON") {
+		t.Errorf("malformed JSON: %v", got)
+	}
+	if got := issues(t, s, `["people"]`); len(got) != 1 || got[0] != "$: expected object, got array" {
+		t.Errorf("wrong root type: %v", got)
+	}
+}
+
+func TestCombinatorsAndRecursion(t *testing.T) {
+	s := compile(t, `{
+		"$defs": {"node": {
+			"type": "object",
+			"properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}},
+			"oneOf": [{"required": ["leaf"]}, {"required": ["children"]}]
+		}},
+		"allOf": [{"$ref": "#/$defs/node"}],
+		"not": {"required": ["forbidden"]},
+		"properties": {
+			"count": {"anyOf": [{"type": "integer", "multipleOf": 5}, {"const": "many"}]},
+			"pair": {"type": "array", "prefixItems": [{"type": "string"}, {"type": "number"}], "items": false}
+		}
+	}`)
+
+	if got := issues(t, s, `{"children": [{"leaf": 1}, {"children": []}], "count": 10, "pair": ["x", 1.5]}`); got != nil {
+		t.Errorf("valid document: %v", got)
+	}
+	got := issues(t, s, `{"children": [{"leaf": 1, "children": []}], "count": 7, "pair": [1, 2, 3], "forbidden": 1}`)
+	for _, want := range []string{
+		`$.children[0]: must match exactly one of the oneOf schemas, matched 2`,
+		`$.count: does not match any of the anyOf schemas`,
+		`$.pair[0]: expected string, got integer`,
+		`$.pair[2]: no value is allowed here`,
+		`$: must not match the schema in "not"`,
+	} {
+		if !strings.Contains(strings.Join(got, "\n"), want) {
+			t.Errorf("missing issue %q in:\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		`"object"`:        "#: schema must be an object or a boolean",
+		`{"type": "map"}`: `#/type: unknown type "map"`,
+		`{"properties": {"a": {"minLenght": 1}}}`:     `#/properties/a/minLenght: unsupported keyword "minLenght"`,
+		`{"required": "a"}`:                           "#/required: must be an array of strings",
+		`{"items": {"$ref": "#/$defs/missing"}}`:      `#/items/$ref: reference "#/$defs/missing" not found`,
+		`{"$ref": "https://example.com/schema.json"}`: "only local references",
+		`{"pattern": "("}`:                            "#/pattern: invalid pattern",
+		`{"maxItems": -1}`:                            "#/maxItems: must be a non-negative integer",
+		`{"anyOf": []}`:                               "#/anyOf: must be a non-empty array of schemas",
+	} {
+		var raw interface{}
+		if err := json.Unmarshal([]byte(src), &raw); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Compile(raw)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%s) = %v, want %q", src, err, want)
+		}
+	}
+}
+
+func TestPropertyPath(t *testing.T) {
+	s := compile(t, `{"properties": {"first name": {"type": "string"}}}`)
+	got := issues(t, s, `{"first name": 1}`)
+	if len(got) != 1 || got[0] != `$["first name"]: expected string, got integer` {
+		t.Errorf("issues = %v", got)
+	}
+}
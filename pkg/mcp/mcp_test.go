@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, s *Server, messages ...string) []Response {
+	t.Helper()
+	var out strings.Builder
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(messages, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	var resps []Response
+	sc := bufio.NewScanner(strings.NewReader(out.String()))
+	for sc.Scan() {
+		var r Response
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("invalid response line %q: %v", sc.Text(), err)
+		}
+		resps = append(resps, r)
+	}
+	return resps
+}
+
+func TestServe(t *testing.T) {
+	s := &Server{
+		Name:    "test",
+		Version: "1.0",
+		Tools: []Tool{
+			{
+				Name:        "echo",
+				InputSchema: &Schema{Type: "object", Properties: map[string]*Schema{"msg": {Type: "string"}}},
+				Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+					if args["msg"] == "fail" {
+						return nil, errors.New("boom")
+					}
+					return map[string]interface{}{"echo": args["msg"]}, nil
+				},
+			},
+		},
+		ErrorData: func(err error) interface{} { return map[string]string{"code": "TEST"} },
+	}
+
+	resps := serve(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"msg":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{"msg":"fail"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`not json`,
+	)
+	if len(resps) != 7 {
+		t.Fatalf("got %d responses, want 7 (notifications get none)", len(resps))
+	}
+
+	init, _ := json.Marshal(resps[0].Result)
+	if !strings.Contains(string(init), `"protocolVersion":"2024-11-05"`) || !strings.Contains(string(init), `"name":"test"`) {
+		t.Errorf("initialize = %s", init)
+	}
+	if string(resps[1].ID) != `"two"` {
+		t.Errorf("string ID not echoed: %s", resps[1].ID)
+	}
+	list, _ := json.Marshal(resps[1].Result)
+	if !strings.Contains(string(list), `"name":"echo"`) || !strings.Contains(string(list), `"inputSchema":{`) {
+		t.Errorf("tools/list = %s", list)
+	}
+
+	ok, _ := json.Marshal(resps[2].Result)
+	if !strings.Contains(string(ok), `"structuredContent":{"echo":"hi"}`) || strings.Contains(string(ok), "isError") {
+		t.Errorf("tools/call = %s", ok)
+	}
+	failed, _ := json.Marshal(resps[3].Result)
+	if !strings.Contains(string(failed), `"isError":true`) || !strings.Contains(string(failed), `"text":"boom"`) || !strings.Contains(string(failed), `"code":"TEST"`) {
+		t.Errorf("failed tools/call = %s", failed)
+	}
+
+	for i, code := range map[int]int{4: CodeInvalidParams, 5: CodeMethodNotFound, 6: CodeParseError} {
+		if resps[i].Error == nil || resps[i].Error.Code != code {
+			t.Errorf("response %d error = %+v, want code %d", i, resps[i].Error, code)
+		}
+	}
+}
+
+func TestServe_UnsupportedVersion(t *testing.T) {
+	resps := serve(t, &Server{}, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	init, _ := json.Marshal(resps[0].Result)
+	if !strings.Contains(string(init), LatestProtocolVersion) {
+		t.Errorf("initialize = %s, want latest version", init)
+	}
+}
+
+func TestServe_HandlersDoNotOverlap(t *testing.T) {
+	var running, overlaps int32
+	s := &Server{Tools: []Tool{{Name: "slow", Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil, nil
+	}}}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = s.call(context.Background(), json.RawMessage(`{"name":"slow"}`))
+		}()
+	}
+	wg.Wait()
+	if overlaps != 0 {
+		t.Errorf("%d handler calls overlapped", overlaps)
+	}
+}
@@ -0,0 +1,482 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+// Kinds of cited work, chosen from the result's host.
+const (
+	kindWebpage  = "webpage"
+	kindArticle  = "article"
+	kindPreprint = "preprint"
+)
+
+// preprintHosts and articleHosts map hosts of scholarly sources to the name
+// used as the container (journal or repository) of their citations.
+var (
+	preprintHosts = map[string]string{
+		"arxiv.org":       "arXiv",
+		"biorxiv.org":     "bioRxiv",
+		"medrxiv.org":     "medRxiv",
+		"chemrxiv.org":    "ChemRxiv",
+		"ssrn.com":        "SSRN",
+		"papers.ssrn.com": "SSRN",
+		"openreview.net":  "OpenReview",
+		"psyarxiv.com":    "PsyArXiv",
+		"osf.io":          "OSF Preprints",
+	}
+	articleHosts = map[string]string{
+		"doi.org":                 "",
+		"dl.acm.org":              "ACM Digital Library",
+		"ieeexplore.ieee.org":     "IEEE Xplore",
+		"link.springer.com":       "Springer",
+		"nature.com":              "Nature",
+		"science.org":             "Science",
+		"sciencedirect.com":       "ScienceDirect",
+		"pubmed.ncbi.nlm.nih.gov": "PubMed",
+		"ncbi.nlm.nih.gov":        "PubMed Central",
+		"aclanthology.org":        "ACL Anthology",
+		"jmlr.org":                "Journal of Machine Learning Research",
+		"proceedings.mlr.press":   "Proceedings of Machine Learning Research",
+		"proceedings.neurips.cc":  "Advances in Neural Information Processing Systems",
+		"plos.org":                "PLOS",
+		"journals.plos.org":       "PLOS",
+		"onlinelibrary.wiley.com": "Wiley Online Library",
+		"tandfonline.com":         "Taylor & Francis Online",
+		"jstor.org":               "JSTOR",
+		"semanticscholar.org":     "Semantic Scholar",
+	}
+)
+
+// citation is a result mapped to the fields reference managers import.
+type citation struct {
+	Key      string
+	Kind     string
+	Title    string
+	URL      string
+	Authors  []personName
+	Site     string    // Journal, repository or website name
+	Issued   time.Time // Zero when the result has no published date
+	Accessed time.Time
+}
+
+// personName is an author split into family and given names. Single-word
+// and organisation names only set Literal.
+type personName struct {
+	Family  string
+	Given   string
+	Literal string
+}
+
+// citationResults returns the results or citations of a response.
+func citationResults(data interface{}) ([]api.SearchResult, bool) {
+	switch resp := data.(type) {
+	case *api.SearchResponse:
+		return resp.Results, true
+	case *api.FindSimilarResponse:
+		return resp.Results, true
+	case *api.ContentsResponse:
+		return resp.Results, true
+	case *api.AnswerResponse:
+		return resp.Citations, true
+	}
+	return nil, false
+}
+
+// renderCitations writes the results of data as BibTeX, RIS or CSL-JSON.
+func renderCitations(w io.Writer, data interface{}, mode Mode) error {
+	results, ok := citationResults(data)
+	if !ok {
+		return &UsageError{Err: fmt.Errorf("citation output is only supported for search results, contents and answer citations")}
+	}
+	cites := citations(results, time.Now())
+	switch mode {
+	case ModeRIS:
+		return writeRIS(w, cites)
+	case ModeCSLJSON:
+		return writeCSLJSON(w, cites)
+	}
+	return writeBibTeX(w, cites)
+}
+
+// citations maps results to citations accessed at now. Keys are unique
+// within the list; repeats get a letter suffix in result order.
+func citations(results []api.SearchResult, now time.Time) []citation {
+	cites := make([]citation, 0, len(results))
+	used := make(map[string]bool)
+	repeats := make(map[string]int)
+	for _, r := range results {
+		c := citation{
+			Title:    strings.TrimSpace(r.Title),
+			URL:      r.URL,
+			Authors:  parseAuthors(r.Author),
+			Accessed: now,
+		}
+		if c.Title == "" {
+			c.Title = r.URL
+		}
+		c.Kind, c.Site = classifyURL(r.URL)
+		if t, err := time.Parse(time.RFC3339, r.PublishedDate); err == nil {
+			c.Issued = t
+		} else if t, err := time.Parse("2006-01-02", r.PublishedDate); err == nil {
+			c.Issued = t
+		}
+
+		key := citationKey(c)
+		for base := key; used[key]; key = base + keySuffix(repeats[base]) {
+			repeats[base]++
+		}
+		used[key] = true
+		c.Key = key
+		cites = append(cites, c)
+	}
+	return cites
+}
+
+// keySuffix returns the letter suffix for the nth repeat of a key: b to z,
+// then aa, ab and so on.
+func keySuffix(n int) string {
+	var b []byte
+	for ; n >= 0; n = n/26 - 1 {
+		b = append([]byte{byte('a' + n%26)}, b...)
+	}
+	return string(b)
+}
+
+// classifyURL returns the kind of work at rawURL and the name of the site
+// that published it.
+func classifyURL(rawURL string) (kind, site string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return kindWebpage, ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if name, ok := lookupHost(preprintHosts, host); ok {
+		return kindPreprint, name
+	}
+	if name, ok := lookupHost(articleHosts, host); ok {
+		if name == "" {
+			name = host
+		}
+		return kindArticle, name
+	}
+	return kindWebpage, host
+}
+
+// lookupHost finds host or its closest parent domain in hosts.
+func lookupHost(hosts map[string]string, host string) (string, bool) {
+	for {
+		if name, ok := hosts[host]; ok {
+			return name, true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 || !strings.Contains(host[i+1:], ".") {
+			return "", false
+		}
+		host = host[i+1:]
+	}
+}
+
+// parseAuthors splits an Exa author string such as "Ada Lovelace, Alan
+// Turing" or "Lovelace, Ada and Turing, Alan" into names.
+func parseAuthors(s string) []personName {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	var parts []string
+	for _, p := range strings.Split(s, ";") {
+		parts = append(parts, strings.Split(p, " and ")...)
+	}
+	// "First Last, First Last" lists names separated by commas; a single
+	// comma between one-word halves is "Last, First".
+	if len(parts) == 1 && strings.Contains(s, ",") {
+		commaParts := strings.Split(s, ",")
+		inverted := len(commaParts) == 2 && !strings.Contains(strings.TrimSpace(commaParts[0]), " ")
+		if !inverted {
+			parts = commaParts
+		}
+	}
+
+	var names []personName
+	for _, p := range parts {
+		p = strings.Join(strings.Fields(p), " ")
+		if p == "" {
+			continue
+		}
+		if family, given, ok := strings.Cut(p, ", "); ok {
+			names = append(names, personName{Family: family, Given: given})
+			continue
+		}
+		i := strings.LastIndexByte(p, ' ')
+		if i < 0 {
+			names = append(names, personName{Literal: p})
+			continue
+		}
+		names = append(names, personName{Family: p[i+1:], Given: p[:i]})
+	}
+	return names
+}
+
+// keyStopWords are skipped when picking the title word of a citation key.
+var keyStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "on": true, "of": true, "in": true,
+	"for": true, "to": true, "and": true, "with": true, "from": true, "how": true,
+	"what": true, "why": true, "is": true, "are": true,
+}
+
+// citationKey builds an ASCII key from the first author's family name (or
+// the site), the year and the first significant title word, such as
+// vaswani2017attention.
+func citationKey(c citation) string {
+	var who string
+	if len(c.Authors) > 0 {
+		who = c.Authors[0].Family
+		if who == "" {
+			who = c.Authors[0].Literal
+		}
+		who = keyWord(strings.Fields(who))
+	}
+	if who == "" {
+		who = keyWord(strings.FieldsFunc(c.Site, func(r rune) bool { return r == '.' }))
+	}
+	if who == "" {
+		who = "exa"
+	}
+
+	year := "nd"
+	if !c.Issued.IsZero() {
+		year = strconv.Itoa(c.Issued.Year())
+	}
+
+	var words []string
+	for _, w := range strings.Fields(c.Title) {
+		if !keyStopWords[strings.ToLower(w)] {
+			words = append(words, w)
+		}
+	}
+	return who + year + keyWord(words)
+}
+
+// keyWord returns the first of words that has ASCII letters or digits left
+// after folding, lower-cased.
+func keyWord(words []string) string {
+	for _, w := range words {
+		var b strings.Builder
+		for _, r := range accentFolder.Replace(w) {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				b.WriteRune(unicode.ToLower(r))
+			}
+		}
+		if b.Len() > 0 {
+			return b.String()
+		}
+	}
+	return ""
+}
+
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a", "æ", "ae",
+	"ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o", "œ", "oe",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+	"Á", "A", "À", "A", "Â", "A", "Ä", "A", "Å", "A", "Ç", "C", "É", "E",
+	"È", "E", "Í", "I", "Ñ", "N", "Ó", "O", "Ö", "O", "Ø", "O", "Ú", "U", "Ü", "U",
+)
+
+// BibTeX
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`, "}", `\}`,
+	"&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+	"~", `\textasciitilde{}`, "^", `\textasciicircum{}`,
+)
+
+// bibtexURLEscaper percent-encodes the characters that would break a braced
+// url field.
+var bibtexURLEscaper = strings.NewReplacer("{", "%7B", "}", "%7D", `\`, "%5C", " ", "%20")
+
+func bibtexValue(s string) string {
+	return bibtexEscaper.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+func bibtexName(n personName) string {
+	if n.Literal != "" {
+		return "{" + bibtexValue(n.Literal) + "}"
+	}
+	return bibtexValue(n.Family) + ", " + bibtexValue(n.Given)
+}
+
+func writeBibTeX(w io.Writer, cites []citation) error {
+	var b strings.Builder
+	for i, c := range cites {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		entryType := "misc"
+		if c.Kind == kindArticle {
+			entryType = "article"
+		}
+		fmt.Fprintf(&b, "@%s{%s,\n", entryType, c.Key)
+		field := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "  %s = {%s},\n", name, value)
+			}
+		}
+
+		field("title", bibtexValue(c.Title))
+		var authors []string
+		for _, a := range c.Authors {
+			authors = append(authors, bibtexName(a))
+		}
+		field("author", strings.Join(authors, " and "))
+		switch c.Kind {
+		case kindArticle:
+			field("journal", bibtexValue(c.Site))
+		case kindPreprint:
+			field("howpublished", bibtexValue(c.Site))
+		default:
+			field("organization", bibtexValue(c.Site))
+		}
+		if !c.Issued.IsZero() {
+			field("year", strconv.Itoa(c.Issued.Year()))
+			field("month", strings.ToLower(c.Issued.Month().String()[:3]))
+		}
+		field("url", bibtexURLEscaper.Replace(c.URL))
+		field("urldate", c.Accessed.Format("2006-01-02"))
+		b.WriteString("}\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RIS
+
+func risValue(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func writeRIS(w io.Writer, cites []citation) error {
+	var b strings.Builder
+	for i, c := range cites {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		tag := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "%s  - %s\n", name, value)
+			}
+		}
+
+		switch c.Kind {
+		case kindArticle:
+			tag("TY", "JOUR")
+		case kindPreprint:
+			tag("TY", "UNPB")
+		default:
+			tag("TY", "ELEC")
+		}
+		tag("ID", c.Key)
+		tag("TI", risValue(c.Title))
+		for _, a := range c.Authors {
+			if a.Literal != "" {
+				tag("AU", risValue(a.Literal))
+			} else {
+				tag("AU", risValue(a.Family)+", "+risValue(a.Given))
+			}
+		}
+		if c.Kind == kindArticle {
+			tag("JO", risValue(c.Site))
+		} else {
+			tag("PB", risValue(c.Site))
+		}
+		if !c.Issued.IsZero() {
+			tag("PY", strconv.Itoa(c.Issued.Year()))
+			tag("DA", c.Issued.Format("2006/01/02/"))
+		}
+		tag("UR", risValue(c.URL))
+		tag("Y2", c.Accessed.Format("2006/01/02/"))
+		b.WriteString("ER  - \n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// CSL-JSON
+
+type cslItem struct {
+	ID             string    `json:"id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title"`
+	Author         []cslName `json:"author,omitempty"`
+	ContainerTitle string    `json:"container-title,omitempty"`
+	Publisher      string    `json:"publisher,omitempty"`
+	Genre          string    `json:"genre,omitempty"`
+	URL            string    `json:"URL,omitempty"`
+	Issued         *cslDate  `json:"issued,omitempty"`
+	Accessed       cslDate   `json:"accessed"`
+}
+
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+func newCSLDate(t time.Time) cslDate {
+	return cslDate{DateParts: [][]int{{t.Year(), int(t.Month()), t.Day()}}}
+}
+
+func writeCSLJSON(w io.Writer, cites []citation) error {
+	items := make([]cslItem, 0, len(cites))
+	for _, c := range cites {
+		item := cslItem{
+			ID:       c.Key,
+			Type:     c.Kind,
+			Title:    c.Title,
+			URL:      c.URL,
+			Accessed: newCSLDate(c.Accessed),
+		}
+		for _, a := range c.Authors {
+			item.Author = append(item.Author, cslName(a))
+		}
+		switch c.Kind {
+		case kindArticle:
+			item.Type = "article-journal"
+			item.ContainerTitle = c.Site
+		case kindPreprint:
+			item.Type = "article"
+			item.Publisher = c.Site
+			item.Genre = "preprint"
+		default:
+			item.ContainerTitle = c.Site
+		}
+		if !c.Issued.IsZero() {
+			d := newCSLDate(c.Issued)
+			item.Issued = &d
+		}
+		items = append(items, item)
+	}
+
+	out, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json marshal: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
@@ -0,0 +1,173 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+var citeNow = time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func TestParseAuthors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []personName
+	}{
+		{"", nil},
+		{"Ada Lovelace", []personName{{Family: "Lovelace", Given: "Ada"}}},
+		{"Ada Lovelace, Alan Turing", []personName{{Family: "Lovelace", Given: "Ada"}, {Family: "Turing", Given: "Alan"}}},
+		{"Lovelace, Ada and Turing, Alan", []personName{{Family: "Lovelace", Given: "Ada"}, {Family: "Turing", Given: "Alan"}}},
+		{"Lovelace, Ada", []personName{{Family: "Lovelace", Given: "Ada"}}},
+		{"OpenAI", []personName{{Literal: "OpenAI"}}},
+	}
+	for _, tt := range tests {
+		if got := parseAuthors(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseAuthors(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCitations_KeysAndKinds(t *testing.T) {
+	results := []api.SearchResult{
+		{Title: "Attention Is All You Need", URL: "https://arxiv.org/abs/1706.03762", Author: "Ashish Vaswani, Noam Shazeer", PublishedDate: "2017-06-12T00:00:00.000Z"},
+		{Title: "Attention is all you need", URL: "https://proceedings.neurips.cc/paper/7181", Author: "Vaswani, Ashish", PublishedDate: "2017-12-04"},
+		{Title: "The Go Blog", URL: "https://www.go.dev/blog"},
+		{Title: "Über Müller", URL: "https://example.com/x", Author: "Jörg Müller"},
+	}
+	cites := citations(results, citeNow)
+
+	var keys, kinds []string
+	for _, c := range cites {
+		keys = append(keys, c.Key)
+		kinds = append(kinds, c.Kind)
+	}
+	wantKeys := []string{"vaswani2017attention", "vaswani2017attentionb", "gondgo", "mullernduber"}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("keys = %v, want %v", keys, wantKeys)
+	}
+	wantKinds := []string{kindPreprint, kindArticle, kindWebpage, kindWebpage}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("kinds = %v, want %v", kinds, wantKinds)
+	}
+	if cites[0].Site != "arXiv" || cites[2].Site != "go.dev" {
+		t.Errorf("sites = %q, %q", cites[0].Site, cites[2].Site)
+	}
+
+	// Keys are deterministic across runs.
+	if again := citations(results, citeNow.Add(time.Hour)); again[1].Key != cites[1].Key {
+		t.Errorf("key changed between runs: %q vs %q", again[1].Key, cites[1].Key)
+	}
+}
+
+func TestCitations_ManyRepeats(t *testing.T) {
+	results := make([]api.SearchResult, 30)
+	for i := range results {
+		results[i] = api.SearchResult{Title: "Same", URL: "https://example.com/" + strconv.Itoa(i)}
+	}
+	// A key that looks like a suffixed repeat must not be handed out twice.
+	results = append(results, api.SearchResult{Title: "Sameb", URL: "https://example.com/b"})
+
+	seen := make(map[string]bool)
+	for _, c := range citations(results, citeNow) {
+		if seen[c.Key] {
+			t.Errorf("duplicate key %q", c.Key)
+		}
+		seen[c.Key] = true
+	}
+	for _, key := range []string{"examplendsame", "examplendsameb", "examplendsamez", "examplendsameaa", "examplendsamead", "examplendsamebb"} {
+		if !seen[key] {
+			t.Errorf("missing key %q in %v", key, seen)
+		}
+	}
+}
+
+func TestBibTeX_Escaping(t *testing.T) {
+	cites := citations([]api.SearchResult{{
+		Title:         "50% of C# & {Go}_devs",
+		URL:           "https://example.com/a b?q={x}",
+		Author:        "Jane Doe",
+		PublishedDate: "2024-03-09T00:00:00.000Z",
+	}}, citeNow)
+
+	var buf bytes.Buffer
+	if err := writeBibTeX(&buf, cites); err != nil {
+		t.Fatal(err)
+	}
+	want := `@misc{doe202450,
+  title = {50\% of C\# \& \{Go\}\_devs},
+  author = {Doe, Jane},
+  organization = {example.com},
+  year = {2024},
+  month = {mar},
+  url = {https://example.com/a%20b?q=%7Bx%7D},
+  urldate = {2026-03-10},
+}
+`
+	if buf.String() != want {
+		t.Errorf("bibtex =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRIS(t *testing.T) {
+	cites := citations([]api.SearchResult{{
+		Title:         "A paper\nwith a newline",
+		URL:           "https://doi.org/10.1000/182",
+		Author:        "Doe, Jane and OpenAI",
+		PublishedDate: "2024-03-09T00:00:00.000Z",
+	}}, citeNow)
+
+	var buf bytes.Buffer
+	if err := writeRIS(&buf, cites); err != nil {
+		t.Fatal(err)
+	}
+	want := `TY  - JOUR
+ID  - doe2024paper
+TI  - A paper with a newline
+AU  - Doe, Jane
+AU  - OpenAI
+JO  - doi.org
+PY  - 2024
+DA  - 2024/03/09/
+UR  - https://doi.org/10.1000/182
+Y2  - 2026/03/10/
+ER  - 
+`
+	if buf.String() != want {
+		t.Errorf("ris =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestCSLJSON(t *testing.T) {
+	cites := citations([]api.SearchResult{
+		{Title: `Quotes "inside"`, URL: "https://arxiv.org/abs/1", Author: "Jane Doe", PublishedDate: "2024-03-09T00:00:00.000Z"},
+		{Title: "No date", URL: "https://example.com/"},
+	}, citeNow)
+
+	var buf bytes.Buffer
+	if err := writeCSLJSON(&buf, cites); err != nil {
+		t.Fatal(err)
+	}
+	var items []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &items); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items", len(items))
+	}
+	first := items[0]
+	if first["type"] != "article" || first["title"] != `Quotes "inside"` || first["publisher"] != "arXiv" {
+		t.Errorf("preprint item = %v", first)
+	}
+	if !strings.Contains(buf.String(), `"family": "Doe"`) {
+		t.Errorf("author not split:\n%s", buf.String())
+	}
+	if items[1]["type"] != "webpage" || items[1]["issued"] != nil || items[1]["container-title"] != "example.com" {
+		t.Errorf("webpage item = %v", items[1])
+	}
+}
@@ -0,0 +1,136 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NewDelimitedWriter returns a CSV writer for ModeCSV, or one that separates
+// cells with tabs for ModeTSV. Both quote cells per RFC 4180.
+func NewDelimitedWriter(w io.Writer, mode Mode) *csv.Writer {
+	cw := csv.NewWriter(w)
+	if mode == ModeTSV {
+		cw.Comma = '\t'
+	}
+	return cw
+}
+
+// ParseFields splits a --fields value into trimmed, non-empty paths.
+func ParseFields(fields string) []string {
+	var out []string
+	for _, f := range strings.Split(fields, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// FieldRows builds one row per record in data (see records), with one cell
+// per field path. Paths are dotted (costDollars.total) and are looked up in
+// the record first, then in the enclosing document.
+func FieldRows(data interface{}, fields []string) [][]string {
+	items, parent := records(data)
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		row := make([]string, len(fields))
+		for i, f := range fields {
+			v, ok := lookupPath(item, f)
+			if !ok && parent != nil {
+				v, _ = lookupPath(parent, f)
+			}
+			row[i] = cellValue(v)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func renderDelimited(w io.Writer, td TableData, data interface{}, opts Options) error {
+	header, rows := td.Headers, td.Rows
+	if fields := ParseFields(opts.Fields); len(fields) > 0 {
+		header, rows = fields, FieldRows(data, fields)
+	}
+
+	cw := NewDelimitedWriter(w, opts.Mode)
+	if !opts.NoHeader && len(header) > 0 {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	return cw.WriteAll(rows)
+}
+
+// records returns the items a response renders as one row or line each:
+// the elements of a list, or of the document's result array, or else the
+// document itself. parent is the enclosing document, if any.
+func records(data interface{}) (items []interface{}, parent map[string]interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return []interface{}{data}, nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return []interface{}{data}, nil
+	}
+
+	switch t := v.(type) {
+	case []interface{}:
+		return t, nil
+	case map[string]interface{}:
+		for _, key := range recordArrayKeys {
+			if arr, ok := t[key].([]interface{}); ok {
+				return arr, t
+			}
+		}
+		return []interface{}{t}, nil
+	}
+	return []interface{}{v}, nil
+}
+
+// lookupPath resolves a dotted path through nested objects.
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// cellValue formats a JSON value for a single cell. Objects and arrays of
+// non-strings are written as compact JSON.
+func cellValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case []interface{}:
+		strs := make([]string, 0, len(t))
+		for _, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				break
+			}
+			strs = append(strs, s)
+		}
+		if len(strs) == len(t) {
+			return strings.Join(strs, "; ")
+		}
+	}
+	out, _ := json.Marshal(v)
+	return string(out)
+}
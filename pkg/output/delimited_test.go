@@ -0,0 +1,65 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func TestRenderDelimited_FieldsAndQuoting(t *testing.T) {
+	resp := &api.SearchResponse{
+		RequestID:   "r1",
+		Results:     []api.SearchResult{{Title: "Tabs\tand \"quotes\"", URL: "https://a", Score: 0.5}, {Title: "Two\nlines", URL: "https://b"}},
+		CostDollars: &api.CostInfo{Total: 0.005},
+	}
+
+	var buf bytes.Buffer
+	opts := Options{Mode: ModeCSV, Fields: "title,url,costDollars.total,score,missing"}
+	if err := renderDelimited(&buf, TableData{}, resp, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "title,url,costDollars.total,score,missing\n" +
+		"\"Tabs\tand \"\"quotes\"\"\",https://a,0.005,0.5,\n" +
+		"\"Two\nlines\",https://b,0.005,0,\n"
+	if buf.String() != want {
+		t.Errorf("csv =\n%q\nwant\n%q", buf.String(), want)
+	}
+
+	buf.Reset()
+	opts = Options{Mode: ModeTSV, Fields: "title,url", NoHeader: true}
+	if err := renderDelimited(&buf, TableData{}, resp, opts); err != nil {
+		t.Fatal(err)
+	}
+	want = "\"Tabs\tand \"\"quotes\"\"\"\thttps://a\n\"Two\nlines\"\thttps://b\n"
+	if buf.String() != want {
+		t.Errorf("tsv =\n%q\nwant\n%q", buf.String(), want)
+	}
+}
+
+func TestRenderDelimited_FallsBackToTable(t *testing.T) {
+	td := TableData{Headers: []string{"A", "B"}, Rows: [][]string{{"1", "x,y"}}}
+	var buf bytes.Buffer
+	if err := renderDelimited(&buf, td, nil, Options{Mode: ModeCSV}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "A,B\n1,\"x,y\"\n"; buf.String() != want {
+		t.Errorf("csv = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCellValue(t *testing.T) {
+	cases := map[string]interface{}{
+		"":            nil,
+		"a; b":        []interface{}{"a", "b"},
+		"[1,2]":       []interface{}{1.0, 2.0},
+		`{"total":1}`: map[string]interface{}{"total": 1.0},
+		"true":        true,
+		"1234567":     1234567.0,
+	}
+	for want, v := range cases {
+		if got := cellValue(v); got != want {
+			t.Errorf("cellValue(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
@@ -2,22 +2,97 @@ package output
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"strings"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+// Process exit codes, one per error class. The same value is reported as
+// "exitCode" in structured JSON errors.
+const (
+	ExitOK             = 0
+	ExitError          = 1 // Unclassified failure
+	ExitUsage          = 2 // Invalid flags, arguments or request parameters
+	ExitAuthRequired   = 3 // No API key configured
+	ExitAuthInvalid    = 4 // API key rejected
+	ExitRateLimited    = 5 // Rate limited after retries
+	ExitNetwork        = 6 // Network failure or timeout
+	ExitServer         = 7 // Exa server error or malformed response
+	ExitNoResults      = 8 // Request succeeded but returned nothing
+	ExitBudgetExceeded = 9 // Spending limit reached
+)
+
+var (
+	// ErrNoResults is returned by commands whose request succeeded but
+	// produced an empty result set.
+	ErrNoResults = errors.New("no results found")
+	// ErrBudgetExceeded is returned when a spending limit prevents a call.
+	ErrBudgetExceeded = errors.New("spending budget exceeded")
+)
+
+// UsageError marks an error caused by invalid command-line usage.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string { return e.Err.Error() }
+func (e *UsageError) Unwrap() error { return e.Err }
+
+// Issue is one way a structured answer fails its schema.
+type Issue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// InvalidAnswerError marks a structured answer that does not match its
+// output schema.
+type InvalidAnswerError struct {
+	Err    error
+	Issues []Issue
+}
+
+func (e *InvalidAnswerError) Error() string { return e.Err.Error() }
+func (e *InvalidAnswerError) Unwrap() error { return e.Err }
+
+// Errors from packages below output mark themselves with these methods
+// rather than being imported here.
+type (
+	authRequired    interface{ AuthRequired() bool }
+	profileNotFound interface{ ProfileNotFound() bool }
 )
 
+// Classify converts err into a structured CLIError.
+func Classify(err error) CLIError {
+	return toStructuredError(err)
+}
+
+// ExitCode returns the process exit code for err (0 for nil).
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	return toStructuredError(err).ExitCode
+}
+
 // CLIError is a structured error for JSON output.
 type CLIError struct {
 	Code        string `json:"code"`
+	ExitCode    int    `json:"exitCode"`
 	Message     string `json:"message"`
 	Recoverable bool   `json:"recoverable"`
 	Suggestion  string `json:"suggestion,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	APICode     string `json:"apiCode,omitempty"`
+	RequestID   string `json:"requestId,omitempty"`
+
+	Issues []Issue `json:"issues,omitempty"` // Schema mismatches in a structured answer
 }
 
 // RenderError outputs an error in the appropriate format.
 func RenderError(err error, opts Options) {
-	if opts.Mode == ModeJSON {
+	if opts.Mode.IsJSON() {
 		cliErr := toStructuredError(err)
 		_ = json.NewEncoder(os.Stderr).Encode(cliErr)
 	} else {
@@ -28,39 +103,131 @@ func RenderError(err error, opts Options) {
 func toStructuredError(err error) CLIError {
 	msg := err.Error()
 
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		return fromAPIError(apiErr)
+	}
+
+	var usageErr *UsageError
+	var schemaErr *InvalidAnswerError
+	var noKey authRequired
+	var noProfile profileNotFound
 	switch {
-	case strings.Contains(msg, "EXA_API_KEY"):
+	case errors.As(err, &usageErr):
 		return CLIError{
-			Code:        "AUTH_REQUIRED",
+			Code:        "USAGE_ERROR",
+			ExitCode:    ExitUsage,
 			Message:     msg,
 			Recoverable: true,
-			Suggestion:  "Set EXA_API_KEY environment variable or run 'exa auth'",
+			Suggestion:  "Run with --help for usage",
+		}
+	case errors.As(err, &schemaErr):
+		return CLIError{
+			Code:        "INVALID_ANSWER",
+			ExitCode:    ExitServer,
+			Message:     msg,
+			Recoverable: true,
+			Suggestion:  "Retry with --retry-on-invalid, or loosen the schema",
+			Issues:      schemaErr.Issues,
+		}
+	case errors.Is(err, ErrNoResults):
+		return CLIError{
+			Code:        "NO_RESULTS",
+			ExitCode:    ExitNoResults,
+			Message:     msg,
+			Recoverable: true,
+			Suggestion:  "Broaden the query or relax filters",
+		}
+	case errors.Is(err, ErrBudgetExceeded):
+		return CLIError{
+			Code:       "BUDGET_EXCEEDED",
+			ExitCode:   ExitBudgetExceeded,
+			Message:    msg,
+			Suggestion: "Raise the spending limit or wait for the budget period to reset",
+		}
+	case errors.As(err, &noProfile) && noProfile.ProfileNotFound():
+		return CLIError{
+			Code:        "PROFILE_NOT_FOUND",
+			ExitCode:    ExitUsage,
+			Message:     msg,
+			Recoverable: true,
+			Suggestion:  "Run 'exa auth list' to see profiles or 'exa auth add <name>' to create one",
 		}
-	case strings.Contains(msg, "status 401"):
+	case errors.As(err, &noKey) && noKey.AuthRequired():
 		return CLIError{
-			Code:        "AUTH_INVALID",
-			Message:     "Invalid API key",
+			Code:        "AUTH_REQUIRED",
+			ExitCode:    ExitAuthRequired,
+			Message:     msg,
 			Recoverable: true,
-			Suggestion:  "Check your EXA_API_KEY value",
+			Suggestion:  "Set EXA_API_KEY environment variable or run 'exa auth'",
 		}
-	case strings.Contains(msg, "status 429"):
+	case errors.Is(err, api.ErrTimeout):
 		return CLIError{
-			Code:        "RATE_LIMITED",
+			Code:        "TIMEOUT",
+			ExitCode:    ExitNetwork,
 			Message:     msg,
 			Recoverable: true,
-			Suggestion:  "Wait and retry, or reduce request frequency",
+			Suggestion:  "Retry, or increase --retries",
 		}
-	case strings.Contains(msg, "request failed"):
+	case errors.Is(err, api.ErrNetwork):
 		return CLIError{
 			Code:        "NETWORK_ERROR",
+			ExitCode:    ExitNetwork,
 			Message:     msg,
 			Recoverable: true,
 			Suggestion:  "Check network connectivity",
 		}
+	case errors.Is(err, api.ErrDecode):
+		return CLIError{
+			Code:     "DECODE_ERROR",
+			ExitCode: ExitServer,
+			Message:  msg,
+		}
 	default:
 		return CLIError{
-			Code:    "UNKNOWN",
-			Message: msg,
+			Code:     "UNKNOWN",
+			ExitCode: ExitError,
+			Message:  msg,
 		}
 	}
 }
+
+func fromAPIError(e *api.APIError) CLIError {
+	cliErr := CLIError{
+		Message:     e.Error(),
+		Recoverable: e.Retryable,
+		Status:      e.StatusCode,
+		APICode:     e.Code,
+		RequestID:   e.RequestID,
+	}
+
+	switch {
+	case e.StatusCode == 403:
+		cliErr.Code = "AUTH_FORBIDDEN"
+		cliErr.ExitCode = ExitAuthInvalid
+		cliErr.Recoverable = true
+		cliErr.Suggestion = "The API key is valid but not permitted to make this request; check its permissions in the Exa dashboard"
+	case e.StatusCode == 401:
+		cliErr.Code = "AUTH_INVALID"
+		cliErr.ExitCode = ExitAuthInvalid
+		cliErr.Message = "Invalid API key"
+		if e.Message != "" {
+			cliErr.Message = e.Message
+		}
+		cliErr.Recoverable = true
+		cliErr.Suggestion = "Check your EXA_API_KEY value"
+	case e.StatusCode == 429:
+		cliErr.Code = "RATE_LIMITED"
+		cliErr.ExitCode = ExitRateLimited
+		cliErr.Suggestion = "Wait and retry, or reduce request frequency"
+	case e.StatusCode >= 500:
+		cliErr.Code = "SERVER_ERROR"
+		cliErr.ExitCode = ExitServer
+		cliErr.Suggestion = "Retry later; Exa may be experiencing issues"
+	default:
+		cliErr.Code = "API_ERROR"
+		cliErr.ExitCode = ExitUsage
+		cliErr.Suggestion = "Check the request parameters"
+	}
+	return cliErr
+}
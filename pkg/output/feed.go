@@ -0,0 +1,314 @@
+package output
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+// Feed is a syndication feed of results.
+type Feed struct {
+	Title   string
+	ID      string // Stable IRI identifying the feed
+	Link    string
+	Updated time.Time
+	Entries []FeedEntry
+}
+
+// FeedEntry is one item of a Feed.
+type FeedEntry struct {
+	ID        string
+	Title     string
+	URL       string
+	Author    string
+	Summary   string
+	Category  string
+	Image     string
+	Published time.Time
+	Updated   time.Time
+}
+
+// FeedEntries converts results into feed entries. Entries are dated by
+// their publish date, else by when their URL was first seen, so feed
+// readers do not announce them again on every run, else now.
+func FeedEntries(results []api.SearchResult, category string, seen map[string]time.Time, now time.Time) []FeedEntry {
+	entries := make([]FeedEntry, 0, len(results))
+	for _, r := range results {
+		e := FeedEntry{
+			ID:       EntryID(r),
+			Title:    r.Title,
+			URL:      r.URL,
+			Author:   r.Author,
+			Summary:  r.Summary,
+			Category: category,
+			Image:    r.Image,
+			Updated:  now,
+		}
+		if e.Title == "" {
+			e.Title = r.URL
+		}
+		if e.Summary == "" {
+			e.Summary = strings.Join(r.Highlights, " … ")
+		}
+		if t, err := time.Parse(time.RFC3339, r.PublishedDate); err == nil {
+			e.Published = t
+			e.Updated = t
+		} else if t, ok := seen[r.URL]; ok && !t.IsZero() {
+			e.Updated = t
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// EntryID returns a stable feed entry ID for a result, derived from its Exa
+// document ID. IDs that are not absolute IRIs are wrapped in a urn:exa: URN.
+func EntryID(r api.SearchResult) string {
+	id := r.ID
+	if id == "" {
+		id = r.URL
+	}
+	if u, err := url.Parse(id); err == nil && u.IsAbs() {
+		return id
+	}
+	return "urn:exa:" + url.PathEscape(id)
+}
+
+// FeedID returns a stable feed ID derived from the feed title.
+func FeedID(title string) string {
+	sum := sha1.Sum([]byte(title))
+	return "urn:exa-cli:feed:" + hex.EncodeToString(sum[:8])
+}
+
+// renderFeed writes search or findSimilar results as an Atom or RSS feed
+// titled by opts.FeedTitle.
+func renderFeed(w io.Writer, data interface{}, opts Options) error {
+	var results []api.SearchResult
+	switch resp := data.(type) {
+	case *api.SearchResponse:
+		results = resp.Results
+	case *api.FindSimilarResponse:
+		results = resp.Results
+	default:
+		return &UsageError{Err: fmt.Errorf("feed output is only supported for search results")}
+	}
+
+	now := time.Now()
+	title := opts.FeedTitle
+	if title == "" {
+		title = "Exa results"
+	}
+	f := Feed{
+		Title:   title,
+		ID:      FeedID(title),
+		Link:    opts.FeedLink,
+		Updated: now,
+		Entries: FeedEntries(results, "", opts.FeedSeen, now),
+	}
+	if opts.Mode == ModeRSS {
+		return WriteRSS(w, f)
+	}
+	return WriteAtom(w, f)
+}
+
+// mediaContent is a Media RSS element, used for result images in both feed
+// formats.
+type mediaContent struct {
+	XMLName xml.Name `xml:"http://search.yahoo.com/mrss/ content"`
+	URL     string   `xml:"url,attr"`
+	Medium  string   `xml:"medium,attr,omitempty"`
+}
+
+func newMediaImage(src string) *mediaContent {
+	if src == "" {
+		return nil
+	}
+	return &mediaContent{URL: src, Medium: "image"}
+}
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Link      *atomLink   `xml:"link,omitempty"`
+	Author    atomPerson  `xml:"author"`
+	Generator string      `xml:"generator,omitempty"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title     string        `xml:"title"`
+	ID        string        `xml:"id"`
+	Link      atomLink      `xml:"link"`
+	Updated   string        `xml:"updated"`
+	Published string        `xml:"published,omitempty"`
+	Author    *atomPerson   `xml:"author,omitempty"`
+	Category  *atomCategory `xml:"category,omitempty"`
+	Summary   string        `xml:"summary,omitempty"`
+	Image     *mediaContent
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// WriteAtom writes f as an Atom 1.0 document.
+func WriteAtom(w io.Writer, f Feed) error {
+	doc := atomFeed{
+		Title:     f.Title,
+		ID:        f.ID,
+		Updated:   f.Updated.UTC().Format(time.RFC3339),
+		Author:    atomPerson{Name: "exa-cli"},
+		Generator: "exa-cli",
+	}
+	if f.Link != "" {
+		doc.Link = &atomLink{Href: f.Link}
+	}
+	for _, e := range f.Entries {
+		ae := atomEntry{
+			Title:   e.Title,
+			ID:      e.ID,
+			Link:    atomLink{Href: e.URL},
+			Updated: e.Updated.UTC().Format(time.RFC3339),
+			Summary: e.Summary,
+			Image:   newMediaImage(e.Image),
+		}
+		if !e.Published.IsZero() {
+			ae.Published = e.Published.UTC().Format(time.RFC3339)
+		}
+		if e.Author != "" {
+			ae.Author = &atomPerson{Name: e.Author}
+		}
+		if e.Category != "" {
+			ae.Category = &atomCategory{Term: e.Category}
+		}
+		doc.Entries = append(doc.Entries, ae)
+	}
+
+	return writeXML(w, doc)
+}
+
+// writeXML writes doc as an indented XML document.
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadAtom parses an Atom document written by WriteAtom.
+func ReadAtom(r io.Reader) (Feed, error) {
+	var doc atomFeed
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return Feed{}, err
+	}
+	f := Feed{Title: doc.Title, ID: doc.ID}
+	f.Updated, _ = time.Parse(time.RFC3339, doc.Updated)
+	if doc.Link != nil {
+		f.Link = doc.Link.Href
+	}
+	for _, ae := range doc.Entries {
+		e := FeedEntry{ID: ae.ID, Title: ae.Title, URL: ae.Link.Href, Summary: ae.Summary}
+		e.Updated, _ = time.Parse(time.RFC3339, ae.Updated)
+		e.Published, _ = time.Parse(time.RFC3339, ae.Published)
+		if ae.Author != nil {
+			e.Author = ae.Author.Name
+		}
+		if ae.Category != nil {
+			e.Category = ae.Category.Term
+		}
+		if ae.Image != nil {
+			e.Image = ae.Image.URL
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f, nil
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+	Creator     string  `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Category    string  `xml:"category,omitempty"`
+	Description string  `xml:"description,omitempty"`
+	Image       *mediaContent
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// WriteRSS writes f as an RSS 2.0 document. RSS requires a channel link, so
+// feeds without one link to exa.ai.
+func WriteRSS(w io.Writer, f Feed) error {
+	link := f.Link
+	if link == "" {
+		link = "https://exa.ai"
+	}
+	doc := rssDoc{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          link,
+			Description:   f.Title,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Generator:     "exa-cli",
+		},
+	}
+	for _, e := range f.Entries {
+		item := rssItem{
+			Title:       e.Title,
+			Link:        e.URL,
+			GUID:        rssGUID{IsPermaLink: e.ID == e.URL, Value: e.ID},
+			Creator:     e.Author,
+			Category:    e.Category,
+			Description: e.Summary,
+			Image:       newMediaImage(e.Image),
+		}
+		if !e.Published.IsZero() {
+			item.PubDate = e.Published.UTC().Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return writeXML(w, doc)
+}
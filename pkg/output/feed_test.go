@@ -0,0 +1,91 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func TestAtom_RoundTrip(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	results := []api.SearchResult{
+		{Title: "Go 1.30 <released>", URL: "https://go.dev/blog/go1.30", Author: "Go Team", PublishedDate: "2026-03-09T00:00:00.000Z", Summary: "A & B"},
+		{URL: "https://example.com/untitled"},
+	}
+	feed := Feed{Title: "exa watch: go", ID: "urn:exa-cli:watch:go", Updated: now, Entries: FeedEntries(results, "go", nil, now)}
+
+	var buf bytes.Buffer
+	if err := WriteAtom(&buf, feed); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`<feed xmlns="http://www.w3.org/2005/Atom">`, "Go 1.30 &lt;released&gt;", `<category term="go">`, "<published>2026-03-09T00:00:00Z</published>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("atom output missing %q:\n%s", want, out)
+		}
+	}
+
+	got, err := ReadAtom(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Entries) != 2 || got.Entries[0].Summary != "A & B" || got.Entries[1].Title != "https://example.com/untitled" {
+		t.Errorf("round trip = %+v", got.Entries)
+	}
+	if !got.Updated.Equal(now) || got.ID != feed.ID {
+		t.Errorf("feed metadata = %+v", got)
+	}
+}
+
+func TestFeedEntries_Dates(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	firstSeen := now.Add(-48 * time.Hour)
+	results := []api.SearchResult{
+		{URL: "https://a.example", PublishedDate: "2026-03-09T00:00:00.000Z"},
+		{URL: "https://b.example"},
+		{URL: "https://c.example"},
+	}
+	seen := map[string]time.Time{"https://a.example": firstSeen, "https://b.example": firstSeen}
+	entries := FeedEntries(results, "", seen, now)
+	want := []time.Time{time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), firstSeen, now}
+	for i, e := range entries {
+		if !e.Updated.Equal(want[i]) {
+			t.Errorf("%s: updated %v, want %v", e.URL, e.Updated, want[i])
+		}
+	}
+}
+
+func TestRSS(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	results := []api.SearchResult{
+		{ID: "https://go.dev/blog", URL: "https://go.dev/blog", Title: "Blog", Image: "https://go.dev/img.png", PublishedDate: "2026-03-09T00:00:00.000Z"},
+		{ID: "doc 42", URL: "https://example.com/42", Highlights: []string{"one", "two"}},
+	}
+	var buf bytes.Buffer
+	if err := WriteRSS(&buf, Feed{Title: "t", Updated: now, Entries: FeedEntries(results, "", nil, now)}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<rss version="2.0">`,
+		"<link>https://exa.ai</link>",
+		`<guid isPermaLink="true">https://go.dev/blog</guid>`,
+		`<guid isPermaLink="false">urn:exa:doc%2042</guid>`,
+		"<pubDate>Mon, 09 Mar 2026 00:00:00 +0000</pubDate>",
+		`url="https://go.dev/img.png" medium="image"`,
+		"<description>one … two</description>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rss output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFeedID_Stable(t *testing.T) {
+	if FeedID("Exa search: go") != FeedID("Exa search: go") || FeedID("a") == FeedID("b") {
+		t.Error("FeedID should be deterministic and distinct per title")
+	}
+}
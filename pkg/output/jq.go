@@ -3,6 +3,7 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/itchyny/gojq"
@@ -10,6 +11,11 @@ import (
 
 // RunJQ applies a jq expression to data and prints results to stdout.
 func RunJQ(data interface{}, expr string) error {
+	return runJQ(os.Stdout, data, expr, false)
+}
+
+// runJQ writes each jq result to w, pretty-printed or one per line.
+func runJQ(w io.Writer, data interface{}, expr string, compact bool) error {
 	query, err := gojq.Parse(expr)
 	if err != nil {
 		return fmt.Errorf("invalid jq expression: %w", err)
@@ -36,11 +42,16 @@ func RunJQ(data interface{}, expr string) error {
 			return fmt.Errorf("jq error: %w", err)
 		}
 
-		out, err := json.MarshalIndent(v, "", "  ")
+		var out []byte
+		if compact {
+			out, err = json.Marshal(v)
+		} else {
+			out, err = json.MarshalIndent(v, "", "  ")
+		}
 		if err != nil {
 			return fmt.Errorf("marshal jq result: %w", err)
 		}
-		fmt.Fprintln(os.Stdout, string(out))
+		fmt.Fprintln(w, string(out))
 	}
 
 	return nil
@@ -0,0 +1,203 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+// renderMarkdown writes data as a markdown document. Responses without a
+// dedicated layout are written as a markdown table built from td.
+func renderMarkdown(w io.Writer, td TableData, data interface{}) error {
+	var b strings.Builder
+	switch resp := data.(type) {
+	case *api.SearchResponse:
+		markdownResults(&b, "# Search results", resp.Results)
+	case *api.FindSimilarResponse:
+		markdownResults(&b, "# Similar pages", resp.Results)
+	case *Digest:
+		markdownDigest(&b, resp)
+	case *api.ContentsResponse:
+		markdownContents(&b, resp.Results)
+	case *api.AnswerResponse:
+		markdownAnswer(&b, resp)
+	case *api.ContextResponse:
+		b.WriteString(strings.TrimSpace(resp.Context))
+		b.WriteString("\n")
+	default:
+		markdownTable(&b, td)
+	}
+
+	footer := td.Footer
+	if resp, ok := data.(*api.AnswerResponse); ok && resp.CostDollars != nil {
+		footer = fmt.Sprintf("Cost: $%.4f", resp.CostDollars.Total)
+	}
+	if footer != "" {
+		fmt.Fprintf(&b, "\n---\n\n*%s*\n", strings.ReplaceAll(footer, " | ", " · "))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownResults writes a heading line and search-style results as a
+// numbered list of linked titles with their metadata, summary and
+// highlights.
+func markdownResults(b *strings.Builder, heading string, results []api.SearchResult) {
+	b.WriteString(heading + "\n")
+	for i, r := range results {
+		fmt.Fprintf(b, "\n%d. %s\n", i+1, markdownLink(r.Title, r.URL))
+		if meta := resultMeta(r, true); meta != "" {
+			fmt.Fprintf(b, "   *%s*\n", meta)
+		}
+		if r.Summary != "" {
+			fmt.Fprintf(b, "\n   %s\n", oneLine(r.Summary))
+		}
+		for _, h := range r.Highlights {
+			fmt.Fprintf(b, "\n   > %s\n", oneLine(h))
+		}
+	}
+}
+
+// Digest groups results under headings, such as one section per saved
+// search.
+type Digest struct {
+	Title    string          `json:"title"`
+	Sections []DigestSection `json:"sections"`
+}
+
+// DigestSection is one group of a Digest.
+type DigestSection struct {
+	Heading string             `json:"heading"`
+	Results []api.SearchResult `json:"results"`
+}
+
+// markdownDigest writes one level-two section per digest group.
+func markdownDigest(b *strings.Builder, d *Digest) {
+	fmt.Fprintf(b, "# %s\n", d.Title)
+	for _, s := range d.Sections {
+		b.WriteString("\n")
+		if len(s.Results) == 0 {
+			fmt.Fprintf(b, "## %s\n\n_No new results._\n", s.Heading)
+			continue
+		}
+		markdownResults(b, "## "+s.Heading, s.Results)
+	}
+}
+
+// markdownContents writes one section per page with its full text.
+func markdownContents(b *strings.Builder, results []api.SearchResult) {
+	b.WriteString("# Page contents\n")
+	for _, r := range results {
+		fmt.Fprintf(b, "\n## %s\n", markdownLink(r.Title, r.URL))
+		if meta := resultMeta(r, false); meta != "" {
+			fmt.Fprintf(b, "\n*%s*\n", meta)
+		}
+		if r.Summary != "" {
+			fmt.Fprintf(b, "\n### Summary\n\n%s\n", strings.TrimSpace(r.Summary))
+		}
+		if len(r.Highlights) > 0 {
+			b.WriteString("\n### Highlights\n\n")
+			for _, h := range r.Highlights {
+				fmt.Fprintf(b, "- %s\n", oneLine(h))
+			}
+		}
+		if r.Text != "" {
+			fmt.Fprintf(b, "\n### Text\n\n%s\n", strings.TrimSpace(r.Text))
+		}
+	}
+}
+
+// markdownAnswer writes the answer followed by its citations as numbered
+// footnotes.
+func markdownAnswer(b *strings.Builder, resp *api.AnswerResponse) {
+	b.WriteString(strings.TrimSpace(resp.Answer))
+	for i := range resp.Citations {
+		fmt.Fprintf(b, "[^%d]", i+1)
+	}
+	b.WriteString("\n")
+	if len(resp.Citations) == 0 {
+		return
+	}
+
+	b.WriteString("\n")
+	for i, c := range resp.Citations {
+		fmt.Fprintf(b, "[^%d]: %s", i+1, markdownLink(c.Title, c.URL))
+		if d := shortDate(c.PublishedDate); d != "" {
+			fmt.Fprintf(b, " (%s)", d)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// markdownTable writes td as a GitHub-flavored markdown table.
+func markdownTable(b *strings.Builder, td TableData) {
+	if len(td.Headers) == 0 {
+		return
+	}
+	row := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = strings.ReplaceAll(oneLine(c), "|", `\|`)
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(escaped, " | "))
+	}
+	row(td.Headers)
+	sep := make([]string, len(td.Headers))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	fmt.Fprintf(b, "| %s |\n", strings.Join(sep, " | "))
+	for _, r := range td.Rows {
+		row(r)
+	}
+}
+
+func resultMeta(r api.SearchResult, score bool) string {
+	var parts []string
+	if d := shortDate(r.PublishedDate); d != "" {
+		parts = append(parts, d)
+	}
+	if r.Author != "" {
+		parts = append(parts, MarkdownEscape(r.Author))
+	}
+	if score && r.Score > 0 {
+		parts = append(parts, fmt.Sprintf("score %.3f", r.Score))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// markdownLink formats a link, falling back to the URL for untitled pages.
+func markdownLink(title, url string) string {
+	if strings.TrimSpace(title) == "" {
+		title = url
+	}
+	if strings.ContainsAny(url, " ()<>") {
+		url = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return fmt.Sprintf("[%s](%s)", MarkdownEscape(oneLine(title)), url)
+}
+
+// MarkdownEscape backslash-escapes characters that markdown would otherwise
+// interpret as formatting.
+func MarkdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "|", `\|`, "#", `\#`,
+)
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func shortDate(s string) string {
+	if len(s) >= 10 {
+		return s[:10]
+	}
+	return s
+}
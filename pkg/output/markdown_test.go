@@ -0,0 +1,65 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func TestRenderMarkdown_Search(t *testing.T) {
+	resp := &api.SearchResponse{Results: []api.SearchResult{{
+		Title:         "Go [generics]",
+		URL:           "https://go.dev/doc/(tutorial)",
+		PublishedDate: "2024-03-01T00:00:00.000Z",
+		Score:         0.5,
+		Summary:       "A\nsummary",
+		Highlights:    []string{"first highlight"},
+	}}}
+
+	var buf bytes.Buffer
+	if err := renderMarkdown(&buf, TableData{Footer: "Cost: $0.0050 | 1 results"}, resp); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Search results\n",
+		`1. [Go \[generics\]](<https://go.dev/doc/(tutorial)>)`,
+		"*2024-03-01 · score 0.500*",
+		"   A summary\n",
+		"   > first highlight\n",
+		"*Cost: $0.0050 · 1 results*",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderMarkdown_AnswerFootnotes(t *testing.T) {
+	resp := &api.AnswerResponse{
+		Answer:    "Paris.",
+		Citations: []api.SearchResult{{Title: "Paris", URL: "https://a"}, {URL: "https://b"}},
+	}
+	var buf bytes.Buffer
+	if err := renderMarkdown(&buf, TableData{}, resp); err != nil {
+		t.Fatal(err)
+	}
+	want := "Paris.[^1][^2]\n\n[^1]: [Paris](https://a)\n[^2]: [https://b](https://b)\n"
+	if buf.String() != want {
+		t.Errorf("got\n%q\nwant\n%q", buf.String(), want)
+	}
+}
+
+func TestRenderMarkdown_TableFallback(t *testing.T) {
+	td := TableData{Headers: []string{"DATE", "NOTE"}, Rows: [][]string{{"2025-01-01", "a|b"}}}
+	var buf bytes.Buffer
+	if err := renderMarkdown(&buf, td, &api.UsageResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "| DATE | NOTE |\n| --- | --- |\n| 2025-01-01 | a\\|b |\n"
+	if buf.String() != want {
+		t.Errorf("got\n%q\nwant\n%q", buf.String(), want)
+	}
+}
@@ -0,0 +1,39 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+// recordArrayKeys are the top-level arrays whose elements are rendered as
+// one NDJSON line or CSV row each, checked in order.
+var recordArrayKeys = []string{"results", "citations", "usage", "apiKeys", "groups"}
+
+// Event is a typed NDJSON record emitted while a response streams in.
+type Event struct {
+	Type        string        `json:"type"` // text, citations, cost or done
+	Text        string        `json:"text,omitempty"`
+	Citations   interface{}   `json:"citations,omitempty"`
+	CostDollars *api.CostInfo `json:"costDollars,omitempty"`
+}
+
+// WriteEvent writes a single streaming event as one NDJSON line on stdout.
+func WriteEvent(ev Event) error {
+	return json.NewEncoder(os.Stdout).Encode(ev)
+}
+
+// renderNDJSON writes one compact JSON object per item: each element of a
+// list, or of the document's result array, or the document itself.
+func renderNDJSON(w io.Writer, data interface{}) error {
+	enc := json.NewEncoder(w)
+	items, _ := records(data)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
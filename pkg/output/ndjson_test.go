@@ -0,0 +1,43 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func TestRenderNDJSON_SplitsResults(t *testing.T) {
+	resp := &api.SearchResponse{
+		RequestID: "r1",
+		Results:   []api.SearchResult{{Title: "A", URL: "https://a"}, {Title: "B", URL: "https://b"}},
+	}
+	var buf bytes.Buffer
+	if err := renderNDJSON(&buf, resp); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"url":"https://a"`) || !strings.Contains(lines[1], `"url":"https://b"`) {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestRenderNDJSON_FilteredAndScalarDocuments(t *testing.T) {
+	var buf bytes.Buffer
+	filtered := FilterFields(&api.SearchResponse{Results: []api.SearchResult{{Title: "A", URL: "https://a"}}}, "url")
+	if err := renderNDJSON(&buf, filtered); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != `{"url":"https://a"}` {
+		t.Errorf("filtered = %s", got)
+	}
+
+	buf.Reset()
+	if err := renderNDJSON(&buf, map[string]string{"query": "q"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != `{"query":"q"}` {
+		t.Errorf("document = %s", got)
+	}
+}
@@ -3,8 +3,10 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
@@ -17,15 +19,82 @@ const (
 	ModeTable     Mode = iota // Default: colored table
 	ModePlaintext             // Tab-separated, no colors
 	ModeJSON                  // Pretty-printed JSON
+	ModeNDJSON                // One compact JSON object per line
+	ModeCSV                   // RFC 4180 comma-separated values
+	ModeTSV                   // Tab-separated values with CSV quoting
+	ModeMarkdown              // Markdown document
+	ModeTemplate              // Go text/template from Options.Template
+	ModeAtom                  // Atom 1.0 feed of results
+	ModeRSS                   // RSS 2.0 feed of results
+	ModeBibTeX                // BibTeX entries for results or citations
+	ModeRIS                   // RIS records for results or citations
+	ModeCSLJSON               // CSL-JSON items for results or citations
 )
 
+// modeNames maps --format values to modes.
+var modeNames = map[string]Mode{
+	"table":     ModeTable,
+	"plaintext": ModePlaintext,
+	"json":      ModeJSON,
+	"ndjson":    ModeNDJSON,
+	"csv":       ModeCSV,
+	"tsv":       ModeTSV,
+	"markdown":  ModeMarkdown,
+	"md":        ModeMarkdown,
+	"atom":      ModeAtom,
+	"rss":       ModeRSS,
+	"bibtex":    ModeBibTeX,
+	"ris":       ModeRIS,
+	"csljson":   ModeCSLJSON,
+	"csl-json":  ModeCSLJSON,
+}
+
+// ParseMode returns the mode named by a --format value.
+func ParseMode(name string) (Mode, error) {
+	if m, ok := modeNames[strings.ToLower(name)]; ok {
+		return m, nil
+	}
+	return 0, fmt.Errorf("unknown format %q (want table, plaintext, json, ndjson, csv, tsv, markdown, atom, rss, bibtex, ris or csljson)", name)
+}
+
+// IsJSON reports whether the mode writes JSON to stdout.
+func (m Mode) IsJSON() bool {
+	return m == ModeJSON || m == ModeNDJSON
+}
+
+// IsHuman reports whether the mode is meant for a terminal, where commands
+// may print extra text around the rendered table.
+func (m Mode) IsHuman() bool {
+	return m == ModeTable || m == ModePlaintext
+}
+
+// IsDelimited reports whether the mode writes CSV or TSV.
+func (m Mode) IsDelimited() bool {
+	return m == ModeCSV || m == ModeTSV
+}
+
+// IsFeed reports whether the mode writes an Atom or RSS feed.
+func (m Mode) IsFeed() bool {
+	return m == ModeAtom || m == ModeRSS
+}
+
+// IsCitation reports whether the mode writes reference manager entries.
+func (m Mode) IsCitation() bool {
+	return m == ModeBibTeX || m == ModeRIS || m == ModeCSLJSON
+}
+
 // Options controls output rendering.
 type Options struct {
-	Mode    Mode
-	NoColor bool
-	Debug   bool
-	Fields  string
-	JQ      string
+	Mode      Mode
+	NoColor   bool
+	Debug     bool
+	Fields    string
+	JQ        string
+	NoHeader  bool                 // Omit the header row in CSV/TSV output
+	Template  string               // text/template source for ModeTemplate
+	FeedTitle string               // Feed title for ModeAtom and ModeRSS
+	FeedLink  string               // Feed link for ModeAtom and ModeRSS
+	FeedSeen  map[string]time.Time // URL → first seen, dates undated feed entries
 }
 
 // TableData holds rows and headers for table rendering.
@@ -37,50 +106,76 @@ type TableData struct {
 
 // RenderTable renders data in the appropriate output mode.
 func RenderTable(td TableData, data interface{}, opts Options) error {
+	return Render(os.Stdout, td, data, opts)
+}
+
+// Render renders data to w in the appropriate output mode.
+func Render(w io.Writer, td TableData, data interface{}, opts Options) error {
 	switch opts.Mode {
-	case ModeJSON:
-		return renderJSONOutput(data, opts)
+	case ModeJSON, ModeNDJSON:
+		return renderJSONOutput(w, data, opts)
+	case ModeCSV, ModeTSV:
+		return renderDelimited(w, td, data, opts)
+	case ModeMarkdown:
+		return renderMarkdown(w, td, data)
+	case ModeTemplate:
+		return renderTemplate(w, data, opts.Template)
+	case ModeAtom, ModeRSS:
+		return renderFeed(w, data, opts)
+	case ModeBibTeX, ModeRIS, ModeCSLJSON:
+		return renderCitations(w, data, opts.Mode)
 	case ModePlaintext:
-		return renderPlaintext(os.Stdout, td)
+		return renderPlaintext(w, td)
 	default:
-		return renderTable(os.Stdout, td, opts)
+		return renderTable(w, td, opts)
 	}
 }
 
 // RenderJSON outputs raw data as JSON.
 func RenderJSON(data interface{}, opts Options) error {
-	return renderJSONOutput(data, opts)
+	return renderJSONOutput(os.Stdout, data, opts)
 }
 
-func renderJSONOutput(data interface{}, opts Options) error {
+func renderJSONOutput(w io.Writer, data interface{}, opts Options) error {
 	data = FilterFields(data, opts.Fields)
 
 	if opts.JQ != "" {
-		return RunJQ(data, opts.JQ)
+		return runJQ(w, data, opts.JQ, opts.Mode == ModeNDJSON)
+	}
+
+	if opts.Mode == ModeNDJSON {
+		return renderNDJSON(w, data)
 	}
 
 	out, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("json marshal: %w", err)
 	}
-	_, err = fmt.Fprintln(os.Stdout, string(out))
+	_, err = fmt.Fprintln(w, string(out))
 	return err
 }
 
-func renderPlaintext(w *os.File, td TableData) error {
+func renderPlaintext(w io.Writer, td TableData) error {
 	if len(td.Headers) > 0 {
 		fmt.Fprintln(w, strings.Join(td.Headers, "\t"))
 	}
 	for _, row := range td.Rows {
-		fmt.Fprintln(w, strings.Join(row, "\t"))
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = plaintextCell.Replace(c)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
 	}
 	return nil
 }
 
-func renderTable(w *os.File, td TableData, opts Options) error {
+// plaintextCell keeps a cell on one line and in one column.
+var plaintextCell = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+func renderTable(w io.Writer, td TableData, opts Options) error {
 	table := tablewriter.NewWriter(w)
 
-	if shouldColor(opts) {
+	if shouldColor(w, opts) {
 		colored := make([]string, len(td.Headers))
 		for i, h := range td.Headers {
 			colored[i] = color.New(color.FgCyan, color.Bold).Sprint(h)
@@ -108,7 +203,7 @@ func renderTable(w *os.File, td TableData, opts Options) error {
 	table.Render()
 
 	if td.Footer != "" {
-		if shouldColor(opts) {
+		if shouldColor(w, opts) {
 			fmt.Fprintf(w, "\n%s\n", color.New(color.FgHiBlack).Sprint(td.Footer))
 		} else {
 			fmt.Fprintf(w, "\n%s\n", td.Footer)
@@ -118,14 +213,20 @@ func renderTable(w *os.File, td TableData, opts Options) error {
 	return nil
 }
 
-func shouldColor(opts Options) bool {
+// shouldColor reports whether output to w should be colored: only when w
+// is a terminal.
+func shouldColor(w io.Writer, opts Options) bool {
 	if opts.NoColor {
 		return false
 	}
 	if os.Getenv("NO_COLOR") != "" {
 		return false
 	}
-	fi, err := os.Stdout.Stat()
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
 	if err != nil {
 		return false
 	}
@@ -134,8 +235,8 @@ func shouldColor(opts Options) bool {
 
 // Error outputs an error message respecting the output mode.
 func Error(message string, opts Options) {
-	switch opts.Mode {
-	case ModeJSON:
+	switch {
+	case opts.Mode.IsJSON():
 		_ = json.NewEncoder(os.Stderr).Encode(map[string]string{"error": message})
 	default:
 		fmt.Fprintf(os.Stderr, "%s %s\n", color.New(color.FgRed).Sprint("Error:"), message)
@@ -144,8 +245,8 @@ func Error(message string, opts Options) {
 
 // Success outputs a success message respecting the output mode.
 func Success(message string, opts Options) {
-	switch opts.Mode {
-	case ModeJSON:
+	switch {
+	case opts.Mode.IsJSON():
 		_ = json.NewEncoder(os.Stderr).Encode(map[string]string{"status": "success", "message": message})
 	default:
 		fmt.Printf("%s %s\n", color.New(color.FgGreen).Sprint("OK:"), message)
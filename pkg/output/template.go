@@ -0,0 +1,160 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are the helpers available to --template. Functions take the
+// value last so they work in pipelines: {{.Title | truncate 40}}.
+var templateFuncs = template.FuncMap{
+	"truncate": templateTruncate,
+	"date":     templateDate,
+	"wrap":     templateWrap,
+	"join":     templateJoin,
+	"json":     templateJSON,
+	"mdescape": MarkdownEscape,
+}
+
+// ParseTemplate parses a --template string with the output helpers.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// CheckTemplate parses a --template string and executes it against a
+// sample of zero's type, so misspelled fields and bad function calls are
+// reported before any request is sent. zero is a pointer to the response
+// type the command renders; nil only parses.
+func CheckTemplate(text string, zero interface{}) error {
+	tmpl, err := ParseTemplate(text)
+	if err != nil || zero == nil {
+		return err
+	}
+	sample := reflect.New(reflect.TypeOf(zero).Elem())
+	fillSample(sample.Elem(), 0)
+	if err := tmpl.Execute(io.Discard, sample.Interface()); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+	return nil
+}
+
+// fillSample allocates v's pointers and gives its slices one element, so a
+// dry run reaches nested fields and range bodies.
+func fillSample(v reflect.Value, depth int) {
+	if depth > 8 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillSample(v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fillSample(v.Field(i), depth+1)
+			}
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillSample(v.Index(0), depth+1)
+	}
+}
+
+func renderTemplate(w io.Writer, data interface{}, text string) error {
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+	return nil
+}
+
+// templateTruncate shortens s to n runes, ending with "..." when cut.
+func templateTruncate(n int, s string) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(r[:n])
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// templateDate reformats an API timestamp with a Go time layout. Values
+// that cannot be parsed are returned unchanged.
+func templateDate(layout, s string) string {
+	for _, in := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000Z", "2006-01-02"} {
+		if t, err := time.Parse(in, s); err == nil {
+			return t.Format(layout)
+		}
+	}
+	return s
+}
+
+// templateWrap word-wraps s to lines of at most width characters.
+func templateWrap(width int, s string) string {
+	if width <= 0 {
+		return s
+	}
+	var b strings.Builder
+	lineLen := 0
+	for _, word := range strings.Fields(s) {
+		switch {
+		case lineLen == 0:
+		case lineLen+1+len(word) > width:
+			b.WriteByte('\n')
+			lineLen = 0
+		default:
+			b.WriteByte(' ')
+			lineLen++
+		}
+		b.WriteString(word)
+		lineLen += len(word)
+	}
+	return b.String()
+}
+
+// templateJoin joins a list with sep; non-string elements are formatted
+// with fmt.
+func templateJoin(sep string, v interface{}) (string, error) {
+	switch t := v.(type) {
+	case []string:
+		return strings.Join(t, sep), nil
+	case []interface{}:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, sep), nil
+	case []float64:
+		parts := make([]string, len(t))
+		for i, e := range t {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, sep), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("join: unsupported type %T", v)
+}
+
+// templateJSON encodes v as compact JSON.
+func templateJSON(v interface{}) (string, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,67 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func TestRenderTemplate_Helpers(t *testing.T) {
+	resp := &api.SearchResponse{
+		Results: []api.SearchResult{{
+			Title:         "A very long title indeed",
+			URL:           "https://a",
+			Score:         0.25,
+			PublishedDate: "2025-01-15T00:00:00.000Z",
+			Highlights:    []string{"one", "two"},
+		}},
+		CostDollars: &api.CostInfo{Total: 0.005},
+	}
+	tmpl := `{{range .Results}}[{{.Score}}] {{.Title | truncate 10}} <{{.URL}}> {{.PublishedDate | date "2006/01/02"}} {{.Highlights | join "+"}}{{end}} {{json .CostDollars}} {{mdescape "*x*"}}`
+
+	var buf bytes.Buffer
+	if err := renderTemplate(&buf, resp, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	want := `[0.25] A very ... <https://a> 2025/01/15 one+two {"total":0.005} \*x\*`
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestRenderTemplate_Errors(t *testing.T) {
+	if _, err := ParseTemplate("{{.Results"); err == nil {
+		t.Error("expected parse error")
+	}
+	var buf bytes.Buffer
+	if err := renderTemplate(&buf, &api.SearchResponse{}, "{{.Missing}}"); err == nil {
+		t.Error("expected execute error")
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	for _, tmpl := range []string{
+		`{{range .Results}}{{.Title | truncate 10}} {{.Highlights | join ","}}{{end}}`,
+		`{{.CostDollars.Total}}`,
+	} {
+		if err := CheckTemplate(tmpl, &api.SearchResponse{}); err != nil {
+			t.Errorf("CheckTemplate(%q): %v", tmpl, err)
+		}
+	}
+	for _, tmpl := range []string{
+		`{{.Missing}}`,
+		`{{range .Results}}{{.Titel}}{{end}}`,
+		`{{range .Results}}{{truncate .Title 5}}{{end}}`,
+	} {
+		if err := CheckTemplate(tmpl, &api.SearchResponse{}); err == nil {
+			t.Errorf("CheckTemplate(%q) should fail", tmpl)
+		}
+	}
+}
+
+func TestTemplateWrap(t *testing.T) {
+	if got := templateWrap(10, "the quick brown fox jumps"); got != "the quick\nbrown fox\njumps" {
+		t.Errorf("wrap = %q", got)
+	}
+}
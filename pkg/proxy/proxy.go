@@ -0,0 +1,245 @@
+// Package proxy serves an Exa-compatible REST API that forwards requests
+// through an api.Client, which injects the API key and applies the cache,
+// retries and spending guards. Costs are tallied per client.
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/output"
+)
+
+// Endpoints are the forwarded API paths.
+var Endpoints = []string{"/search", "/contents", "/findSimilar", "/answer", "/context"}
+
+// StatsPath serves the per-client totals as JSON.
+const StatsPath = "/_proxy/stats"
+
+// maxBodySize bounds a request body.
+const maxBodySize = 10 << 20
+
+// ClientHeader names the client a request is accounted to. Without it the
+// client's x-api-key (hashed if it looks like a real key) or address is used.
+const ClientHeader = "X-Exa-Client"
+
+// ClientStats are the totals for one client since the server started.
+type ClientStats struct {
+	Client      string  `json:"client"`
+	Requests    int     `json:"requests"`
+	CacheHits   int     `json:"cacheHits"`
+	Errors      int     `json:"errors"`
+	RateLimited int     `json:"rateLimited"`
+	CostDollars float64 `json:"costDollars"`
+}
+
+// Server forwards API requests for many clients.
+type Server struct {
+	Client  *api.Client
+	Limiter *Limiter                     // Optional per-client rate limit
+	Log     func(string, ...interface{}) // Optional access log
+
+	mu    sync.Mutex
+	stats map[string]*ClientStats
+}
+
+// Handler returns the HTTP handler serving Endpoints and StatsPath.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, endpoint := range Endpoints {
+		mux.HandleFunc("POST "+endpoint, s.forward(endpoint))
+	}
+	mux.HandleFunc("GET "+StatsPath, s.serveStats)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("no such endpoint: %s %s", r.Method, r.URL.Path))
+	})
+	return mux
+}
+
+// Stats returns the per-client totals sorted by client.
+func (s *Server) Stats() []ClientStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ClientStats, 0, len(s.stats))
+	for _, st := range s.stats {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Client < out[j].Client })
+	return out
+}
+
+// update applies fn to client's stats.
+func (s *Server) update(client string, fn func(*ClientStats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = make(map[string]*ClientStats)
+	}
+	st, ok := s.stats[client]
+	if !ok {
+		st = &ClientStats{Client: client}
+		s.stats[client] = st
+	}
+	fn(st)
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Log != nil {
+		s.Log(format, args...)
+	}
+}
+
+func (s *Server) serveStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"clients": s.Stats()})
+}
+
+// ClientName identifies the client that sent r. An x-api-key may be a
+// real key, so it is only ever recorded as a hash.
+func ClientName(r *http.Request) string {
+	if name := r.Header.Get(ClientHeader); name != "" {
+		return name
+	}
+	if key := r.Header.Get("x-api-key"); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key-" + hex.EncodeToString(sum[:4])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (s *Server) forward(endpoint string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		client := ClientName(r)
+		s.update(client, func(st *ClientStats) { st.Requests++ })
+
+		if ok, wait := s.Limiter.Allow(client); !ok {
+			s.update(client, func(st *ClientStats) { st.RateLimited++ })
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			writeJSONError(w, http.StatusTooManyRequests, "RATE_LIMITED", fmt.Sprintf("rate limit of %d requests per minute exceeded", s.Limiter.PerMinute))
+			s.logf("%s %s %d rate limited", client, endpoint, http.StatusTooManyRequests)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil || len(body) > maxBodySize || !json.Valid(body) {
+			s.update(client, func(st *ClientStats) { st.Errors++ })
+			writeJSONError(w, http.StatusBadRequest, "INVALID_REQUEST_BODY", "request body must be a JSON object")
+			return
+		}
+
+		ctx := api.WithCaller(r.Context(), client)
+		var call *api.Call
+		streamed := false
+		if endpoint == "/answer" && wantsStream(body) {
+			call, err = s.Client.ForwardAnswerStream(ctx, body, func(line string) {
+				if !streamed {
+					w.Header().Set("Content-Type", "text/event-stream")
+					w.Header().Set("Cache-Control", "no-cache")
+					w.WriteHeader(http.StatusOK)
+					streamed = true
+				}
+				_, _ = io.WriteString(w, line+"\n")
+				if f, ok := w.(http.Flusher); ok {
+					f.Flush()
+				}
+			})
+		} else {
+			call, err = s.Client.Forward(ctx, endpoint, body)
+		}
+
+		if err != nil {
+			s.update(client, func(st *ClientStats) { st.Errors++ })
+			status := http.StatusBadGateway
+			if !streamed {
+				status = writeError(w, err)
+			}
+			s.logf("%s %s %d %s (%s)", client, endpoint, status, err, time.Since(start).Round(time.Millisecond))
+			return
+		}
+
+		var cost float64
+		if call.Cost != nil && !call.Cached {
+			cost = call.Cost.Total
+		}
+		s.update(client, func(st *ClientStats) {
+			st.CostDollars += cost
+			if call.Cached {
+				st.CacheHits++
+			}
+		})
+		if !streamed {
+			w.Header().Set("Content-Type", "application/json")
+			if call.Cached {
+				w.Header().Set("X-Exa-Cache", "hit")
+			}
+			_, _ = w.Write(call.Response)
+		}
+		s.logf("%s %s 200 $%.4f cached=%t (%s)", client, endpoint, cost, call.Cached, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// wantsStream reports whether an /answer body asks for a streamed response.
+func wantsStream(body []byte) bool {
+	var req struct {
+		Stream bool `json:"stream"`
+	}
+	_ = json.Unmarshal(body, &req)
+	return req.Stream
+}
+
+// writeError writes err as a JSON error and returns the status used. API
+// errors are passed through with the upstream status and body.
+func writeError(w http.ResponseWriter, err error) int {
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) && len(apiErr.Body) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(apiErr.StatusCode)
+		_, _ = w.Write(apiErr.Body)
+		return apiErr.StatusCode
+	}
+
+	cliErr := output.Classify(err)
+	status := http.StatusInternalServerError
+	switch cliErr.ExitCode {
+	case output.ExitUsage:
+		status = http.StatusBadRequest
+	case output.ExitBudgetExceeded:
+		status = http.StatusPaymentRequired
+	case output.ExitAuthRequired:
+		status = http.StatusServiceUnavailable
+	case output.ExitNetwork:
+		status = http.StatusBadGateway
+		if errors.Is(err, api.ErrTimeout) {
+			status = http.StatusGatewayTimeout
+		}
+	}
+	if apiErr != nil {
+		status = apiErr.StatusCode
+	}
+	writeJSONError(w, status, cliErr.Code, cliErr.Message)
+	return status
+}
+
+// writeJSONError writes an error body in the API's own format, so Exa
+// clients report it like an upstream error.
+func writeJSONError(w http.ResponseWriter, status int, tag, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message, "tag": tag})
+}
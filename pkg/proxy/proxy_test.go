@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/cache"
+	"github.com/roboalchemist/exa-cli/pkg/exatest"
+)
+
+// newProxy starts a proxy in front of a fake API that only accepts key
+// "real-key".
+func newProxy(t *testing.T, limiter *Limiter) (*exatest.Server, *Server, *httptest.Server) {
+	t.Helper()
+	upstream := exatest.NewServer(exatest.WithAPIKey("real-key"))
+	t.Cleanup(upstream.Close)
+	client := api.NewClient(upstream.URL, "real-key")
+	client.SetRetryPolicy(api.RetryPolicy{MaxAttempts: 1})
+	client.SetCache(cache.New(t.TempDir(), time.Hour))
+	s := &Server{Client: client, Limiter: limiter}
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+	return upstream, s, srv
+}
+
+func post(t *testing.T, url, client, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-api-key", "client-placeholder")
+	if client != "" {
+		req.Header.Set(ClientHeader, client)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestProxy_InjectsKeyAndCaches(t *testing.T) {
+	upstream, s, srv := newProxy(t, nil)
+
+	for i, wantCache := range []string{"", "hit"} {
+		resp := post(t, srv.URL+"/search", "crawler", `{"query":"golang","numResults":2}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-Exa-Cache"); got != wantCache {
+			t.Errorf("request %d: X-Exa-Cache = %q, want %q", i, got, wantCache)
+		}
+		var out api.SearchResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		if len(out.Results) != 2 {
+			t.Errorf("request %d: got %d results, want 2", i, len(out.Results))
+		}
+	}
+
+	upstream.AssertRequestCount(t, exatest.PathSearch, 1)
+	last, _ := upstream.LastRequest(exatest.PathSearch)
+	if got := last.Header.Get("x-api-key"); got != "real-key" {
+		t.Errorf("upstream saw key %q, want the injected key", got)
+	}
+
+	stats := s.Stats()
+	if len(stats) != 1 || stats[0].Client != "crawler" || stats[0].Requests != 2 || stats[0].CacheHits != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if stats[0].CostDollars <= 0 {
+		t.Errorf("cost = %v, want the uncached call's cost", stats[0].CostDollars)
+	}
+}
+
+func TestProxy_StreamsAnswer(t *testing.T) {
+	_, s, srv := newProxy(t, nil)
+
+	resp := post(t, srv.URL+"/answer", "", `{"query":"what is go","stream":true}`)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `data: {"text":`) || !strings.HasSuffix(strings.TrimSpace(string(body)), "data: [DONE]") {
+		t.Errorf("unexpected stream:\n%s", body)
+	}
+
+	stats := s.Stats()
+	if len(stats) != 1 || !strings.HasPrefix(stats[0].Client, "key-") || stats[0].CostDollars <= 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestProxy_PassesThroughAPIErrors(t *testing.T) {
+	upstream, s, srv := newProxy(t, nil)
+	upstream.InjectFault(exatest.PathSearch, exatest.Fault{Status: http.StatusBadRequest})
+
+	resp := post(t, srv.URL+"/search", "a", `{"query":"x"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+	if s.Stats()[0].Errors != 1 {
+		t.Errorf("stats = %+v", s.Stats())
+	}
+
+	resp = post(t, srv.URL+"/search", "a", `not json`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid body: status = %d, want 400", resp.StatusCode)
+	}
+	resp = post(t, srv.URL+"/nope", "a", `{}`)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown path: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestProxy_RateLimitsPerClient(t *testing.T) {
+	_, s, srv := newProxy(t, NewLimiter(1))
+
+	if resp := post(t, srv.URL+"/context", "a", `{"query":"x"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("first request: status %d", resp.StatusCode)
+	}
+	resp := post(t, srv.URL+"/context", "a", `{"query":"y"}`)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("missing Retry-After")
+	}
+	if resp := post(t, srv.URL+"/context", "b", `{"query":"y"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("other client: status %d", resp.StatusCode)
+	}
+	if got := s.Stats()[0].RateLimited; got != 1 {
+		t.Errorf("rateLimited = %d, want 1", got)
+	}
+}
+
+func TestLimiter_Refills(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewLimiter(60)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 60; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d denied within burst", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != time.Second {
+		t.Fatalf("Allow = %v, %v; want denied for 1s", ok, wait)
+	}
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("denied after refill")
+	}
+
+	var unlimited *Limiter
+	if ok, _ := unlimited.Allow("a"); !ok {
+		t.Error("nil limiter denied")
+	}
+}
+
+func TestClientName(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/search", nil)
+	r.RemoteAddr = "10.0.0.5:4321"
+	if got := ClientName(r); got != "10.0.0.5" {
+		t.Errorf("address: %q", got)
+	}
+	for _, key := range []string{"short", strings.Repeat("k", 36)} {
+		r.Header.Set("x-api-key", key)
+		if got := ClientName(r); !strings.HasPrefix(got, "key-") || len(got) != 12 {
+			t.Errorf("key %q: %q", key, got)
+		}
+	}
+	r.Header.Set(ClientHeader, "indexer")
+	if got := ClientName(r); got != "indexer" {
+		t.Errorf("header: %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a per-client token bucket allowing PerMinute requests a minute
+// with bursts of up to PerMinute. A zero PerMinute allows everything.
+type Limiter struct {
+	PerMinute int
+	now       func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter for perMinute requests per client.
+func NewLimiter(perMinute int) *Limiter {
+	return &Limiter{PerMinute: perMinute, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from client's bucket. When the bucket is empty it
+// returns false and how long until the next token.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	if l == nil || l.PerMinute <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(l.PerMinute)
+	perSecond := capacity / 60
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * perSecond
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
@@ -0,0 +1,269 @@
+// Package session stores multi-turn answer conversations so follow-up
+// questions can be asked with the earlier turns as context.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/config"
+)
+
+// ErrNotFound is returned when no session has the requested name.
+var ErrNotFound = errors.New("session not found")
+
+// validName restricts session names to safe file names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Context budgets for composing a follow-up query.
+const (
+	// MaxContextChars bounds the conversation text sent with a follow-up.
+	MaxContextChars = 2000
+	// maxAnswerChars bounds each earlier answer within that context.
+	maxAnswerChars = 400
+)
+
+// Turn is one question and its answer.
+type Turn struct {
+	Time      time.Time          `json:"time"`
+	Question  string             `json:"question"`
+	Query     string             `json:"query"` // Query sent, with conversation context
+	Answer    string             `json:"answer"`
+	Citations []api.SearchResult `json:"citations,omitempty"`
+	Cost      float64            `json:"costDollars,omitempty"`
+}
+
+// Session is a named conversation.
+type Session struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Turns   []Turn    `json:"turns"`
+}
+
+// ValidateName checks that name can be used as a session name.
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid session name %q (use letters, digits, '.', '_' or '-', up to 64 characters)", name)
+	}
+	return nil
+}
+
+// Compose returns the query to send for question: the question alone for
+// a new session, otherwise the most recent turns that fit in
+// MaxContextChars followed by the question.
+func (s *Session) Compose(question string) string {
+	if len(s.Turns) == 0 {
+		return question
+	}
+
+	var blocks []string
+	used := 0
+	for i := len(s.Turns) - 1; i >= 0; i-- {
+		t := s.Turns[i]
+		block := "Q: " + oneLine(t.Question) + "\nA: " + truncate(oneLine(t.Answer), maxAnswerChars)
+		if used+len(block) > MaxContextChars {
+			break
+		}
+		used += len(block)
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		return question
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return "Conversation so far:\n" + strings.Join(blocks, "\n\n") +
+		"\n\nFollow-up question: " + question
+}
+
+// Add appends a turn for question, answered by resp.
+func (s *Session) Add(question, query string, resp *api.AnswerResponse, now time.Time) {
+	t := Turn{Time: now, Question: question, Query: query, Answer: resp.Answer, Citations: resp.Citations}
+	if resp.CostDollars != nil {
+		t.Cost = resp.CostDollars.Total
+	}
+	s.Turns = append(s.Turns, t)
+	if s.Created.IsZero() {
+		s.Created = now
+	}
+	s.Updated = now
+}
+
+// Cost returns the total cost of the session's answers.
+func (s *Session) Cost() float64 {
+	var total float64
+	for _, t := range s.Turns {
+		total += t.Cost
+	}
+	return total
+}
+
+// Citations returns every cited source once, in order of first citation.
+func (s *Session) Citations() []api.SearchResult {
+	seen := make(map[string]bool)
+	out := []api.SearchResult{}
+	for _, t := range s.Turns {
+		for _, c := range t.Citations {
+			if !seen[c.URL] {
+				seen[c.URL] = true
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// WriteMarkdown writes the conversation as a Markdown transcript.
+func (s *Session) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", s.Name)
+	for i, t := range s.Turns {
+		fmt.Fprintf(&b, "\n## %d. %s\n\n%s\n", i+1, oneLine(t.Question), strings.TrimSpace(t.Answer))
+		if len(t.Citations) > 0 {
+			b.WriteString("\nSources:\n\n")
+			for _, c := range t.Citations {
+				title := oneLine(c.Title)
+				if title == "" {
+					title = c.URL
+				}
+				fmt.Fprintf(&b, "- [%s](%s)\n", title, c.URL)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Store keeps one JSON file per session in a directory.
+type Store struct {
+	dir string
+}
+
+// DefaultDir returns the sessions directory in the exa data directory.
+func DefaultDir() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
+
+// Open returns the store in dir. The directory is created on first save.
+func Open(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the store location.
+func (st *Store) Dir() string {
+	return st.dir
+}
+
+func (st *Store) path(name string) string {
+	return filepath.Join(st.dir, name+".json")
+}
+
+// Load reads the named session.
+func (st *Store) Load(name string) (*Session, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(st.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", st.path(name), err)
+	}
+	return &s, nil
+}
+
+// LoadOrNew reads the named session, or returns an empty one if it does
+// not exist yet.
+func (st *Store) LoadOrNew(name string) (*Session, error) {
+	s, err := st.Load(name)
+	if errors.Is(err, ErrNotFound) {
+		return &Session{Name: name}, nil
+	}
+	return s, err
+}
+
+// Save writes s, replacing its file atomically.
+func (st *Store) Save(s *Session) error {
+	if err := ValidateName(s.Name); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(st.dir, 0700); err != nil {
+		return err
+	}
+	path := st.path(s.Name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Delete removes the named session.
+func (st *Store) Delete(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	err := os.Remove(st.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return err
+}
+
+// List returns all sessions, most recently updated first.
+func (st *Store) List() ([]*Session, error) {
+	files, err := filepath.Glob(filepath.Join(st.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sessions := []*Session{}
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), ".json")
+		if ValidateName(name) != nil {
+			continue
+		}
+		s, err := st.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Updated.After(sessions[j].Updated) })
+	return sessions, nil
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-3]) + "..."
+}
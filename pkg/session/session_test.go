@@ -0,0 +1,121 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func answer(text string, urls ...string) *api.AnswerResponse {
+	resp := &api.AnswerResponse{Answer: text, CostDollars: &api.CostInfo{Total: 0.005}}
+	for _, u := range urls {
+		resp.Citations = append(resp.Citations, api.SearchResult{Title: "Title of " + u, URL: u})
+	}
+	return resp
+}
+
+func TestCompose(t *testing.T) {
+	s := &Session{Name: "rag"}
+	if got := s.Compose("What is RAG?"); got != "What is RAG?" {
+		t.Errorf("first question should be sent alone, got %q", got)
+	}
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.Add("What is RAG?", "What is RAG?", answer("Retrieval augmented\ngeneration.", "https://a.example"), now)
+	s.Add("Who coined it?", "...", answer("Lewis et al. in 2020.", "https://b.example"), now.Add(time.Minute))
+
+	got := s.Compose("And the paper?")
+	want := "Conversation so far:\nQ: What is RAG?\nA: Retrieval augmented generation.\n\n" +
+		"Q: Who coined it?\nA: Lewis et al. in 2020.\n\nFollow-up question: And the paper?"
+	if got != want {
+		t.Errorf("Compose =\n%s\nwant\n%s", got, want)
+	}
+
+	// Only the most recent turns that fit are kept, oldest first.
+	s.Add("Long one?", "...", answer(strings.Repeat("x ", 1000)), now.Add(2*time.Minute))
+	for i := 0; i < 5; i++ {
+		s.Add("Filler?", "...", answer(strings.Repeat("y", 350)), now.Add(time.Duration(3+i)*time.Minute))
+	}
+	got = s.Compose("Last?")
+	if len(got) > MaxContextChars+200 {
+		t.Errorf("context too long: %d chars", len(got))
+	}
+	if strings.Contains(got, "What is RAG?") || !strings.HasSuffix(got, "Follow-up question: Last?") {
+		t.Errorf("oldest turns should be dropped first:\n%s", got)
+	}
+
+	if s.Created != now || s.Updated != now.Add(7*time.Minute) {
+		t.Errorf("Created/Updated = %v/%v", s.Created, s.Updated)
+	}
+	if c := s.Cost(); c < 0.0399 || c > 0.0401 {
+		t.Errorf("Cost = %v, want 0.04", c)
+	}
+}
+
+func TestCitationsAndMarkdown(t *testing.T) {
+	s := &Session{Name: "go"}
+	now := time.Now()
+	s.Add("q1", "q1", answer("a1", "https://a.example", "https://b.example"), now)
+	s.Add("q2", "q2", answer("a2", "https://b.example", "https://c.example"), now)
+
+	cites := s.Citations()
+	if len(cites) != 3 || cites[2].URL != "https://c.example" {
+		t.Errorf("Citations = %+v", cites)
+	}
+
+	var b strings.Builder
+	if err := s.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# go\n", "## 2. q2\n\na2\n", "- [Title of https://c.example](https://c.example)"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	st := Open(t.TempDir())
+
+	if _, err := st.Load("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load missing: %v", err)
+	}
+	s, err := st.LoadOrNew("first")
+	if err != nil || s.Name != "first" || len(s.Turns) != 0 {
+		t.Fatalf("LoadOrNew = %+v, %v", s, err)
+	}
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.Add("q", "q", answer("a", "https://a.example"), now)
+	second := &Session{Name: "second"}
+	second.Add("q", "q", answer("a"), now.Add(time.Hour))
+	for _, x := range []*Session{s, second} {
+		if err := st.Save(x); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, err := st.Load("first")
+	if err != nil || len(loaded.Turns) != 1 || loaded.Turns[0].Citations[0].URL != "https://a.example" {
+		t.Fatalf("Load = %+v, %v", loaded, err)
+	}
+	all, err := st.List()
+	if err != nil || len(all) != 2 || all[0].Name != "second" {
+		t.Fatalf("List = %v, %v", all, err)
+	}
+
+	if err := st.Delete("first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Delete("first"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete: %v", err)
+	}
+	for _, bad := range []string{"", "../etc", ".hidden", "a/b", strings.Repeat("n", 65)} {
+		if err := st.Save(&Session{Name: bad}); err == nil {
+			t.Errorf("Save accepted name %q", bad)
+		}
+	}
+}
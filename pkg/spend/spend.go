@@ -0,0 +1,164 @@
+// Package spend keeps a local ledger of API spending, one JSON line per
+// billed call, used for budgets and the 'exa spend' report.
+package spend
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/config"
+)
+
+// Entry is one billed API call.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Command   string    `json:"command"`
+	Profile   string    `json:"profile,omitempty"`
+	Client    string    `json:"client,omitempty"` // Proxy client that made the call
+	Endpoint  string    `json:"endpoint"`
+	Cost      float64   `json:"cost"`
+	RequestID string    `json:"requestId,omitempty"`
+}
+
+// Group is the spending for one day, command, profile or client.
+type Group struct {
+	Key   string  `json:"key"`
+	Calls int     `json:"calls"`
+	Cost  float64 `json:"cost"`
+}
+
+// Ledger is an append-only JSONL file of entries.
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns spend.jsonl in the exa data directory.
+func DefaultPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "spend.jsonl"), nil
+}
+
+// Open returns the ledger stored at path. The file is created on the first
+// Append.
+func Open(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Path returns the ledger file location.
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append records e. It is safe for concurrent use.
+func (l *Ledger) Append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns the entries recorded at or after since, oldest first.
+// A missing ledger is empty; unparseable lines are skipped.
+func (l *Ledger) Entries(since time.Time) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", l.path, err)
+	}
+	return entries, nil
+}
+
+// Total sums the cost of entries at or after since.
+func Total(entries []Entry, since time.Time) float64 {
+	var total float64
+	for _, e := range entries {
+		if !e.Time.Before(since) {
+			total += e.Cost
+		}
+	}
+	return total
+}
+
+// Key functions for GroupBy.
+var (
+	ByDay     = func(e Entry) string { return e.Time.Local().Format("2006-01-02") }
+	ByCommand = func(e Entry) string { return e.Command }
+	ByProfile = func(e Entry) string { return e.Profile }
+	ByClient  = func(e Entry) string { return e.Client }
+)
+
+// GroupBy totals entries by key, sorted by key.
+func GroupBy(entries []Entry, key func(Entry) string) []Group {
+	index := make(map[string]int)
+	var groups []Group
+	for _, e := range entries {
+		k := key(e)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, Group{Key: k})
+		}
+		groups[i].Calls++
+		groups[i].Cost += e.Cost
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+// StartOfDay returns local midnight on t's day.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Local().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// StartOfMonth returns local midnight on the first of t's month.
+func StartOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Local().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,65 @@
+package spend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedger_AppendAndGroup(t *testing.T) {
+	l := Open(filepath.Join(t.TempDir(), "exa", "spend.jsonl"))
+	day1 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	for _, e := range []Entry{
+		{Time: day1, Command: "search", Profile: "work", Endpoint: "/search", Cost: 0.005},
+		{Time: day2, Command: "answer", Profile: "work", Endpoint: "/answer", Cost: 0.01},
+		{Time: day2, Command: "search", Profile: "home", Endpoint: "/search", Cost: 0.005, Client: "crawler"},
+	} {
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := l.Entries(time.Time{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Entries = %d, %v", len(all), err)
+	}
+	recent, _ := l.Entries(StartOfDay(day2))
+	if len(recent) != 2 {
+		t.Errorf("Entries since day2 = %d, want 2", len(recent))
+	}
+	if got := Total(all, StartOfMonth(day1)); got < 0.0199 || got > 0.0201 {
+		t.Errorf("month total = %v, want 0.02", got)
+	}
+
+	days := GroupBy(all, ByDay)
+	if len(days) != 2 || days[0].Key != "2026-03-01" || days[1].Calls != 2 {
+		t.Errorf("by day = %+v", days)
+	}
+	cmds := GroupBy(all, ByCommand)
+	if len(cmds) != 2 || cmds[0].Key != "answer" || cmds[1].Cost != 0.01 {
+		t.Errorf("by command = %+v", cmds)
+	}
+	clients := GroupBy(all, ByClient)
+	if len(clients) != 2 || clients[1].Key != "crawler" || clients[1].Calls != 1 {
+		t.Errorf("by client = %+v", clients)
+	}
+}
+
+func TestLedger_MissingAndCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spend.jsonl")
+	l := Open(path)
+	if entries, err := l.Entries(time.Time{}); err != nil || len(entries) != 0 {
+		t.Fatalf("missing ledger = %v, %v", entries, err)
+	}
+
+	data := "not json\n" + `{"time":"2026-03-01T00:00:00Z","command":"search","endpoint":"/search","cost":0.005}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := l.Entries(time.Time{})
+	if err != nil || len(entries) != 1 {
+		t.Errorf("corrupt line should be skipped, got %v, %v", entries, err)
+	}
+}
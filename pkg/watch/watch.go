@@ -0,0 +1,210 @@
+// Package watch stores saved searches that are re-run periodically to
+// report results not seen before.
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+	"github.com/roboalchemist/exa-cli/pkg/config"
+)
+
+var (
+	// ErrNotFound is returned when no watch has the requested name.
+	ErrNotFound = errors.New("watch not found")
+	// ErrExists is returned when adding a watch whose name is taken.
+	ErrExists = errors.New("watch already exists")
+)
+
+// Date fields a watch window can apply to.
+const (
+	DatePublished = "published"
+	DateCrawled   = "crawl"
+)
+
+// minRetention is the shortest time a seen URL is remembered.
+const minRetention = 30 * 24 * time.Hour
+
+// Watch is a saved search.
+type Watch struct {
+	Name      string               `json:"name"`
+	Request   api.SearchRequest    `json:"request"`
+	Window    string               `json:"window"`    // Rolling window, e.g. "7d" or "36h"
+	DateField string               `json:"dateField"` // DatePublished or DateCrawled
+	Created   time.Time            `json:"created"`
+	LastRun   time.Time            `json:"lastRun,omitempty"`
+	Seen      map[string]time.Time `json:"seen,omitempty"` // URL → first seen
+}
+
+// ParseWindow parses a Go duration, also accepting whole days ("7d").
+func ParseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q (want e.g. 24h or 7d)", s)
+	}
+	return d, nil
+}
+
+// Validate checks the window and date field, and that the saved request
+// has no start date of its own for the window to overwrite.
+func (w *Watch) Validate() error {
+	if _, err := ParseWindow(w.Window); err != nil {
+		return err
+	}
+	switch {
+	case w.DateField != DatePublished && w.DateField != DateCrawled:
+		return fmt.Errorf("invalid date field %q (want %s or %s)", w.DateField, DatePublished, DateCrawled)
+	case w.DateField == DatePublished && w.Request.StartPublishedDate != "",
+		w.DateField == DateCrawled && w.Request.StartCrawlDate != "":
+		return fmt.Errorf("a start date cannot be combined with a rolling %s-date window, which sets it on each run", w.DateField)
+	}
+	return nil
+}
+
+// SearchRequest returns the saved request with its start date set to the
+// beginning of the rolling window ending at now.
+func (w *Watch) SearchRequest(now time.Time) (*api.SearchRequest, error) {
+	window, err := ParseWindow(w.Window)
+	if err != nil {
+		return nil, err
+	}
+	req := w.Request
+	start := now.Add(-window).UTC().Format("2006-01-02T15:04:05.000Z")
+	if w.DateField == DateCrawled {
+		req.StartCrawlDate = start
+	} else {
+		req.StartPublishedDate = start
+	}
+	return &req, nil
+}
+
+// Unseen returns the results whose URLs the watch has not reported before
+// and marks them seen. URLs first seen longer ago than twice the window
+// (at least 30 days) are forgotten.
+func (w *Watch) Unseen(results []api.SearchResult, now time.Time) []api.SearchResult {
+	if w.Seen == nil {
+		w.Seen = make(map[string]time.Time)
+	}
+	retention := minRetention
+	if window, err := ParseWindow(w.Window); err == nil && 2*window > retention {
+		retention = 2 * window
+	}
+	for u, t := range w.Seen {
+		if now.Sub(t) > retention {
+			delete(w.Seen, u)
+		}
+	}
+
+	fresh := []api.SearchResult{}
+	for _, r := range results {
+		if _, ok := w.Seen[r.URL]; ok {
+			continue
+		}
+		w.Seen[r.URL] = now
+		fresh = append(fresh, r)
+	}
+
+	w.LastRun = now
+	return fresh
+}
+
+// File is the saved watches file.
+type File struct {
+	path    string
+	Watches []*Watch `json:"watches"`
+}
+
+// DefaultPath returns watches.json in the exa data directory.
+func DefaultPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "watches.json"), nil
+}
+
+// Load reads the watches file at path. A missing file has no watches.
+func Load(path string) (*File, error) {
+	f := &File{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Path returns the file location.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Get returns the named watch.
+func (f *File) Get(name string) (*Watch, error) {
+	for _, w := range f.Watches {
+		if w.Name == name {
+			return w, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+}
+
+// Add saves a new watch.
+func (f *File) Add(w *Watch) error {
+	if _, err := f.Get(w.Name); err == nil {
+		return fmt.Errorf("%w: %q", ErrExists, w.Name)
+	}
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	f.Watches = append(f.Watches, w)
+	sort.Slice(f.Watches, func(i, j int) bool { return f.Watches[i].Name < f.Watches[j].Name })
+	return nil
+}
+
+// Remove deletes the named watch.
+func (f *File) Remove(name string) error {
+	for i, w := range f.Watches {
+		if w.Name == name {
+			f.Watches = append(f.Watches[:i], f.Watches[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrNotFound, name)
+}
+
+// Save writes the file, replacing it atomically.
+func (f *File) Save() error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,106 @@
+package watch
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/roboalchemist/exa-cli/pkg/api"
+)
+
+func TestParseWindow(t *testing.T) {
+	for in, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "36h": 36 * time.Hour} {
+		if got, err := ParseWindow(in); err != nil || got != want {
+			t.Errorf("ParseWindow(%q) = %v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"", "0d", "-1h", "week"} {
+		if _, err := ParseWindow(in); err == nil {
+			t.Errorf("ParseWindow(%q) should fail", in)
+		}
+	}
+}
+
+func TestWatch_SearchRequestWindow(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	w := &Watch{Request: api.SearchRequest{Query: "q"}, Window: "2d", DateField: DatePublished}
+	req, err := w.SearchRequest(now)
+	if err != nil || req.StartPublishedDate != "2026-03-08T12:00:00.000Z" || req.StartCrawlDate != "" {
+		t.Fatalf("published window = %+v, %v", req, err)
+	}
+	if w.Request.StartPublishedDate != "" {
+		t.Error("SearchRequest modified the saved request")
+	}
+
+	w.DateField = DateCrawled
+	req, _ = w.SearchRequest(now)
+	if req.StartCrawlDate != "2026-03-08T12:00:00.000Z" || req.StartPublishedDate != "" {
+		t.Errorf("crawl window = %+v", req)
+	}
+}
+
+func TestWatch_ValidateStartDate(t *testing.T) {
+	w := &Watch{Request: api.SearchRequest{StartPublishedDate: "2026-01-01T00:00:00.000Z"}, Window: "7d", DateField: DatePublished}
+	if err := w.Validate(); err == nil {
+		t.Error("start published date with a published window should fail")
+	}
+	w.DateField = DateCrawled
+	if err := w.Validate(); err != nil {
+		t.Errorf("start published date with a crawl window: %v", err)
+	}
+}
+
+func TestWatch_Unseen(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	w := &Watch{Window: "1d", DateField: DatePublished}
+	results := []api.SearchResult{{URL: "a"}, {URL: "b"}}
+
+	if got := w.Unseen(results, now); len(got) != 2 {
+		t.Fatalf("first run = %d new, want 2", len(got))
+	}
+	got := w.Unseen(append(results, api.SearchResult{URL: "c"}), now.Add(time.Hour))
+	if len(got) != 1 || got[0].URL != "c" {
+		t.Errorf("second run = %+v, want only c", got)
+	}
+
+	// Past retention, old URLs are forgotten and reported again.
+	if got := w.Unseen(results, now.Add(31*24*time.Hour)); len(got) != 2 {
+		t.Errorf("after retention = %d new, want 2", len(got))
+	}
+}
+
+func TestFile_AddRemoveSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exa", "watches.json")
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Watch{Name: "news", Request: api.SearchRequest{Query: "q"}, Window: "7d", DateField: DatePublished}
+	if err := f.Add(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Add(w); !errors.Is(err, ErrExists) {
+		t.Errorf("duplicate Add err = %v", err)
+	}
+	if err := f.Add(&Watch{Name: "bad", Window: "7d", DateField: "updated"}); err == nil {
+		t.Error("Add with invalid date field should fail")
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.Get("news"); err != nil || got.Request.Query != "q" {
+		t.Fatalf("Get after reload = %+v, %v", got, err)
+	}
+	if err := f.Remove("news"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Get("news"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Remove err = %v", err)
+	}
+}